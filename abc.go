@@ -57,7 +57,15 @@ func Init(ctx context.Context, projectIDList []string, opts ...InitOption) (err
 		if !c.IsCustomDMPClient {
 			client.RegisterDMPClient(client.NewDMPClient(client.WithEnvTypeOption(c.EnvType)))
 		}
-		initExposureConsumer()
+		err = initExposureSpool(c)
+		if err != nil {
+			return
+		}
+		initExposureConsumer(c)
+		initExposureDedupe(c)
+		initExposureBatcher(c)
+		cache.SetVersionHistory(c.VersionHistory, c.VersionCheck)
+		cache.SetLenientValidation(c.LenientValidation)
 		err = initCustomMetricsPlugin(ctx, c)
 		if err != nil {
 			return
@@ -77,7 +85,11 @@ func Init(ctx context.Context, projectIDList []string, opts ...InitOption) (err
 
 // Release local cache, concurrency is not safe
 func Release() {
+	releaseExposureConsumer()
+	releaseExposureBatcher()
+	releaseExposureSpool()
 	cache.Release()
+	exposureFilter = nil
 	once = sync.Once{}
 	internal.C = &internal.GlobalConfig{}
 }
@@ -212,6 +224,19 @@ func WithRegionCode(regionCode string) InitOption {
 	}
 }
 
+// WithSamplingMode set the sampling mode of the metrics tables whose expanded data does not select one,
+// the default is mp.SamplingModeRandom. With mp.SamplingModeHash a sampled-in unit ID is always sampled in,
+// so that the funnels of the users can be analysed
+func WithSamplingMode(mode mp.SamplingMode) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		if mode != mp.SamplingModeRandom && mode != mp.SamplingModeHash {
+			return errors.Errorf("unknown sampling mode %s", mode)
+		}
+		config.SamplingMode = string(mode)
+		return nil
+	}
+}
+
 // GetGlobalConfig returns the global configuration object,
 // including the projectID passed in Init, whether to enable exposure reporting, etc., deep copy
 // modifying the returned globalConfig will not update the global configuration, it is only used as a data query
@@ -236,3 +261,9 @@ func deepCopyGlobalConfig(source *internal.GlobalConfig) (*internal.GlobalConfig
 func RegisterProjectIDs(ctx context.Context, projectIDList []string) error {
 	return cache.InitLocalCache(ctx, projectIDList)
 }
+
+// IsProjectReady Whether the data of projectID has been loaded into the local cache,
+// evaluations of a project that is not ready return an error or the caller-supplied fallback
+func IsProjectReady(projectID string) bool {
+	return cache.GetApplication(projectID) != nil
+}
@@ -193,3 +193,18 @@ func TestInit(t *testing.T) {
 		})
 	}
 }
+
+func TestIsProjectReady(t *testing.T) {
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if !IsProjectReady(projectID) {
+		t.Errorf("IsProjectReady(%s) = false, want true", projectID)
+	}
+	if IsProjectReady("not exist") {
+		t.Errorf("IsProjectReady(not exist) = true, want false")
+	}
+}
@@ -0,0 +1,240 @@
+// Package abcgrpc provides the gRPC interceptors that carry the abc.Context along a call chain.
+// The server interceptors build the abc.Context from the incoming metadata and attach it to the handler's context,
+// the client interceptors forward the identity and the assignments of the caller to the downstream services,
+// so that a whole call chain evaluates consistently.
+package abcgrpc
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/internal/recorder"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys of the identity and the assignments forwarded along the call chain
+const (
+	// UnitIDKey The metadata key of the unit ID
+	UnitIDKey = "abc-unit-id"
+	// DecisionIDKey The metadata key of the decisionID, the unitID is used if absent
+	DecisionIDKey = "abc-decision-id"
+	// NewUnitIDKey The metadata key of the newUnitID
+	NewUnitIDKey = "abc-new-unit-id"
+	// NewDecisionIDKey The metadata key of the newDecisionID
+	NewDecisionIDKey = "abc-new-decision-id"
+	// TagsKey The metadata key of the tags, each value is a url-escaped tagKey=tagValue pair
+	TagsKey = "abc-tags"
+	// AssignmentsKey The metadata key of the assignments in the form of layerKey1=groupKey1;layerKey2=groupKey2,
+	// the keys are url-escaped
+	AssignmentsKey = "abc-assignments"
+)
+
+type options struct {
+	metadataTags    map[string]string
+	isDeferExposure bool
+}
+
+// Option Server interceptor options
+type Option func(o *options)
+
+// WithMetadataTags sets other metadata read as tags, the key of metadataTags is the metadata key
+// and the value is the tag key. Metadata that is absent is skipped
+func WithMetadataTags(metadataTags map[string]string) Option {
+	return func(o *options) {
+		o.metadataTags = metadataTags
+	}
+}
+
+// WithDeferredExposure sets whether the automatic exposure is deferred until the handler returns without error,
+// the exposures of a handler that returns an error are dropped.
+// The exposure of GetValueByVariantKey is never deferred. Closed by default
+func WithDeferredExposure(isDeferExposure bool) Option {
+	return func(o *options) {
+		o.isDeferExposure = isDeferExposure
+	}
+}
+
+// UnaryServerInterceptor Build the abc.Context from the incoming metadata and attach it to the handler's context.
+// If the unit ID is absent, the attached abc.Context returns the error of the missing unit ID on evaluation.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		userCtx := o.newUserContext(ctx)
+		resp, err := handler(recorder.NewContext(ctx, userCtx), req)
+		o.finish(ctx, userCtx, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor The stream version of UnaryServerInterceptor
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		userCtx := o.newUserContext(ss.Context())
+		err := handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          recorder.NewContext(ss.Context(), userCtx),
+		})
+		o.finish(ss.Context(), userCtx, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor Forward the identity and the assignments of the abc.Context in ctx to the downstream service.
+// The abc.Context is the one attached by the server interceptors or by the abchttp middleware,
+// nothing is forwarded if absent or if the unit ID is already set in the outgoing metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor The stream version of UnaryClientInterceptor
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+// FromContext The abc.Context attached by the server interceptors, nil if absent.
+// It is also attached as the user context of abc.FromContext and the package-level helpers such as abc.GetExperiment
+func FromContext(ctx context.Context) abc.Context {
+	userCtx := recorder.FromContext(ctx)
+	if userCtx == nil {
+		return nil
+	}
+	return userCtx
+}
+
+// IncomingAssignments The assignments made by the upstream services, the key is layerKey and the value is groupKey
+func IncomingAssignments(ctx context.Context) map[string]string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return recorder.ParseAssignments(strings.Join(md.Get(AssignmentsKey), ";"))
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) newUserContext(ctx context.Context) *recorder.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	identity := recorder.Identity{
+		UnitID:        firstValue(md, UnitIDKey),
+		DecisionID:    firstValue(md, DecisionIDKey),
+		NewUnitID:     firstValue(md, NewUnitIDKey),
+		NewDecisionID: firstValue(md, NewDecisionIDKey),
+		Tags:          decodeTags(md.Get(TagsKey)),
+	}
+	for key, tagKey := range o.metadataTags {
+		values := md.Get(key)
+		if len(values) == 0 {
+			continue
+		}
+		identity.Tags[tagKey] = append(identity.Tags[tagKey], values...)
+	}
+	userCtx := recorder.New(identity, o.isDeferExposure)
+	userCtx.Inherit(recorder.ParseAssignments(strings.Join(md.Get(AssignmentsKey), ";")))
+	return userCtx
+}
+
+func (o *options) finish(ctx context.Context, userCtx *recorder.Context, err error) {
+	if err != nil {
+		userCtx.Discard()
+		return
+	}
+	_ = userCtx.Flush(ctx)
+}
+
+// outgoingContext Append the identity and the assignments of the abc.Context in ctx to the outgoing metadata
+func outgoingContext(ctx context.Context) context.Context {
+	userCtx := recorder.FromContext(ctx)
+	if userCtx == nil {
+		return ctx
+	}
+	identity := userCtx.Identity()
+	if identity.UnitID == "" {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if len(md.Get(UnitIDKey)) != 0 { // set by the caller explicitly
+		return ctx
+	}
+	var kv = []string{UnitIDKey, identity.UnitID}
+	if identity.DecisionID != "" {
+		kv = append(kv, DecisionIDKey, identity.DecisionID)
+	}
+	if identity.NewUnitID != "" {
+		kv = append(kv, NewUnitIDKey, identity.NewUnitID)
+	}
+	if identity.NewDecisionID != "" {
+		kv = append(kv, NewDecisionIDKey, identity.NewDecisionID)
+	}
+	for _, tag := range encodeTags(identity.Tags) {
+		kv = append(kv, TagsKey, tag)
+	}
+	if assignments := userCtx.Assignments(); assignments != "" {
+		kv = append(kv, AssignmentsKey, assignments)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// encodeTags Encode each tag value into a url-escaped tagKey=tagValue pair
+func encodeTags(tags map[string][]string) []string {
+	var result []string
+	for key, values := range tags {
+		for _, value := range values {
+			result = append(result, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return result
+}
+
+// decodeTags Decode the tagKey=tagValue pairs, malformed pairs are skipped
+func decodeTags(pairs []string) map[string][]string {
+	var tags = make(map[string][]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, err := url.QueryUnescape(kv[0])
+		if err != nil || key == "" {
+			continue
+		}
+		value, err := url.QueryUnescape(kv[1])
+		if err != nil {
+			continue
+		}
+		tags[key] = append(tags[key], value)
+	}
+	return tags
+}
+
+// serverStream Replace the context of the stream with the one carrying the abc.Context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context The context carrying the abc.Context
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
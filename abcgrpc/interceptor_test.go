@@ -0,0 +1,143 @@
+package abcgrpc
+
+import (
+	"context"
+	"testing"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var projectID = "123"
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	defer abc.Release()
+	tests := []struct {
+		name       string
+		md         metadata.MD
+		opts       []Option
+		wantErr    bool
+		wantConfig string
+	}{
+		{
+			name:    "unit ID missing",
+			md:      metadata.Pairs(),
+			wantErr: true,
+		},
+		{
+			name:       "unit ID",
+			md:         metadata.Pairs(UnitIDKey, "unitID"),
+			wantConfig: "withTagDefaultValue",
+		},
+		{
+			name:       "tags",
+			md:         metadata.Pairs(UnitIDKey, "unitID", TagsKey, "tagKey1=ios"),
+			wantConfig: "withTag-condition1",
+		},
+		{
+			name:       "metadata tags",
+			md:         metadata.Pairs(UnitIDKey, "unitID", "x-platform", "ios"),
+			opts:       []Option{WithMetadataTags(map[string]string{"x-platform": "tagKey1"})},
+			wantConfig: "withTag-condition1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			_, err := UnaryServerInterceptor(tt.opts...)(ctx, nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					userCtx := FromContext(ctx)
+					assert.NotNil(t, userCtx)
+					config, err := userCtx.GetRemoteConfig(ctx, projectID, "withTag")
+					if err != nil {
+						return nil, err
+					}
+					assert.Equal(t, tt.wantConfig, config.String())
+					return nil, nil
+				})
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *mockServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	defer abc.Release()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(UnitIDKey, "unitID"))
+	wantErr := errors.New("handler error")
+	err = StreamServerInterceptor(WithDeferredExposure(true))(nil, &mockServerStream{ctx: ctx},
+		&grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+			result, err := FromContext(stream.Context()).GetExperiment(stream.Context(), projectID, "overrideLayer")
+			assert.Nil(t, err)
+			assert.Equal(t, "100003001", result.Key)
+			return wantErr
+		})
+	assert.Equal(t, wantErr, err)
+}
+
+func TestClientInterceptor(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	defer abc.Release()
+	md := metadata.Pairs(UnitIDKey, "unitID", DecisionIDKey, "decisionID", TagsKey, "tag%3Bkey=a%3Db",
+		AssignmentsKey, "upstreamLayer=upstreamGroup;upstream%3BLayer%3D2=upstream%3DGroup%3B2")
+	var outgoing metadata.MD
+	_, err = UnaryServerInterceptor()(metadata.NewIncomingContext(context.Background(), md), nil,
+		&grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			assert.Equal(t, map[string]string{"upstreamLayer": "upstreamGroup", "upstream;Layer=2": "upstream=Group;2"},
+				IncomingAssignments(ctx))
+			_, err := FromContext(ctx).GetExperiment(ctx, projectID, "overrideLayer")
+			assert.Nil(t, err)
+			err = UnaryClientInterceptor()(ctx, "/test", nil, nil, nil,
+				func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+					opts ...grpc.CallOption) error {
+					outgoing, _ = metadata.FromOutgoingContext(ctx)
+					return nil
+				})
+			return nil, err
+		})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"unitID"}, outgoing.Get(UnitIDKey))
+	assert.Equal(t, []string{"decisionID"}, outgoing.Get(DecisionIDKey))
+	assert.Equal(t, []string{"tag%3Bkey=a%3Db"}, outgoing.Get(TagsKey))
+	assert.Equal(t, []string{"overrideLayer=100001001;upstream%3BLayer%3D2=upstream%3DGroup%3B2;" +
+		"upstreamLayer=upstreamGroup"}, outgoing.Get(AssignmentsKey))
+
+	// nothing is forwarded without the abc.Context
+	_, err = StreamClientInterceptor()(context.Background(), &grpc.StreamDesc{}, nil, "/test",
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+			opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			outgoing, _ = metadata.FromOutgoingContext(ctx)
+			return nil, nil
+		})
+	assert.Nil(t, err)
+	assert.Nil(t, outgoing)
+}
+
+func Test_decodeTags(t *testing.T) {
+	tags := map[string][]string{"key": {"v1", "v=2"}, "k;2": {"v;3"}}
+	assert.Equal(t, tags, decodeTags(encodeTags(tags)))
+	assert.Equal(t, map[string][]string{}, decodeTags([]string{"invalid", "=empty", "%zz=1"}))
+}
@@ -0,0 +1,264 @@
+// Package abchttp provides the net/http middleware that builds the abc.Context of each request.
+// The unit ID and the tags are read from the request by the configured extractors,
+// the abc.Context is stored in the request context and can be obtained by FromRequest, FromContext or abc.FromContext,
+// the package-level helpers such as abc.GetExperiment use it directly.
+package abchttp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/internal/recorder"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultUnitIDHeader The request header of the unit ID read by default
+	DefaultUnitIDHeader = "X-ABC-Unit-ID"
+	// DefaultAssignmentHeader The response header of the assignments set by WithAssignmentHeader
+	DefaultAssignmentHeader = "X-ABC-Assignments"
+)
+
+// UnitIDExtractor Read the unit ID from the request, empty if absent
+type UnitIDExtractor func(r *http.Request) string
+
+// TagExtractor Read the tags used for targeting from the request
+type TagExtractor func(r *http.Request) map[string][]string
+
+// AttributionExtractor Read other attributions from the request, such as abc.WithDecisionID or abc.WithExpandedData.
+// Only the unit ID and the tags are forwarded to the downstream services by the abcgrpc client interceptors
+type AttributionExtractor func(r *http.Request) []abc.Attribution
+
+// HeaderUnitID Read the unit ID from the request header
+func HeaderUnitID(name string) UnitIDExtractor {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// CookieUnitID Read the unit ID from the cookie
+func CookieUnitID(name string) UnitIDExtractor {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+}
+
+// HeaderTags Read the tags from the request headers, the key of headers is the header name
+// and the value is the tag key. Headers that are absent are skipped
+func HeaderTags(headers map[string]string) TagExtractor {
+	return func(r *http.Request) map[string][]string {
+		var tags = make(map[string][]string, len(headers))
+		for header, tagKey := range headers {
+			values := r.Header.Values(header)
+			if len(values) == 0 {
+				continue
+			}
+			tags[tagKey] = append(tags[tagKey], values...)
+		}
+		return tags
+	}
+}
+
+type options struct {
+	unitIDExtractors      []UnitIDExtractor
+	tagExtractors         []TagExtractor
+	attributionExtractors []AttributionExtractor
+	assignmentHeader      string
+	isDeferExposure       bool
+	isSuccess             func(status int) bool
+}
+
+// Option Middleware options
+type Option func(o *options)
+
+// WithUnitIDExtractors sets the extractors of the unit ID, the first non-empty one is used.
+// The unit ID is read from the DefaultUnitIDHeader header by default
+func WithUnitIDExtractors(extractors ...UnitIDExtractor) Option {
+	return func(o *options) {
+		o.unitIDExtractors = extractors
+	}
+}
+
+// WithTagExtractors appends the extractors of the tags, the values of the same tag key are merged
+func WithTagExtractors(extractors ...TagExtractor) Option {
+	return func(o *options) {
+		o.tagExtractors = append(o.tagExtractors, extractors...)
+	}
+}
+
+// WithAttributionExtractors appends the extractors of other attributions
+func WithAttributionExtractors(extractors ...AttributionExtractor) Option {
+	return func(o *options) {
+		o.attributionExtractors = append(o.attributionExtractors, extractors...)
+	}
+}
+
+// WithAssignmentHeader sets the response header echoing the assigned groups in the form of
+// layerKey1=groupKey1;layerKey2=groupKey2 with url-escaped keys. Only the evaluations before the response header is written are echoed.
+// Not echoed by default, an empty name turns it off
+func WithAssignmentHeader(name string) Option {
+	return func(o *options) {
+		o.assignmentHeader = name
+	}
+}
+
+// WithDeferredExposure sets whether the automatic exposure is deferred until the handler finishes without error,
+// the exposures of a handler that panics or responds an error status are dropped.
+// The exposure of GetValueByVariantKey is never deferred. Closed by default
+func WithDeferredExposure(isDeferExposure bool) Option {
+	return func(o *options) {
+		o.isDeferExposure = isDeferExposure
+	}
+}
+
+// WithSuccessStatus sets whether the response status means the handler finishes without error,
+// status below 400 is a success by default
+func WithSuccessStatus(isSuccess func(status int) bool) Option {
+	return func(o *options) {
+		if isSuccess != nil {
+			o.isSuccess = isSuccess
+		}
+	}
+}
+
+// Middleware Build the abc.Context of each request and store it in the request context.
+// If the unit ID is absent, the stored abc.Context returns the error of the missing unit ID on evaluation.
+//
+// example:
+//
+//	http.Handle("/", abchttp.Middleware(abchttp.WithUnitIDExtractors(abchttp.CookieUnitID("uid")))(handler))
+func Middleware(opts ...Option) func(next http.Handler) http.Handler {
+	o := &options{
+		unitIDExtractors: []UnitIDExtractor{HeaderUnitID(DefaultUnitIDHeader)},
+		isSuccess: func(status int) bool {
+			return status < http.StatusBadRequest
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userCtx := o.newUserContext(r)
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			if o.assignmentHeader != "" {
+				rw.beforeWriteHeader = func() {
+					assignments := userCtx.Assignments()
+					if assignments != "" {
+						w.Header().Set(o.assignmentHeader, assignments)
+					}
+				}
+			}
+			isFinished := false
+			defer func() {
+				if isFinished && o.isSuccess(rw.status) {
+					_ = userCtx.Flush(r.Context())
+					return
+				}
+				userCtx.Discard()
+			}()
+			next.ServeHTTP(rw, r.WithContext(recorder.NewContext(r.Context(), userCtx)))
+			// the response header is written after the handler returns if the handler did not write anything
+			rw.writeHeader()
+			isFinished = true
+		})
+	}
+}
+
+// FromRequest The abc.Context of the request built by Middleware, nil if the request is not passed through Middleware
+func FromRequest(r *http.Request) abc.Context {
+	return FromContext(r.Context())
+}
+
+// FromContext The abc.Context stored in ctx by Middleware, nil if absent
+func FromContext(ctx context.Context) abc.Context {
+	userCtx := recorder.FromContext(ctx)
+	if userCtx == nil {
+		return nil
+	}
+	return userCtx
+}
+
+func (o *options) newUserContext(r *http.Request) *recorder.Context {
+	var unitID string
+	for _, extractor := range o.unitIDExtractors {
+		unitID = extractor(r)
+		if unitID != "" {
+			break
+		}
+	}
+	var tags = make(map[string][]string)
+	for _, extractor := range o.tagExtractors {
+		for key, values := range extractor(r) {
+			tags[key] = append(tags[key], values...)
+		}
+	}
+	var attributions []abc.Attribution
+	for _, extractor := range o.attributionExtractors {
+		attributions = append(attributions, extractor(r)...)
+	}
+	return recorder.New(recorder.Identity{UnitID: unitID, Tags: tags}, o.isDeferExposure, attributions...)
+}
+
+// responseWriter Record the response status and run beforeWriteHeader right before the header is written
+type responseWriter struct {
+	http.ResponseWriter
+	status            int
+	wroteHeader       bool
+	beforeWriteHeader func()
+}
+
+func (w *responseWriter) writeHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.beforeWriteHeader != nil {
+		w.beforeWriteHeader()
+	}
+}
+
+// WriteHeader Record the status and write the response header
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+	}
+	w.writeHeader()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write Write the response body, the response header is written first if not yet
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.writeHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush Implement http.Flusher if the underlying writer supports it
+func (w *responseWriter) Flush() {
+	w.writeHeader()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack Implement http.Hijacker if the underlying writer supports it
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the underlying http.ResponseWriter does not implement http.Hijacker")
+	}
+	w.writeHeader()
+	return hijacker.Hijack()
+}
+
+// Unwrap The underlying writer, used by http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,129 @@
+package abchttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+var projectID = "123"
+
+func TestMiddleware(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	defer abc.Release()
+	tests := []struct {
+		name            string
+		opts            []Option
+		setup           func(r *http.Request)
+		wantErr         bool
+		wantAssignments string
+		wantConfig      string
+	}{
+		{
+			name:    "unit ID missing",
+			wantErr: true,
+		},
+		{
+			name: "default header",
+			setup: func(r *http.Request) {
+				r.Header.Set(DefaultUnitIDHeader, "unitID")
+			},
+			wantConfig: "withTagDefaultValue",
+		},
+		{
+			name: "cookie and header tags",
+			opts: []Option{
+				WithUnitIDExtractors(HeaderUnitID("X-Uid"), CookieUnitID("uid")),
+				WithTagExtractors(HeaderTags(map[string]string{"X-Platform": "tagKey1"})),
+				WithAssignmentHeader(DefaultAssignmentHeader),
+			},
+			setup: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "uid", Value: "unitID"})
+				r.Header.Set("X-Platform", "ios")
+			},
+			wantAssignments: "overrideLayer=100003001",
+			wantConfig:      "withTag-condition1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotErr error
+			var gotConfig string
+			handler := Middleware(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userCtx := FromRequest(r)
+				assert.NotNil(t, userCtx)
+				_, gotErr = userCtx.GetExperiment(r.Context(), projectID, "overrideLayer")
+				config, err := userCtx.GetRemoteConfig(r.Context(), projectID, "withTag")
+				if err == nil {
+					gotConfig = config.String()
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.setup != nil {
+				tt.setup(r)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.wantErr, gotErr != nil)
+			assert.Equal(t, tt.wantConfig, gotConfig)
+			assert.Equal(t, tt.wantAssignments, w.Header().Get(DefaultAssignmentHeader))
+			assert.Equal(t, "ok", w.Body.String())
+		})
+	}
+}
+
+func TestMiddleware_DeferredExposure(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	defer abc.Release()
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "success", status: http.StatusOK},
+		{name: "error status", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Middleware(WithDeferredExposure(true), WithAssignmentHeader("X-Assignments"))(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, err := FromRequest(r).GetExperiment(r.Context(), projectID, "overrideLayer")
+					assert.Nil(t, err)
+					w.WriteHeader(tt.status)
+				}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(DefaultUnitIDHeader, "unitID")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "overrideLayer=100003001", w.Header().Get("X-Assignments"))
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+	assert.Nil(t, FromContext(nil))
+}
+
+func TestMiddleware_AbcFromContext(t *testing.T) {
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userCtx, ok := abc.FromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, FromRequest(r), userCtx)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(DefaultUnitIDHeader, "unitID")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+}
@@ -0,0 +1,201 @@
+package benchmark
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	tab "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/internal/client"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/abetterchoice/protoc_cache_server"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	largeProjectID     = "large"
+	largeGroupCount    = 5000
+	largeLayerGroups   = 100 // the groups per layer
+	largeLayerBuckets  = 10000
+	largeGroupBuckets  = largeLayerBuckets / largeLayerGroups
+	largeLayerKeyBase  = "layer"
+	largeExperimentIDs = 1000
+)
+
+// largeProject The tab config of a project with 5k groups and the bitmaps of the groups. The bitmaps are serialized
+// without the run containers, like the ones built by adding the buckets one by one
+func largeProject() (*protoc_cache_server.TabConfig, map[int64][]byte) {
+	var layers []*protoc_cache_server.Layer
+	var bitmaps = make(map[int64][]byte, largeGroupCount)
+	for l := 0; l < largeGroupCount/largeLayerGroups; l++ {
+		layerKey := largeLayerKeyBase + strconv.Itoa(l)
+		experimentID := int64(largeExperimentIDs + l)
+		experiment := &protoc_cache_server.Experiment{Id: experimentID, Key: layerKey,
+			IssueType: protoc_cache_server.IssueType_ISSUE_TYPE_PERCENTAGE, GroupIdIndex: map[int64]bool{}}
+		layer := &protoc_cache_server.Layer{
+			Metadata: &protoc_cache_server.LayerMetadata{Key: layerKey, BucketSize: largeLayerBuckets,
+				HashType: protoc_cache_server.HashType_HASH_TYPE_SINGLE, HashSeed: int64(l)},
+			GroupIndex:      map[int64]*protoc_cache_server.Group{},
+			ExperimentIndex: map[int64]*protoc_cache_server.Experiment{0: {}, experimentID: experiment},
+		}
+		for g := 0; g < largeLayerGroups; g++ {
+			groupID := experimentID*1000 + int64(g)
+			layer.GroupIndex[groupID] = &protoc_cache_server.Group{Id: groupID, GroupKey: strconv.FormatInt(groupID, 10),
+				ExperimentId: experimentID, LayerKey: layerKey, Params: map[string]string{layerKey: strconv.Itoa(g)},
+				IssueInfo: &protoc_cache_server.IssueInfo{IssueType: protoc_cache_server.IssueType_ISSUE_TYPE_PERCENTAGE}}
+			experiment.GroupIdIndex[groupID] = true
+			bitmap := roaring.New()
+			for bucket := g*largeGroupBuckets + 1; bucket <= (g+1)*largeGroupBuckets; bucket++ {
+				bitmap.Add(uint32(bucket))
+			}
+			bitmaps[groupID], _ = bitmap.ToBytes()
+		}
+		layers = append(layers, layer)
+	}
+	fullTraffic := []*protoc_cache_server.TrafficRange{{Left: 1, Right: 100}}
+	return &protoc_cache_server.TabConfig{
+		ExperimentData: &protoc_cache_server.ExperimentData{
+			GlobalDomain: &protoc_cache_server.Domain{
+				Metadata: &protoc_cache_server.DomainMetadata{Key: "global", BucketSize: 100,
+					TrafficRangeList: fullTraffic},
+				MultiLayerDomainList: []*protoc_cache_server.MultiLayerDomain{{
+					Metadata: &protoc_cache_server.DomainMetadata{Key: "multiLayer", BucketSize: 100,
+						TrafficRangeList: fullTraffic},
+					LayerList: layers,
+				}},
+			},
+		},
+		ConfigData:  &protoc_cache_server.RemoteConfigData{},
+		ControlData: &protoc_cache_server.ControlData{},
+	}, bitmaps
+}
+
+// largeProjectClient The cache server of the large project, each refresh gets a new version of the tab config
+// while the bucket infos of the groups are not changed
+func largeProjectClient(b *testing.B) (*client.MockClient, int64) {
+	tabConfig, bitmaps := largeProject()
+	var version int64
+	var rawBytes int64
+	for _, bitmap := range bitmaps {
+		rawBytes += int64(len(bitmap))
+	}
+	mockClient := client.NewMockClient(gomock.NewController(b))
+	mockClient.EXPECT().GetTabConfigData(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *protoc_cache_server.GetTabConfigReq) (*protoc_cache_server.GetTabConfigResp,
+			error) {
+			return &protoc_cache_server.GetTabConfigResp{
+				Code: protoc_cache_server.Code_CODE_SUCCESS,
+				TabConfigManager: &protoc_cache_server.TabConfigManager{ProjectId: largeProjectID,
+					Version: strconv.FormatInt(atomic.AddInt64(&version, 1), 10), TabConfig: tabConfig},
+			}, nil
+		}).AnyTimes()
+	mockClient.EXPECT().BatchGetExperimentBucketInfo(gomock.Any(), gomock.Any()).Return(
+		&protoc_cache_server.BatchGetExperimentBucketResp{Code: protoc_cache_server.Code_CODE_SUCCESS}, nil).AnyTimes()
+	mockClient.EXPECT().BatchGetGroupBucketInfo(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *protoc_cache_server.BatchGetGroupBucketReq) (
+			*protoc_cache_server.BatchGetGroupBucketResp, error) {
+			// the bucket infos of each response are new, like the ones decoded from the wire
+			var bucketIndex = make(map[int64]*protoc_cache_server.BucketInfo, len(bitmaps))
+			for groupID, bitmap := range bitmaps {
+				bucketIndex[groupID] = &protoc_cache_server.BucketInfo{
+					BucketType: protoc_cache_server.BucketType_BUCKET_TYPE_BITMAP, Bitmap: bitmap,
+					Version: "1", ModifyType: protoc_cache_server.ModifyType_MODIFY_UPDATE}
+			}
+			return &protoc_cache_server.BatchGetGroupBucketResp{Code: protoc_cache_server.Code_CODE_SUCCESS,
+				BucketIndex: bucketIndex}, nil
+		}).AnyTimes()
+	return mockClient, rawBytes
+}
+
+// initLargeProject Init the SDK with the large project, the size of the bitmaps as served is returned
+func initLargeProject(b *testing.B, opts ...tab.InitOption) int64 {
+	mockClient, rawBytes := largeProjectClient(b)
+	err := tab.Init(context.Background(), []string{largeProjectID}, append([]tab.InitOption{
+		tab.WithRegisterCacheClient(mockClient),
+		tab.WithRegisterDMPClient(testdata.MockEmptyDMPClient),
+		tab.WithRegisterMetricsPlugin(testdata.EmptyMetricsClient, nil)}, opts...)...)
+	assert.Nil(b, err)
+	return rawBytes
+}
+
+// BenchmarkLargeProjectMemory The memory of a project with 5k groups. raw-bitmap-B is the size of the bitmaps
+// as served, the memory of the bitmap index before the compaction, and bitmap-B is the memory of the compacted
+// bitmaps. bucket-info-B is the memory of the bucket infos with the bitmaps as served.
+// history-B is the memory of the 2 other kept versions not shared with the served one
+func BenchmarkLargeProjectMemory(b *testing.B) {
+	defer tab.Release()
+	rawBytes := initLargeProject(b, tab.WithVersionHistory(3))
+	for i := 0; i < 2; i++ {
+		_, err := cache.NewAndSetApplication(context.Background(), largeProjectID)
+		assert.Nil(b, err)
+	}
+	b.ResetTimer()
+	var usage *tab.MemoryUsage
+	for i := 0; i < b.N; i++ {
+		var err error
+		usage, err = tab.MemoryStats(largeProjectID)
+		assert.Nil(b, err)
+	}
+	b.StopTimer()
+	assert.Equal(b, largeGroupCount, usage.GroupBitmaps.Entries)
+	assert.Equal(b, 2, usage.KeptVersions)
+	b.ReportMetric(float64(rawBytes), "raw-bitmap-B")
+	b.ReportMetric(float64(usage.GroupBucketInfo.Bytes), "bucket-info-B")
+	b.ReportMetric(float64(usage.GroupBitmaps.Bytes), "bitmap-B")
+	b.ReportMetric(float64(usage.Total), "total-B")
+	b.ReportMetric(float64(usage.History), "history-B")
+}
+
+// BenchmarkLargeProjectRefresh The refresh of a project with 5k groups to a new version not changing the buckets.
+// The baseline case adds the work each refresh did before the indexes were shared between the versions:
+// the deep copy of the bucket info and the bitmap indexes, and the bitmaps built again without the compaction
+func BenchmarkLargeProjectRefresh(b *testing.B) {
+	for _, baseline := range []bool{true, false} {
+		name := "shared"
+		if baseline {
+			name = "baseline"
+		}
+		b.Run(name, func(b *testing.B) {
+			defer tab.Release()
+			initLargeProject(b)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if baseline {
+					rebuildIndexes(b, cache.GetApplication(largeProjectID))
+				}
+				_, err := cache.NewAndSetApplication(context.Background(), largeProjectID)
+				if err != nil {
+					b.Fatalf("refresh fail:%v", err)
+				}
+			}
+		})
+	}
+}
+
+// rebuildIndexes Copy the indexes of the application and build its bitmaps from the buffers without
+// the compaction, as each refresh did before the indexes were shared between the versions
+func rebuildIndexes(b *testing.B, application *cache.Application) {
+	experimentBucketInfo := make(map[int64]*protoc_cache_server.BucketInfo, len(application.ExperimentIDBucketInfoIndex))
+	for k, v := range application.ExperimentIDBucketInfoIndex {
+		experimentBucketInfo[k] = v
+	}
+	experimentBitmaps := make(map[int64]*roaring.Bitmap, len(application.ExperimentIDRoaringBitmapIndex))
+	for k, v := range application.ExperimentIDRoaringBitmapIndex {
+		experimentBitmaps[k] = v
+	}
+	groupBucketInfo := make(map[int64]*protoc_cache_server.BucketInfo, len(application.GroupIDBucketInfoIndex))
+	groupBitmaps := make(map[int64]*roaring.Bitmap, len(application.GroupIDRoaringBitmapIndex))
+	for groupID, bucketInfo := range application.GroupIDBucketInfoIndex {
+		groupBucketInfo[groupID] = bucketInfo
+		bitmap := roaring.New()
+		if _, err := bitmap.FromBuffer(bucketInfo.Bitmap); err != nil {
+			b.Fatalf("new bitmap fromBuffer fail:%v", err)
+		}
+		groupBitmaps[groupID] = bitmap
+	}
+}
@@ -108,6 +108,9 @@ type userContext struct {
 	// This information will be logged as an additional field in the exposure table,
 	// in a format similar to k1=v1; k1=v2.
 	expandedData map[string]string
+
+	// The local cache pinned by WithPinnedSnapshot, nil means each evaluation reads the current local cache
+	snapshot *applicationSnapshot
 }
 
 // Attribution Pass in each option as needed, including but not limited to setting label information, etc.
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
+)
+
+// flatten Flatten the snapshot into entries keyed by the entity such as "layer L" or "group L/E/G",
+// the value describes the entity. Comparing the entries of two snapshots gives their difference
+func flatten(s *snapshot) map[string]string {
+	var entries = make(map[string]string)
+	addLayer := func(kind string, domainPath []string, layer *protoccacheserver.Layer) {
+		key := layerKey(layer)
+		description := "domains=" + strings.Join(domainPath, "/")
+		if layer.Metadata != nil {
+			description += fmt.Sprintf(" hashType=%v bucketSize=%d", layer.Metadata.HashType, layer.Metadata.BucketSize)
+			if len(layer.Metadata.HoldoutLayerKeys) != 0 {
+				description += " holdouts=" + strings.Join(layer.Metadata.HoldoutLayerKeys, ",")
+			}
+			if layer.Metadata.DefaultGroup != nil {
+				entries["default group "+key] = describeGroup(layer.Metadata.DefaultGroup)
+			}
+		}
+		entries[kind+" "+key] = description
+		for experimentID, experiment := range layer.ExperimentIndex {
+			if experiment == nil {
+				continue
+			}
+			experimentPath := key + "/" + experiment.Key
+			entries["experiment "+experimentPath] = fmt.Sprintf("id=%d%s", experimentID,
+				bucketVersion(s.ExperimentBuckets, experimentID))
+			for _, groupID := range groupIDs(experiment) {
+				group, ok := layer.GroupIndex[groupID]
+				if !ok || group == nil {
+					continue
+				}
+				entries["group "+experimentPath+"/"+group.GroupKey] = describeGroup(group) +
+					bucketVersion(s.GroupBuckets, groupID)
+			}
+		}
+	}
+	if experimentData := s.TabConfig.ExperimentData; experimentData != nil {
+		walkLayers(experimentData.GlobalDomain, nil, func(domainPath []string, layer *protoccacheserver.Layer) {
+			addLayer("layer", domainPath, layer)
+		})
+		if experimentData.HoldoutData != nil {
+			for _, layer := range experimentData.HoldoutData.HoldoutLayerIndex {
+				if layer != nil {
+					addLayer("holdout layer", nil, layer)
+				}
+			}
+		}
+	}
+	if s.TabConfig.ConfigData != nil {
+		for key, remoteConfig := range s.TabConfig.ConfigData.RemoteConfigIndex {
+			entries["remote config "+key] = describeRemoteConfig(remoteConfig)
+		}
+	}
+	return entries
+}
+
+func bucketVersion(buckets map[int64]*protoccacheserver.BucketInfo, id int64) string {
+	bucketInfo, ok := buckets[id]
+	if !ok || bucketInfo == nil {
+		return ""
+	}
+	return " bucketVersion=" + bucketInfo.Version
+}
+
+// diff Print the difference between the snapshots, the lines are prefixed by - for removed,
+// + for added and ~ for changed entities. It returns whether there is any difference
+func diff(w io.Writer, old *snapshot, new *snapshot) bool {
+	fmt.Fprintf(w, "project %s version %s -> project %s version %s\n", old.ProjectID, old.Version,
+		new.ProjectID, new.Version)
+	oldEntries, newEntries := flatten(old), flatten(new)
+	var keys = make([]string, 0, len(oldEntries)+len(newEntries))
+	for key := range oldEntries {
+		keys = append(keys, key)
+	}
+	for key := range newEntries {
+		if _, ok := oldEntries[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var changed int
+	for _, key := range keys {
+		oldValue, inOld := oldEntries[key]
+		newValue, inNew := newEntries[key]
+		switch {
+		case !inNew:
+			fmt.Fprintf(w, "- %s: %s\n", key, oldValue)
+		case !inOld:
+			fmt.Fprintf(w, "+ %s: %s\n", key, newValue)
+		case oldValue != newValue:
+			fmt.Fprintf(w, "~ %s: %s -> %s\n", key, oldValue, newValue)
+		default:
+			continue
+		}
+		changed++
+	}
+	if changed == 0 {
+		fmt.Fprintln(w, "no difference")
+		return false
+	}
+	fmt.Fprintln(w, strconv.Itoa(changed)+" difference(s)")
+	return true
+}
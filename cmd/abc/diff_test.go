@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dump(t *testing.T) {
+	var buffer bytes.Buffer
+	dump(&buffer, normalSnapshot())
+	assert.Contains(t, buffer.String(), "project 123 version 1\ndomain globalDomain")
+	assert.Contains(t, buffer.String(), "layer overrideLayer")
+	assert.Contains(t, buffer.String(), "remote configs\n")
+	assert.Contains(t, buffer.String(), "  remoteConfig1 version=")
+}
+
+func Test_diff(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.False(t, diff(&buffer, normalSnapshot(), normalSnapshot()))
+	assert.Contains(t, buffer.String(), "no difference")
+
+	newSnapshot := normalSnapshot()
+	newSnapshot.Version = "2"
+	configData := *newSnapshot.TabConfig.ConfigData
+	configData.RemoteConfigIndex = map[string]*protoccacheserver.RemoteConfig{}
+	for key, remoteConfig := range newSnapshot.TabConfig.ConfigData.RemoteConfigIndex {
+		configData.RemoteConfigIndex[key] = remoteConfig
+	}
+	changed := *configData.RemoteConfigIndex["remoteConfig1"]
+	changed.DefaultValue = []byte("changed")
+	configData.RemoteConfigIndex["remoteConfig1"] = &changed
+	delete(configData.RemoteConfigIndex, "withTag")
+	configData.RemoteConfigIndex["added"] = &protoccacheserver.RemoteConfig{Key: "added"}
+	tabConfig := *newSnapshot.TabConfig
+	tabConfig.ConfigData = &configData
+	newSnapshot.TabConfig = &tabConfig
+
+	buffer.Reset()
+	assert.True(t, diff(&buffer, normalSnapshot(), newSnapshot))
+	assert.Contains(t, buffer.String(), "project 123 version 1 -> project 123 version 2")
+	assert.Contains(t, buffer.String(), "+ remote config added: ")
+	assert.Contains(t, buffer.String(), "- remote config withTag: ")
+	assert.Contains(t, buffer.String(), "~ remote config remoteConfig1: ")
+	assert.Contains(t, buffer.String(), "3 difference(s)")
+}
+
+func Test_runDiff(t *testing.T) {
+	path := writeNormalSnapshot(t)
+	other := filepath.Join(t.TempDir(), "other.json")
+	s := normalSnapshot()
+	s.ExperimentBuckets = nil
+	assert.Nil(t, writeSnapshot(other, s))
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run(context.Background(), []string{"diff", path, path}, &stdout, &stderr), stderr.String())
+	stdout.Reset()
+	assert.Equal(t, exitDifference, run(context.Background(), []string{"diff", path, other}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "~ experiment ")
+	assert.Equal(t, 2, run(context.Background(), []string{"diff", path}, &stdout, &stderr))
+}
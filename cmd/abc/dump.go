@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
+)
+
+// layerVisitor Visit the layer, domainPath is the keys of the parent domains from top to bottom
+type layerVisitor func(domainPath []string, layer *protoccacheserver.Layer)
+
+// walkLayers Visit the layers of the domain tree depth first, in the order of the holdout domains,
+// the multi-layer domains and the subdomains
+func walkLayers(domain *protoccacheserver.Domain, domainPath []string, visit layerVisitor) {
+	if domain == nil {
+		return
+	}
+	path := append(domainPath[:len(domainPath):len(domainPath)], domainKey(domain.Metadata))
+	for _, holdoutDomain := range domain.HoldoutDomainList {
+		if holdoutDomain == nil {
+			continue
+		}
+		holdoutPath := append(path[:len(path):len(path)], domainKey(holdoutDomain.Metadata))
+		for _, layer := range holdoutDomain.LayerList {
+			if layer != nil {
+				visit(holdoutPath, layer)
+			}
+		}
+	}
+	for _, multiLayerDomain := range domain.MultiLayerDomainList {
+		if multiLayerDomain == nil {
+			continue
+		}
+		multiLayerPath := append(path[:len(path):len(path)], domainKey(multiLayerDomain.Metadata))
+		for _, layer := range multiLayerDomain.LayerList {
+			if layer != nil {
+				visit(multiLayerPath, layer)
+			}
+		}
+	}
+	for _, subdomain := range domain.DomainList {
+		walkLayers(subdomain, path, visit)
+	}
+}
+
+func domainKey(metadata *protoccacheserver.DomainMetadata) string {
+	if metadata == nil {
+		return ""
+	}
+	return metadata.Key
+}
+
+func layerKey(layer *protoccacheserver.Layer) string {
+	if layer.Metadata == nil {
+		return ""
+	}
+	return layer.Metadata.Key
+}
+
+// dump Print the domain, layer and experiment tree and the remote configurations of the snapshot
+func dump(w io.Writer, s *snapshot) {
+	fmt.Fprintf(w, "project %s version %s\n", s.ProjectID, s.Version)
+	experimentData := s.TabConfig.ExperimentData
+	if experimentData != nil {
+		dumpDomain(w, experimentData.GlobalDomain, "domain", 0)
+		if experimentData.HoldoutData != nil && len(experimentData.HoldoutData.HoldoutLayerIndex) != 0 {
+			fmt.Fprintln(w, "holdout layers")
+			holdoutLayerIndex := experimentData.HoldoutData.HoldoutLayerIndex
+			var keys = make([]string, 0, len(holdoutLayerIndex))
+			for key := range holdoutLayerIndex {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				dumpLayer(w, holdoutLayerIndex[key], 1)
+			}
+		}
+	}
+	if s.TabConfig.ConfigData != nil && len(s.TabConfig.ConfigData.RemoteConfigIndex) != 0 {
+		fmt.Fprintln(w, "remote configs")
+		remoteConfigIndex := s.TabConfig.ConfigData.RemoteConfigIndex
+		var keys = make([]string, 0, len(remoteConfigIndex))
+		for key := range remoteConfigIndex {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s %s\n", key, describeRemoteConfig(remoteConfigIndex[key]))
+		}
+	}
+}
+
+func dumpDomain(w io.Writer, domain *protoccacheserver.Domain, kind string, depth int) {
+	if domain == nil {
+		return
+	}
+	dumpDomainMetadata(w, kind, domain.Metadata, depth)
+	for _, holdoutDomain := range domain.HoldoutDomainList {
+		if holdoutDomain == nil {
+			continue
+		}
+		dumpDomainMetadata(w, "holdout domain", holdoutDomain.Metadata, depth+1)
+		for _, layer := range holdoutDomain.LayerList {
+			dumpLayer(w, layer, depth+2)
+		}
+	}
+	for _, multiLayerDomain := range domain.MultiLayerDomainList {
+		if multiLayerDomain == nil {
+			continue
+		}
+		dumpDomainMetadata(w, "multi-layer domain", multiLayerDomain.Metadata, depth+1)
+		for _, layer := range multiLayerDomain.LayerList {
+			dumpLayer(w, layer, depth+2)
+		}
+	}
+	for _, subdomain := range domain.DomainList {
+		dumpDomain(w, subdomain, "domain", depth+1)
+	}
+}
+
+func dumpDomainMetadata(w io.Writer, kind string, metadata *protoccacheserver.DomainMetadata, depth int) {
+	if metadata == nil {
+		fmt.Fprintf(w, "%s%s\n", indent(depth), kind)
+		return
+	}
+	var ranges []string
+	for _, trafficRange := range metadata.TrafficRangeList {
+		if trafficRange != nil {
+			ranges = append(ranges, fmt.Sprintf("[%d,%d]", trafficRange.Left, trafficRange.Right))
+		}
+	}
+	fmt.Fprintf(w, "%s%s %s type=%v bucketSize=%d traffic=%s\n", indent(depth), kind, metadata.Key,
+		metadata.DomainType, metadata.BucketSize, strings.Join(ranges, ","))
+}
+
+func dumpLayer(w io.Writer, layer *protoccacheserver.Layer, depth int) {
+	if layer == nil {
+		return
+	}
+	fmt.Fprintf(w, "%slayer %s\n", indent(depth), layerKey(layer))
+	if layer.Metadata != nil && layer.Metadata.DefaultGroup != nil {
+		fmt.Fprintf(w, "%sdefault group %s\n", indent(depth+1), describeGroup(layer.Metadata.DefaultGroup))
+	}
+	var experimentIDs = make([]int64, 0, len(layer.ExperimentIndex))
+	for experimentID := range layer.ExperimentIndex {
+		experimentIDs = append(experimentIDs, experimentID)
+	}
+	sortInt64s(experimentIDs)
+	for _, experimentID := range experimentIDs {
+		experiment := layer.ExperimentIndex[experimentID]
+		if experiment == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%sexperiment %s (%d)\n", indent(depth+1), experiment.Key, experimentID)
+		for _, groupID := range groupIDs(experiment) {
+			if group, ok := layer.GroupIndex[groupID]; ok && group != nil {
+				fmt.Fprintf(w, "%sgroup %s\n", indent(depth+2), describeGroup(group))
+			}
+		}
+	}
+}
+
+func describeGroup(group *protoccacheserver.Group) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s (%d)", group.GroupKey, group.Id)
+	if group.IsControl {
+		builder.WriteString(" control")
+	}
+	if len(group.Params) != 0 {
+		builder.WriteString(" params: ")
+		builder.WriteString(formatParams(group.Params))
+	}
+	return builder.String()
+}
+
+func describeRemoteConfig(remoteConfig *protoccacheserver.RemoteConfig) string {
+	if remoteConfig == nil {
+		return ""
+	}
+	description := fmt.Sprintf("version=%s default=%q conditions=%d overrides=%d", remoteConfig.Version,
+		remoteConfig.DefaultValue, len(remoteConfig.ConditionList), len(remoteConfig.OverrideList))
+	if len(remoteConfig.HoldoutLayerKeys) != 0 {
+		description += " holdouts=" + strings.Join(remoteConfig.HoldoutLayerKeys, ",")
+	}
+	return description
+}
+
+func formatParams(params map[string]string) string {
+	var pairs = make([]string, 0, len(params))
+	for key, value := range params {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// groupIDs The sorted IDs of the groups of the experiment
+func groupIDs(experiment *protoccacheserver.Experiment) []int64 {
+	var result = make([]int64, 0, len(experiment.GroupIdIndex))
+	for groupID := range experiment.GroupIdIndex {
+		result = append(result, groupID)
+	}
+	sortInt64s(result)
+	return result
+}
+
+func sortInt64s(values []int64) {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/pkg/errors"
+)
+
+// evaluation The result of eval, the experiments are sorted by layerKey and the remote configs by key
+type evaluation struct {
+	ProjectID     string                `json:"projectId"`
+	Version       string                `json:"version"`
+	UnitID        string                `json:"unitId"`
+	Tags          map[string][]string   `json:"tags,omitempty"`
+	Experiments   []*assignment         `json:"experiments"`
+	RemoteConfigs []*remoteConfigResult `json:"remoteConfigs"`
+	Error         string                `json:"error,omitempty"`
+}
+
+// assignment The group the unit is assigned to on a layer
+type assignment struct {
+	LayerKey      string            `json:"layerKey"`
+	ExperimentKey string            `json:"experimentKey"`
+	GroupID       int64             `json:"groupId"`
+	GroupKey      string            `json:"groupKey"`
+	IsDefault     bool              `json:"isDefault"`
+	Reason        env.Reason        `json:"reason,omitempty"`
+	Params        map[string]string `json:"params,omitempty"`
+}
+
+// remoteConfigResult The value of a remote config for the unit
+type remoteConfigResult struct {
+	Key           string     `json:"key"`
+	Value         string     `json:"value"`
+	Reason        env.Reason `json:"reason,omitempty"`
+	ExperimentKey string     `json:"experimentKey,omitempty"`
+	GroupKey      string     `json:"groupKey,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+func runEval(ctx context.Context, args []string, stdout io.Writer) (int, error) {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	var source sourceFlags
+	source.register(fs)
+	var tags = tagFlags{}
+	fs.Var(tags, "tag", "the tag of the unit in k=v, repeatable")
+	unitID := fs.String("unit", "", "the unitID to evaluate")
+	decisionID := fs.String("decision", "", "the decisionID, for the layers hashed by decisionID")
+	disableDMP := fs.Bool("disable-dmp", false, "do not query the DMP tags")
+	asJSON := fs.Bool("json", false, "print in JSON")
+	err := fs.Parse(args)
+	if err != nil {
+		return 0, err
+	}
+	if *unitID == "" {
+		return 0, errors.New("--unit is required")
+	}
+	var local *snapshot
+	if source.snapshotPath != "" {
+		local, err = source.load(ctx)
+		if err != nil {
+			return 0, err
+		}
+		source.projectID = local.ProjectID
+	}
+	if source.projectID == "" {
+		return 0, errors.New("--project is required")
+	}
+	initCtx, cancel := context.WithTimeout(ctx, source.timeout)
+	defer cancel()
+	err = abc.Init(initCtx, []string{source.projectID}, source.initOptions(local)...)
+	if err != nil {
+		return 0, errors.Wrap(err, "init")
+	}
+	defer abc.Release()
+	attributions := []abc.Attribution{abc.WithTags(tags.copy())}
+	if *decisionID != "" {
+		attributions = append(attributions, abc.WithDecisionID(*decisionID))
+	}
+	result := evaluate(ctx, source.projectID, abc.NewUserContext(*unitID, attributions...), *disableDMP)
+	result.UnitID, result.Tags = *unitID, tags.copy()
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return 0, errors.Wrap(encoder.Encode(result), "json encode")
+	}
+	printEvaluation(stdout, result)
+	return 0, nil
+}
+
+// evaluate Evaluate all the layers and the remote configs of the project for the user,
+// nothing is reported since the evaluation is not automatic
+func evaluate(ctx context.Context, projectID string, userCtx abc.Context, disableDMP bool) *evaluation {
+	result := &evaluation{ProjectID: projectID}
+	application := cache.GetApplication(projectID)
+	if application == nil {
+		result.Error = fmt.Sprintf("projectID %s is not loaded", projectID)
+		return result
+	}
+	result.Version = application.Version
+	experiments, err := userCtx.GetExperiments(ctx, projectID, abc.WithAutomatic(false),
+		abc.WithIsDisableDMP(disableDMP))
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if experiments != nil {
+		for layerKey, group := range experiments.Data {
+			if group == nil {
+				continue
+			}
+			result.Experiments = append(result.Experiments, &assignment{
+				LayerKey:      layerKey,
+				ExperimentKey: group.ExperimentKey,
+				GroupID:       group.ID,
+				GroupKey:      group.Key,
+				IsDefault:     group.IsDefault,
+				Reason:        group.Reason,
+				Params:        group.Params(),
+			})
+		}
+	}
+	sort.Slice(result.Experiments, func(i, j int) bool {
+		return result.Experiments[i].LayerKey < result.Experiments[j].LayerKey
+	})
+	if application.TabConfig != nil && application.TabConfig.ConfigData != nil {
+		for key := range application.TabConfig.ConfigData.RemoteConfigIndex {
+			result.RemoteConfigs = append(result.RemoteConfigs, evaluateRemoteConfig(ctx, projectID, userCtx, key,
+				disableDMP))
+		}
+	}
+	sort.Slice(result.RemoteConfigs, func(i, j int) bool {
+		return result.RemoteConfigs[i].Key < result.RemoteConfigs[j].Key
+	})
+	return result
+}
+
+func evaluateRemoteConfig(ctx context.Context, projectID string, userCtx abc.Context, key string,
+	disableDMP bool) *remoteConfigResult {
+	result := &remoteConfigResult{Key: key}
+	config, err := userCtx.GetRemoteConfig(ctx, projectID, key, abc.WithAutomatic(false),
+		abc.WithIsDisableDMPConfigOpt(disableDMP))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if config == nil || config.Config == nil {
+		return result
+	}
+	if config.Value != nil {
+		result.Value = config.Value.String()
+	}
+	result.Reason = config.Reason
+	if config.Experiment != nil {
+		result.ExperimentKey, result.GroupKey = config.Experiment.ExperimentKey, config.Experiment.Key
+	}
+	return result
+}
+
+func printEvaluation(w io.Writer, result *evaluation) {
+	fmt.Fprintf(w, "project %s version %s unit %s\n", result.ProjectID, result.Version, result.UnitID)
+	if result.Error != "" {
+		fmt.Fprintf(w, "error: %s\n", result.Error)
+	}
+	fmt.Fprintln(w, "experiments")
+	for _, experiment := range result.Experiments {
+		fmt.Fprintf(w, "  %s: %s/%s (%d)", experiment.LayerKey, experiment.ExperimentKey, experiment.GroupKey,
+			experiment.GroupID)
+		if experiment.IsDefault {
+			fmt.Fprint(w, " default")
+		}
+		if experiment.Reason != "" {
+			fmt.Fprintf(w, " reason=%s", experiment.Reason)
+		}
+		if len(experiment.Params) != 0 {
+			fmt.Fprintf(w, " params: %s", formatParams(experiment.Params))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "remote configs")
+	for _, remoteConfig := range result.RemoteConfigs {
+		if remoteConfig.Error != "" {
+			fmt.Fprintf(w, "  %s: error: %s\n", remoteConfig.Key, remoteConfig.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %q", remoteConfig.Key, remoteConfig.Value)
+		if remoteConfig.Reason != "" {
+			fmt.Fprintf(w, " reason=%s", remoteConfig.Reason)
+		}
+		if remoteConfig.ExperimentKey != "" {
+			fmt.Fprintf(w, " experiment=%s/%s", remoteConfig.ExperimentKey, remoteConfig.GroupKey)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_runEval(t *testing.T) {
+	path := writeNormalSnapshot(t)
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"eval", "--snapshot", path, "--unit", "unitID", "--tag",
+		"tagKey1=ios", "--disable-dmp", "--json"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	var result evaluation
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &result))
+	assert.Equal(t, "123", result.ProjectID)
+	assert.Equal(t, "unitID", result.UnitID)
+	assert.Equal(t, map[string][]string{"tagKey1": {"ios"}}, result.Tags)
+	var groupKeys = map[string]string{}
+	for _, experiment := range result.Experiments {
+		groupKeys[experiment.LayerKey] = experiment.GroupKey
+	}
+	assert.Equal(t, "100003001", groupKeys["overrideLayer"])
+	var configValues = map[string]string{}
+	for _, remoteConfig := range result.RemoteConfigs {
+		configValues[remoteConfig.Key] = remoteConfig.Value
+	}
+	assert.Equal(t, "remoteConfig1-condition1", configValues["remoteConfig1"])
+	assert.Equal(t, "withTag-condition1", configValues["withTag"])
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"eval", "--snapshot", path, "--unit", "unitID", "--disable-dmp"},
+		&stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "project 123 version 1 unit unitID")
+	assert.Contains(t, stdout.String(), "  overrideLayer: ")
+	assert.Contains(t, stdout.String(), "  remoteConfig1: ")
+}
+
+func Test_tagFlags(t *testing.T) {
+	var tags = tagFlags{}
+	assert.Nil(t, tags.Set("k=v1"))
+	assert.Nil(t, tags.Set("k=v2=x"))
+	assert.NotNil(t, tags.Set("=v"))
+	assert.NotNil(t, tags.Set("k"))
+	assert.Equal(t, map[string][]string{"k": {"v1", "v2=x"}}, tags.copy())
+}
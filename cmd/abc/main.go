@@ -0,0 +1,305 @@
+// Command abc inspects the projects of the ABetterChoice system with the SDK internals,
+// against the live cache server or the snapshot files stored locally.
+//
+// usage:
+//
+//	abc eval --project P --unit U [--tag k=v]... [--snapshot F]
+//	abc dump --project P [--snapshot F]
+//	abc snapshot save --project P --out F
+//	abc snapshot load F
+//	abc diff [--project P] OLD NEW
+//
+// The secret key of the live cache server is read from --secret-key or the environment variable ABC_SECRET_KEY
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
+	"github.com/pkg/errors"
+)
+
+const usage = `usage: abc <command> [flags] [args]
+
+commands:
+  eval      print the experiment assignments and the remote config values of a unit
+  dump      print the domain, layer and experiment tree and the remote configs of a project
+  snapshot  save a project to a local file or load a local file
+  diff      compare two snapshots, each is a snapshot file or "live"
+
+run "abc <command> -h" for the flags of the command
+`
+
+// exitDifference The exit code of diff when the snapshots are different
+const exitDifference = 1
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run Run the command line and return the exit code
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var command func(ctx context.Context, args []string, stdout io.Writer) (int, error)
+	switch args[0] {
+	case "eval":
+		command = runEval
+	case "dump":
+		command = runDump
+	case "snapshot":
+		command = runSnapshot
+	case "diff":
+		command = runDiff
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+	code, err := command(ctx, args[1:], stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "abc %s: %v\n", args[0], err)
+		return 2
+	}
+	return code
+}
+
+// sourceFlags The flags choosing where the project data comes from
+type sourceFlags struct {
+	projectID    string
+	snapshotPath string
+	secretKey    string
+	envType      string
+	timeout      time.Duration
+	verbose      bool
+}
+
+func (s *sourceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.projectID, "project", "", "the projectID")
+	fs.StringVar(&s.snapshotPath, "snapshot", "", "the snapshot file used instead of the live cache server")
+	fs.StringVar(&s.secretKey, "secret-key", os.Getenv("ABC_SECRET_KEY"), "the secret key of the live cache server")
+	fs.StringVar(&s.envType, "env", env.TypePrd, "the environment of the live cache server, prd or test")
+	fs.DurationVar(&s.timeout, "timeout", 10*time.Second, "the timeout of the live cache server")
+	fs.BoolVar(&s.verbose, "verbose", false, "print the SDK logs")
+}
+
+// initOptions The init options of the SDK for the source, the reports are always disabled
+func (s *sourceFlags) initOptions(local *snapshot) []abc.InitOption {
+	if s.verbose {
+		log.SetLoggerLevel(log.InfoLevel)
+	}
+	opts := []abc.InitOption{abc.WithDisableReport(true)}
+	if local != nil {
+		return append(opts, abc.WithRegisterCacheClient(&snapshotClient{s: local}))
+	}
+	return append(opts, abc.WithSecretKey(s.secretKey), abc.WithEnvType(s.envType))
+}
+
+// load Load the project from the snapshot file, or from the live cache server if there is no snapshot file
+func (s *sourceFlags) load(ctx context.Context) (*snapshot, error) {
+	if s.snapshotPath != "" {
+		local, err := readSnapshot(s.snapshotPath)
+		if err != nil {
+			return nil, err
+		}
+		if s.projectID != "" && s.projectID != local.ProjectID {
+			return nil, errors.Errorf("snapshot %s is of project %s, not %s", s.snapshotPath, local.ProjectID,
+				s.projectID)
+		}
+		return local, nil
+	}
+	return s.loadLive(ctx)
+}
+
+// loadLive Load the project from the live cache server
+func (s *sourceFlags) loadLive(ctx context.Context) (*snapshot, error) {
+	if s.projectID == "" {
+		return nil, errors.New("--project is required")
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	err := abc.Init(ctx, []string{s.projectID}, s.initOptions(nil)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "init")
+	}
+	defer abc.Release()
+	application := cache.GetApplication(s.projectID)
+	if application == nil {
+		return nil, errors.Errorf("projectID %s is not loaded", s.projectID)
+	}
+	return newSnapshot(application), nil
+}
+
+// tagFlags The repeatable --tag k=v flag, the values of the same key are accumulated
+type tagFlags map[string][]string
+
+// String flag.Value
+func (t tagFlags) String() string {
+	var pairs []string
+	for key, values := range t {
+		for _, value := range values {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set flag.Value
+func (t tagFlags) Set(value string) error {
+	index := strings.Index(value, "=")
+	if index <= 0 {
+		return errors.Errorf("invalid tag %q, k=v is required", value)
+	}
+	key := value[:index]
+	t[key] = append(t[key], value[index+1:])
+	return nil
+}
+
+// copy The copy of the tags, the user context may write the DMP tag results into the tags it is given
+func (t tagFlags) copy() map[string][]string {
+	var result = make(map[string][]string, len(t))
+	for key, values := range t {
+		result[key] = append([]string(nil), values...)
+	}
+	return result
+}
+
+func runDump(ctx context.Context, args []string, stdout io.Writer) (int, error) {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	var source sourceFlags
+	source.register(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return 0, err
+	}
+	s, err := source.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	dump(stdout, s)
+	return 0, nil
+}
+
+func runSnapshot(ctx context.Context, args []string, stdout io.Writer) (int, error) {
+	if len(args) == 0 {
+		return 0, errors.New("save or load is required")
+	}
+	switch args[0] {
+	case "save":
+		return runSnapshotSave(ctx, args[1:], stdout)
+	case "load":
+		return runSnapshotLoad(ctx, args[1:], stdout)
+	default:
+		return 0, errors.Errorf("unknown snapshot command %q, save or load is required", args[0])
+	}
+}
+
+func runSnapshotSave(ctx context.Context, args []string, stdout io.Writer) (int, error) {
+	fs := flag.NewFlagSet("snapshot save", flag.ContinueOnError)
+	var source sourceFlags
+	source.register(fs)
+	out := fs.String("out", "", "the snapshot file to write, default {{project}}.snapshot.json")
+	err := fs.Parse(args)
+	if err != nil {
+		return 0, err
+	}
+	s, err := source.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	path := *out
+	if path == "" {
+		path = s.ProjectID + ".snapshot.json"
+	}
+	err = writeSnapshot(path, s)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Fprintf(stdout, "saved project %s version %s to %s\n", s.ProjectID, s.Version, path)
+	return 0, nil
+}
+
+// runSnapshotLoad Load the snapshot file into the SDK to check that it can be evaluated and print its summary
+func runSnapshotLoad(ctx context.Context, args []string, stdout io.Writer) (int, error) {
+	fs := flag.NewFlagSet("snapshot load", flag.ContinueOnError)
+	var source sourceFlags
+	source.register(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return 0, err
+	}
+	if fs.NArg() != 1 {
+		return 0, errors.New("the snapshot file is required")
+	}
+	s, err := readSnapshot(fs.Arg(0))
+	if err != nil {
+		return 0, err
+	}
+	err = abc.Init(ctx, []string{s.ProjectID}, source.initOptions(s)...)
+	if err != nil {
+		return 0, errors.Wrap(err, "init")
+	}
+	defer abc.Release()
+	application := cache.GetApplication(s.ProjectID)
+	if application == nil {
+		return 0, errors.Errorf("projectID %s is not loaded", s.ProjectID)
+	}
+	var layerCount, remoteConfigCount int
+	if s.TabConfig.ExperimentData != nil {
+		walkLayers(s.TabConfig.ExperimentData.GlobalDomain, nil, func([]string, *protoccacheserver.Layer) {
+			layerCount++
+		})
+	}
+	if s.TabConfig.ConfigData != nil {
+		remoteConfigCount = len(s.TabConfig.ConfigData.RemoteConfigIndex)
+	}
+	fmt.Fprintf(stdout, "project %s version %s saved at %s by SDK %s\n", s.ProjectID, s.Version,
+		s.SavedAt.Format(time.RFC3339), s.SDKVersion)
+	fmt.Fprintf(stdout, "layers %d, remote configs %d\n", layerCount, remoteConfigCount)
+	return 0, nil
+}
+
+func runDiff(ctx context.Context, args []string, stdout io.Writer) (int, error) {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	var source sourceFlags
+	source.register(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return 0, err
+	}
+	if fs.NArg() != 2 {
+		return 0, errors.New("OLD and NEW are required, each is a snapshot file or live")
+	}
+	var snapshots = make([]*snapshot, 2)
+	for i, arg := range fs.Args() {
+		if arg == "live" {
+			snapshots[i], err = source.loadLive(ctx)
+		} else {
+			snapshots[i], err = readSnapshot(arg)
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, arg)
+		}
+	}
+	if diff(stdout, snapshots[0], snapshots[1]) {
+		return exitDifference, nil
+	}
+	return 0, nil
+}
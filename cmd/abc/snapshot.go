@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
+	"github.com/pkg/errors"
+)
+
+// snapshot The data of a project stored locally, it holds everything the local cache is built from,
+// so that the project can be evaluated, dumped and compared without the cache server
+type snapshot struct {
+	ProjectID  string                       `json:"projectId"`
+	Version    string                       `json:"version"`
+	SavedAt    time.Time                    `json:"savedAt"`
+	SDKVersion string                       `json:"sdkVersion"`
+	TabConfig  *protoccacheserver.TabConfig `json:"tabConfig"`
+	// ExperimentBuckets The bucket information of the experiments on the double hash layers, the key is experimentID
+	ExperimentBuckets map[int64]*protoccacheserver.BucketInfo `json:"experimentBuckets"`
+	// GroupBuckets The bucket information of the groups, the key is groupID
+	GroupBuckets map[int64]*protoccacheserver.BucketInfo `json:"groupBuckets"`
+}
+
+// newSnapshot Take the snapshot of the local cache data
+func newSnapshot(application *cache.Application) *snapshot {
+	return &snapshot{
+		ProjectID:         application.ProjectID,
+		Version:           application.Version,
+		SavedAt:           time.Now(),
+		SDKVersion:        env.SDKVersion,
+		TabConfig:         application.TabConfig,
+		ExperimentBuckets: application.ExperimentIDBucketInfoIndex,
+		GroupBuckets:      application.GroupIDBucketInfoIndex,
+	}
+}
+
+// readSnapshot Read the snapshot file written by writeSnapshot
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read file")
+	}
+	s := &snapshot{}
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "json unmarshal %s", path)
+	}
+	if s.ProjectID == "" || s.TabConfig == nil {
+		return nil, errors.Errorf("invalid snapshot %s, projectId and tabConfig are required", path)
+	}
+	return s, nil
+}
+
+// writeSnapshot Write the snapshot file in JSON
+func writeSnapshot(path string, s *snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json marshal")
+	}
+	return errors.Wrap(ioutil.WriteFile(path, data, 0644), "write file")
+}
+
+// snapshotClient The cache server client serving the data of the snapshot, it never changes
+type snapshotClient struct {
+	s *snapshot
+}
+
+// GetTabConfigData The tabConfig of the snapshot, the same version is reported if the caller already has it
+func (c *snapshotClient) GetTabConfigData(ctx context.Context, req *protoccacheserver.GetTabConfigReq) (
+	*protoccacheserver.GetTabConfigResp, error) {
+	if req.ProjectId != c.s.ProjectID {
+		return nil, errors.Errorf("projectID %s is not in the snapshot of %s", req.ProjectId, c.s.ProjectID)
+	}
+	if req.Version == c.s.Version && req.Version != "" {
+		return &protoccacheserver.GetTabConfigResp{Code: protoccacheserver.Code_CODE_SAME_VERSION}, nil
+	}
+	return &protoccacheserver.GetTabConfigResp{
+		Code: protoccacheserver.Code_CODE_SUCCESS,
+		TabConfigManager: &protoccacheserver.TabConfigManager{
+			ProjectId:  c.s.ProjectID,
+			UpdateType: req.UpdateType,
+			TabConfig:  c.s.TabConfig,
+			Version:    c.s.Version,
+		},
+	}, nil
+}
+
+// BatchGetExperimentBucketInfo The requested experiment bucket information of the snapshot
+func (c *snapshotClient) BatchGetExperimentBucketInfo(ctx context.Context,
+	req *protoccacheserver.BatchGetExperimentBucketReq) (*protoccacheserver.BatchGetExperimentBucketResp, error) {
+	return &protoccacheserver.BatchGetExperimentBucketResp{
+		Code:        protoccacheserver.Code_CODE_SUCCESS,
+		BucketIndex: selectBuckets(c.s.ExperimentBuckets, req.BucketVersionIndex),
+	}, nil
+}
+
+// BatchGetGroupBucketInfo The requested group bucket information of the snapshot
+func (c *snapshotClient) BatchGetGroupBucketInfo(ctx context.Context,
+	req *protoccacheserver.BatchGetGroupBucketReq) (*protoccacheserver.BatchGetGroupBucketResp, error) {
+	return &protoccacheserver.BatchGetGroupBucketResp{
+		Code:        protoccacheserver.Code_CODE_SUCCESS,
+		BucketIndex: selectBuckets(c.s.GroupBuckets, req.BucketVersionIndex),
+	}, nil
+}
+
+func selectBuckets(buckets map[int64]*protoccacheserver.BucketInfo,
+	versionIndex map[int64]string) map[int64]*protoccacheserver.BucketInfo {
+	var result = make(map[int64]*protoccacheserver.BucketInfo, len(versionIndex))
+	for id := range versionIndex {
+		if bucketInfo, ok := buckets[id]; ok && bucketInfo != nil {
+			result[id] = bucketInfo
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+func normalSnapshot() *snapshot {
+	return &snapshot{
+		ProjectID:         "123",
+		Version:           "1",
+		SavedAt:           time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		TabConfig:         testdata.NormalTabConfig,
+		ExperimentBuckets: testdata.NormalExperimentBucketInfo,
+		GroupBuckets:      testdata.NormalGroupBucketInfo,
+	}
+}
+
+func writeNormalSnapshot(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "123.snapshot.json")
+	assert.Nil(t, writeSnapshot(path, normalSnapshot()))
+	return path
+}
+
+func Test_readSnapshot(t *testing.T) {
+	path := writeNormalSnapshot(t)
+	s, err := readSnapshot(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "123", s.ProjectID)
+	assert.Equal(t, "1", s.Version)
+	assert.True(t, s.SavedAt.Equal(normalSnapshot().SavedAt))
+	assert.Equal(t, len(testdata.NormalTabConfig.ConfigData.RemoteConfigIndex),
+		len(s.TabConfig.ConfigData.RemoteConfigIndex))
+	assert.Equal(t, len(testdata.NormalGroupBucketInfo), len(s.GroupBuckets))
+
+	_, err = readSnapshot(filepath.Join(t.TempDir(), "absent.json"))
+	assert.NotNil(t, err)
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	assert.Nil(t, writeSnapshot(invalid, &snapshot{}))
+	_, err = readSnapshot(invalid)
+	assert.NotNil(t, err)
+}
+
+func Test_runSnapshot(t *testing.T) {
+	path := writeNormalSnapshot(t)
+	out := filepath.Join(t.TempDir(), "copy.json")
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"snapshot", "save", "--snapshot", path, "--out", out}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "saved project 123 version 1 to "+out)
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"snapshot", "load", out}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "project 123 version 1 saved at 2024-01-02T03:04:05Z")
+	assert.Contains(t, stdout.String(), "remote configs")
+
+	stderr.Reset()
+	code = run(context.Background(), []string{"snapshot", "save", "--snapshot", path, "--project", "456"},
+		&stdout, &stderr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), "is of project 123, not 456")
+}
@@ -0,0 +1,91 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"context"
+
+	"github.com/abetterchoice/go-sdk/env"
+)
+
+type userContextKey struct{}
+
+// NewContext Attach the user context to ctx, so that it travels with ctx through the call stack
+// and can be obtained by FromContext or used directly by the package-level GetExperiment etc.
+func NewContext(ctx context.Context, userCtx Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, userContextKey{}, userCtx)
+}
+
+// FromContext The user context attached to ctx by NewContext, false if none is attached
+func FromContext(ctx context.Context) (Context, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	userCtx, ok := ctx.Value(userContextKey{}).(Context)
+	return userCtx, ok && userCtx != nil
+}
+
+// mustFromContext The user context attached to ctx, env.ErrUserContextNotFound is returned if none is attached
+func mustFromContext(ctx context.Context) (Context, error) {
+	userCtx, ok := FromContext(ctx)
+	if !ok {
+		return nil, env.ErrUserContextNotFound
+	}
+	return userCtx, nil
+}
+
+// GetExperiment Context.GetExperiment of the user context attached to ctx,
+// env.ErrUserContextNotFound is returned if none is attached
+func GetExperiment(ctx context.Context, projectID string, layerKey string,
+	opts ...ExperimentOption) (*ExperimentResult, error) {
+	userCtx, err := mustFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return userCtx.GetExperiment(ctx, projectID, layerKey, opts...)
+}
+
+// GetExperiments Context.GetExperiments of the user context attached to ctx,
+// env.ErrUserContextNotFound is returned if none is attached
+func GetExperiments(ctx context.Context, projectID string, opts ...ExperimentOption) (*ExperimentList, error) {
+	userCtx, err := mustFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return userCtx.GetExperiments(ctx, projectID, opts...)
+}
+
+// GetFeatureFlag Context.GetFeatureFlag of the user context attached to ctx,
+// env.ErrUserContextNotFound is returned if none is attached
+func GetFeatureFlag(ctx context.Context, projectID string, key string, opts ...ConfigOption) (*FeatureFlag, error) {
+	userCtx, err := mustFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return userCtx.GetFeatureFlag(ctx, projectID, key, opts...)
+}
+
+// GetValueByVariantKey Context.GetValueByVariantKey of the user context attached to ctx,
+// env.ErrUserContextNotFound is returned if none is attached
+func GetValueByVariantKey(ctx context.Context, projectID string, key string,
+	opts ...ExperimentOption) (*ValueResult, error) {
+	userCtx, err := mustFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return userCtx.GetValueByVariantKey(ctx, projectID, key, opts...)
+}
+
+// GetRemoteConfig Context.GetRemoteConfig of the user context attached to ctx,
+// env.ErrUserContextNotFound is returned if none is attached. GetFeatureFlag is preferred
+func GetRemoteConfig(ctx context.Context, projectID string, key string, opts ...ConfigOption) (*ConfigResult, error) {
+	userCtx, err := mustFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return userCtx.GetRemoteConfig(ctx, projectID, key, opts...)
+}
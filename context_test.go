@@ -0,0 +1,75 @@
+package abc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/pkg/errors"
+)
+
+func TestFromContext(t *testing.T) {
+	userCtx := NewUserContext("unitID")
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   Context
+		wantOK bool
+	}{
+		{name: "nil context", ctx: nil},
+		{name: "not attached", ctx: context.Background()},
+		{name: "attached", ctx: NewContext(context.Background(), userCtx), want: userCtx, wantOK: true},
+		{name: "attached to nil context", ctx: NewContext(nil, userCtx), want: userCtx, wantOK: true},
+		{name: "nil user context", ctx: NewContext(context.Background(), nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromContext(tt.ctx)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("FromContext() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestContextHelpers(t *testing.T) {
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "not attached", ctx: context.Background(), wantErr: env.ErrUserContextNotFound},
+		{name: "attached", ctx: NewContext(context.Background(), NewUserContext("unitID"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			experimentResult, err := GetExperiment(tt.ctx, projectID, "overrideLayer")
+			if !errors.Is(err, tt.wantErr) || (err == nil && experimentResult == nil) {
+				t.Errorf("GetExperiment() = %v, %v, wantErr %v", experimentResult, err, tt.wantErr)
+			}
+			experimentList, err := GetExperiments(tt.ctx, projectID, WithLayerKey("overrideLayer"))
+			if !errors.Is(err, tt.wantErr) || (err == nil && experimentList == nil) {
+				t.Errorf("GetExperiments() = %v, %v, wantErr %v", experimentList, err, tt.wantErr)
+			}
+			featureFlag, err := GetFeatureFlag(tt.ctx, projectID, "remoteConfig1")
+			if !errors.Is(err, tt.wantErr) || (err == nil && featureFlag == nil) {
+				t.Errorf("GetFeatureFlag() = %v, %v, wantErr %v", featureFlag, err, tt.wantErr)
+			}
+			configResult, err := GetRemoteConfig(tt.ctx, projectID, "remoteConfig1")
+			if !errors.Is(err, tt.wantErr) || (err == nil && configResult == nil) {
+				t.Errorf("GetRemoteConfig() = %v, %v, wantErr %v", configResult, err, tt.wantErr)
+			}
+			valueResult, err := GetValueByVariantKey(tt.ctx, projectID, "remoteConfig1")
+			if !errors.Is(err, tt.wantErr) || (err == nil && valueResult == nil) {
+				t.Errorf("GetValueByVariantKey() = %v, %v, wantErr %v", valueResult, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
+)
+
+// DebugHandler The http.Handler of the debug pages, which is meant to be mounted on the admin port.
+// The pages list the loaded projects with the version and the refresh status, browse the layers, experiments,
+// groups, remote configurations and holdouts of the local cache, and evaluate a unit ID with tags.
+// The evaluation page never logs the exposure. Every page returns JSON with the query parameter format=json.
+//
+// The handler serves the paths /, /project?project=P and /evaluate?project=P&unit=U&tag=k=v,
+// mount it with a trailing slash so that the relative links work:
+//
+//	http.Handle("/debug/abc/", http.StripPrefix("/debug/abc", abc.DebugHandler()))
+func DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", debugIndex)
+	mux.HandleFunc("/project", debugProject)
+	mux.HandleFunc("/evaluate", debugEvaluate)
+	return mux
+}
+
+// DebugProjectSummary The loaded project listed by the debug index page
+type DebugProjectSummary struct {
+	ProjectID         string              `json:"projectId"`
+	Version           string              `json:"version"`
+	Refresh           cache.RefreshStatus `json:"refresh"`
+	LayerCount        int                 `json:"layerCount"`
+	RemoteConfigCount int                 `json:"remoteConfigCount"`
+}
+
+// DebugProject The local cache data of a project shown by the debug project page
+type DebugProject struct {
+	DebugProjectSummary
+	Layers        []*DebugLayer        `json:"layers"`   // sorted by key
+	Holdouts      []*DebugLayer        `json:"holdouts"` // holdout layers, sorted by key
+	RemoteConfigs []*DebugRemoteConfig `json:"remoteConfigs"`
+}
+
+// DebugLayer The layer with its experiments and groups
+type DebugLayer struct {
+	Key              string             `json:"key"`
+	DomainKeys       []string           `json:"domainKeys"` // the parent domains from top to bottom
+	DefaultGroup     *DebugGroup        `json:"defaultGroup"`
+	HoldoutLayerKeys []string           `json:"holdoutLayerKeys"`
+	Experiments      []*DebugExperiment `json:"experiments"` // sorted by ID
+}
+
+// DebugExperiment The experiment with its groups
+type DebugExperiment struct {
+	ID     int64         `json:"id"`
+	Key    string        `json:"key"`
+	Groups []*DebugGroup `json:"groups"` // sorted by ID
+}
+
+// DebugGroup The experiment group and its params
+type DebugGroup struct {
+	ID        int64             `json:"id"`
+	Key       string            `json:"key"`
+	IsControl bool              `json:"isControl"`
+	IsDefault bool              `json:"isDefault"`
+	Params    map[string]string `json:"params"`
+}
+
+// DebugRemoteConfig The remote configuration
+type DebugRemoteConfig struct {
+	Key              string   `json:"key"`
+	DefaultValue     string   `json:"defaultValue"`
+	Version          string   `json:"version"`
+	OverrideCount    int      `json:"overrideCount"`
+	ConditionCount   int      `json:"conditionCount"`
+	HoldoutLayerKeys []string `json:"holdoutLayerKeys"`
+}
+
+// DebugEvaluation The evaluation result of the debug evaluate page
+type DebugEvaluation struct {
+	ProjectID     string              `json:"projectId"`
+	UnitID        string              `json:"unitId"`
+	Tags          map[string][]string `json:"tags"`
+	Error         string              `json:"error"`
+	Experiments   []*DebugAssignment  `json:"experiments"`   // sorted by layer key
+	RemoteConfigs []*DebugConfigValue `json:"remoteConfigs"` // sorted by key
+}
+
+// DebugAssignment The group assigned on a layer
+type DebugAssignment struct {
+	LayerKey      string            `json:"layerKey"`
+	ExperimentKey string            `json:"experimentKey"`
+	GroupID       int64             `json:"groupId"`
+	GroupKey      string            `json:"groupKey"`
+	IsDefault     bool              `json:"isDefault"`
+	Reason        env.Reason        `json:"reason"`
+	Params        map[string]string `json:"params"`
+}
+
+// DebugConfigValue The value of a remote configuration
+type DebugConfigValue struct {
+	Key           string     `json:"key"`
+	Value         string     `json:"value"`
+	Reason        env.Reason `json:"reason"`
+	ExperimentKey string     `json:"experimentKey"`
+	GroupKey      string     `json:"groupKey"`
+	Error         string     `json:"error"`
+}
+
+func debugIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	var projects = make([]*DebugProjectSummary, 0)
+	for _, projectID := range cache.ProjectIDList() {
+		application := cache.GetApplication(projectID)
+		if application == nil {
+			continue
+		}
+		projects = append(projects, newDebugProjectSummary(application))
+	}
+	writeDebugPage(w, r, "index", projects)
+}
+
+func debugProject(w http.ResponseWriter, r *http.Request) {
+	application, ok := debugApplication(w, r)
+	if !ok {
+		return
+	}
+	project := &DebugProject{DebugProjectSummary: *newDebugProjectSummary(application)}
+	for layerKey, layer := range application.LayerIndex {
+		project.Layers = append(project.Layers, newDebugLayer(layerKey, layer, application))
+	}
+	sort.Slice(project.Layers, func(i, j int) bool { return project.Layers[i].Key < project.Layers[j].Key })
+	experimentData := application.TabConfig.ExperimentData
+	if experimentData != nil && experimentData.HoldoutData != nil {
+		for layerKey, layer := range experimentData.HoldoutData.HoldoutLayerIndex {
+			project.Holdouts = append(project.Holdouts, newDebugLayer(layerKey, layer, application))
+		}
+	}
+	sort.Slice(project.Holdouts, func(i, j int) bool { return project.Holdouts[i].Key < project.Holdouts[j].Key })
+	for key, remoteConfig := range remoteConfigIndex(application) {
+		if remoteConfig == nil {
+			continue
+		}
+		project.RemoteConfigs = append(project.RemoteConfigs, &DebugRemoteConfig{
+			Key:              key,
+			DefaultValue:     string(remoteConfig.DefaultValue),
+			Version:          remoteConfig.Version,
+			OverrideCount:    len(remoteConfig.OverrideList),
+			ConditionCount:   len(remoteConfig.ConditionList),
+			HoldoutLayerKeys: remoteConfig.HoldoutLayerKeys,
+		})
+	}
+	sort.Slice(project.RemoteConfigs, func(i, j int) bool {
+		return project.RemoteConfigs[i].Key < project.RemoteConfigs[j].Key
+	})
+	writeDebugPage(w, r, "project", project)
+}
+
+func debugEvaluate(w http.ResponseWriter, r *http.Request) {
+	application, ok := debugApplication(w, r)
+	if !ok {
+		return
+	}
+	evaluation := &DebugEvaluation{
+		ProjectID: application.ProjectID,
+		UnitID:    strings.TrimSpace(r.FormValue("unit")),
+		Tags:      parseDebugTags(r.Form["tag"]),
+	}
+	if evaluation.UnitID == "" { // show the form only
+		writeDebugPage(w, r, "evaluate", evaluation)
+		return
+	}
+	// the user context keeps the tags and may add the DMP tag results to them, so it is given its own copy
+	userCtx := NewUserContext(evaluation.UnitID, WithTags(parseDebugTags(r.Form["tag"])))
+	experiments, err := userCtx.GetExperiments(r.Context(), application.ProjectID, WithAutomatic(false))
+	if err != nil {
+		evaluation.Error = err.Error()
+	}
+	if experiments != nil {
+		for layerKey, group := range experiments.Data {
+			if group == nil {
+				continue
+			}
+			evaluation.Experiments = append(evaluation.Experiments, &DebugAssignment{
+				LayerKey:      layerKey,
+				ExperimentKey: group.ExperimentKey,
+				GroupID:       group.ID,
+				GroupKey:      group.Key,
+				IsDefault:     group.IsDefault,
+				Reason:        group.Reason,
+				Params:        group.params,
+			})
+		}
+	}
+	sort.Slice(evaluation.Experiments, func(i, j int) bool {
+		return evaluation.Experiments[i].LayerKey < evaluation.Experiments[j].LayerKey
+	})
+	for key := range remoteConfigIndex(application) {
+		evaluation.RemoteConfigs = append(evaluation.RemoteConfigs, debugConfigValue(r, userCtx, application.ProjectID, key))
+	}
+	sort.Slice(evaluation.RemoteConfigs, func(i, j int) bool {
+		return evaluation.RemoteConfigs[i].Key < evaluation.RemoteConfigs[j].Key
+	})
+	writeDebugPage(w, r, "evaluate", evaluation)
+}
+
+func debugConfigValue(r *http.Request, userCtx Context, projectID string, key string) *DebugConfigValue {
+	value := &DebugConfigValue{Key: key}
+	result, err := userCtx.GetRemoteConfig(r.Context(), projectID, key, WithAutomatic(false))
+	if err != nil {
+		value.Error = err.Error()
+		return value
+	}
+	if result == nil || result.Config == nil {
+		return value
+	}
+	value.Value = result.Value.String()
+	value.Reason = result.Reason
+	if result.Experiment != nil {
+		value.ExperimentKey = result.Experiment.ExperimentKey
+		value.GroupKey = result.Experiment.Key
+	}
+	return value
+}
+
+// debugApplication The local cache of the project in the query, the error response is written if absent
+func debugApplication(w http.ResponseWriter, r *http.Request) (*cache.Application, bool) {
+	projectID := r.FormValue("project")
+	if projectID == "" {
+		http.Error(w, "project is required", http.StatusBadRequest)
+		return nil, false
+	}
+	application := cache.GetApplication(projectID)
+	if application == nil || application.TabConfig == nil {
+		http.Error(w, "project "+projectID+" is not loaded", http.StatusNotFound)
+		return nil, false
+	}
+	return application, true
+}
+
+func newDebugProjectSummary(application *cache.Application) *DebugProjectSummary {
+	refresh, _ := cache.GetRefreshStatus(application.ProjectID)
+	return &DebugProjectSummary{
+		ProjectID:         application.ProjectID,
+		Version:           application.Version,
+		Refresh:           refresh,
+		LayerCount:        len(application.LayerIndex),
+		RemoteConfigCount: len(remoteConfigIndex(application)),
+	}
+}
+
+func newDebugLayer(layerKey string, layer *protoccacheserver.Layer, application *cache.Application) *DebugLayer {
+	result := &DebugLayer{Key: layerKey}
+	for _, metadata := range application.LayerDomainMetadataListIndex[layerKey] {
+		if metadata != nil {
+			result.DomainKeys = append(result.DomainKeys, metadata.Key)
+		}
+	}
+	if layer == nil {
+		return result
+	}
+	if layer.Metadata != nil {
+		result.HoldoutLayerKeys = layer.Metadata.HoldoutLayerKeys
+		if layer.Metadata.DefaultGroup != nil {
+			result.DefaultGroup = newDebugGroup(layer.Metadata.DefaultGroup)
+		}
+	}
+	for experimentID, experiment := range layer.ExperimentIndex {
+		if experiment == nil {
+			continue
+		}
+		debugExperiment := &DebugExperiment{ID: experimentID, Key: experiment.Key}
+		for groupID := range experiment.GroupIdIndex {
+			if group, ok := layer.GroupIndex[groupID]; ok && group != nil {
+				debugExperiment.Groups = append(debugExperiment.Groups, newDebugGroup(group))
+			}
+		}
+		sort.Slice(debugExperiment.Groups, func(i, j int) bool {
+			return debugExperiment.Groups[i].ID < debugExperiment.Groups[j].ID
+		})
+		result.Experiments = append(result.Experiments, debugExperiment)
+	}
+	sort.Slice(result.Experiments, func(i, j int) bool { return result.Experiments[i].ID < result.Experiments[j].ID })
+	return result
+}
+
+func newDebugGroup(group *protoccacheserver.Group) *DebugGroup {
+	return &DebugGroup{
+		ID:        group.Id,
+		Key:       group.GroupKey,
+		IsControl: group.IsControl,
+		IsDefault: group.IsDefault,
+		Params:    group.Params,
+	}
+}
+
+func remoteConfigIndex(application *cache.Application) map[string]*protoccacheserver.RemoteConfig {
+	if application.TabConfig == nil || application.TabConfig.ConfigData == nil {
+		return nil
+	}
+	return application.TabConfig.ConfigData.RemoteConfigIndex
+}
+
+// parseDebugTags Parse the tags in the form of tagKey=tagValue, a value may hold several tags separated by newlines
+func parseDebugTags(values []string) map[string][]string {
+	var tags = make(map[string][]string)
+	for _, value := range values {
+		for _, line := range strings.Split(value, "\n") {
+			kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				continue
+			}
+			tags[kv[0]] = append(tags[kv[0]], kv[1])
+		}
+	}
+	return tags
+}
+
+// writeDebugPage Write data as JSON with the query parameter format=json, or render the page otherwise
+func writeDebugPage(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	if r.FormValue("format") == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(data)
+		if err != nil {
+			log.Errorf("write debug page %s fail:%v", name, err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := debugTemplates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		log.Errorf("render debug page %s fail:%v", name, err)
+	}
+}
+
+var debugTemplates = template.Must(template.New("debug").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	},
+	"join": strings.Join,
+	"tagLines": func(tags map[string][]string) string {
+		var lines []string
+		for key, values := range tags {
+			for _, value := range values {
+				lines = append(lines, key+"="+value)
+			}
+		}
+		sort.Strings(lines)
+		return strings.Join(lines, "\n")
+	},
+}).Parse(debugTemplateText))
+
+const debugTemplateText = `
+{{define "header"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>ABetterChoice SDK</title>
+<style>body{font-family:sans-serif;margin:1em 2em}table{border-collapse:collapse;margin-bottom:1em}
+td,th{border:1px solid #ccc;padding:2px 8px;text-align:left;vertical-align:top}.error{color:#c00}</style>
+</head><body><p><a href="./">projects</a></p>{{end}}
+
+{{define "footer"}}</body></html>{{end}}
+
+{{define "params"}}{{range $key, $value := .}}{{$key}}={{$value}}<br>{{end}}{{end}}
+
+{{define "index"}}{{template "header"}}<h1>Projects</h1>
+<table><tr><th>projectID</th><th>version</th><th>layers</th><th>remote configs</th><th>last refresh</th>
+<th>last success</th><th>last modified</th><th>failures</th><th>last error</th><th></th></tr>
+{{range .}}<tr><td><a href="project?project={{.ProjectID}}">{{.ProjectID}}</a></td><td>{{.Version}}</td>
+<td>{{.LayerCount}}</td><td>{{.RemoteConfigCount}}</td><td>{{formatTime .Refresh.LastRefreshTime}}</td>
+<td>{{formatTime .Refresh.LastSuccessTime}}</td><td>{{formatTime .Refresh.LastModifiedTime}}</td>
+<td>{{.Refresh.ConsecutiveFailures}}</td><td class="error">{{.Refresh.LastError}}</td>
+<td><a href="evaluate?project={{.ProjectID}}">evaluate</a></td></tr>
+{{end}}</table>{{template "footer"}}{{end}}
+
+{{define "layers"}}{{range .}}<h3>{{.Key}}</h3>
+<p>domains: {{join .DomainKeys " / "}}{{if .HoldoutLayerKeys}}, holdouts: {{join .HoldoutLayerKeys ", "}}{{end}}</p>
+<table><tr><th>experiment</th><th>group ID</th><th>group key</th><th>control</th><th>params</th></tr>
+{{with .DefaultGroup}}<tr><td>(default)</td><td>{{.ID}}</td><td>{{.Key}}</td><td></td>
+<td>{{template "params" .Params}}</td></tr>{{end}}
+{{range .Experiments}}{{$experiment := .}}{{range .Groups}}<tr><td>{{$experiment.Key}} ({{$experiment.ID}})</td>
+<td>{{.ID}}</td><td>{{.Key}}</td><td>{{if .IsControl}}yes{{end}}</td><td>{{template "params" .Params}}</td></tr>
+{{end}}{{end}}</table>{{end}}{{end}}
+
+{{define "project"}}{{template "header"}}<h1>Project {{.ProjectID}}</h1>
+<p>version {{.Version}}, last refresh {{formatTime .Refresh.LastRefreshTime}}
+{{with .Refresh.LastError}}<span class="error">{{.}}</span>{{end}},
+<a href="evaluate?project={{.ProjectID}}">evaluate</a></p>
+<h2>Layers</h2>{{template "layers" .Layers}}
+<h2>Holdouts</h2>{{template "layers" .Holdouts}}
+<h2>Remote configs</h2>
+<table><tr><th>key</th><th>default value</th><th>version</th><th>overrides</th><th>conditions</th><th>holdouts</th></tr>
+{{range .RemoteConfigs}}<tr><td>{{.Key}}</td><td>{{.DefaultValue}}</td><td>{{.Version}}</td><td>{{.OverrideCount}}</td>
+<td>{{.ConditionCount}}</td><td>{{join .HoldoutLayerKeys ", "}}</td></tr>
+{{end}}</table>{{template "footer"}}{{end}}
+
+{{define "evaluate"}}{{template "header"}}<h1>Evaluate {{.ProjectID}}</h1>
+<form method="get" action="evaluate"><input type="hidden" name="project" value="{{.ProjectID}}">
+<p>unit ID <input name="unit" value="{{.UnitID}}"></p>
+<p>tags, one tagKey=tagValue per line<br><textarea name="tag" rows="4" cols="40">{{tagLines .Tags}}</textarea></p>
+<p><input type="submit" value="evaluate"> no exposure is logged</p></form>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+{{if .UnitID}}<h2>Experiments</h2>
+<table><tr><th>layer</th><th>experiment</th><th>group ID</th><th>group key</th><th>reason</th><th>params</th></tr>
+{{range .Experiments}}<tr><td>{{.LayerKey}}</td><td>{{.ExperimentKey}}</td><td>{{.GroupID}}</td><td>{{.GroupKey}}</td>
+<td>{{.Reason}}</td><td>{{template "params" .Params}}</td></tr>
+{{end}}</table>
+<h2>Remote configs</h2>
+<table><tr><th>key</th><th>value</th><th>reason</th><th>experiment</th><th>group key</th></tr>
+{{range .RemoteConfigs}}<tr><td>{{.Key}}</td><td>{{.Value}}{{with .Error}}<span class="error">{{.}}</span>{{end}}</td>
+<td>{{.Reason}}</td><td>{{.ExperimentKey}}</td><td>{{.GroupKey}}</td></tr>
+{{end}}</table>{{end}}{{template "footer"}}{{end}}
+`
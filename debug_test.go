@@ -0,0 +1,103 @@
+package abc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveDebug(t *testing.T, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler := http.StripPrefix("/debug/abc", DebugHandler())
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func TestDebugHandler(t *testing.T) {
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+
+	recorder := serveDebug(t, "/debug/abc/?format=json")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var projects []*DebugProjectSummary
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &projects))
+	assert.Len(t, projects, 1)
+	assert.Equal(t, projectID, projects[0].ProjectID)
+	assert.Equal(t, applicationVersion(cache.GetApplication(projectID)), projects[0].Version)
+	assert.False(t, projects[0].Refresh.LastSuccessTime.IsZero())
+	assert.Empty(t, projects[0].Refresh.LastError)
+
+	recorder = serveDebug(t, "/debug/abc/project?format=json&project="+projectID)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var project DebugProject
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &project))
+	assert.Equal(t, projects[0].LayerCount, len(project.Layers))
+	assert.Equal(t, projects[0].RemoteConfigCount, len(project.RemoteConfigs))
+	var layerKeys []string
+	for _, layer := range project.Layers {
+		layerKeys = append(layerKeys, layer.Key)
+	}
+	assert.Contains(t, layerKeys, "overrideLayer")
+
+	query := url.Values{"format": {"json"}, "project": {projectID}, "unit": {"unitID"}, "tag": {"tagKey1=ios"}}
+	recorder = serveDebug(t, "/debug/abc/evaluate?"+query.Encode())
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var evaluation DebugEvaluation
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &evaluation))
+	assert.Equal(t, map[string][]string{"tagKey1": {"ios"}}, evaluation.Tags)
+	assert.Empty(t, evaluation.Error)
+	var groupKeys = map[string]string{}
+	for _, assignment := range evaluation.Experiments {
+		groupKeys[assignment.LayerKey] = assignment.GroupKey
+	}
+	assert.Equal(t, "100003001", groupKeys["overrideLayer"])
+	var configValues = map[string]string{}
+	for _, value := range evaluation.RemoteConfigs {
+		configValues[value.Key] = value.Value
+	}
+	assert.Equal(t, "remoteConfig1-condition1", configValues["remoteConfig1"])
+	assert.Equal(t, "withTag-condition1", configValues["withTag"])
+}
+
+func TestDebugHandler_Pages(t *testing.T) {
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+	}{
+		{name: "index", target: "/debug/abc/", wantCode: http.StatusOK},
+		{name: "project", target: "/debug/abc/project?project=" + projectID, wantCode: http.StatusOK},
+		{name: "evaluate form", target: "/debug/abc/evaluate?project=" + projectID, wantCode: http.StatusOK},
+		{name: "evaluate", target: "/debug/abc/evaluate?unit=unitID&project=" + projectID, wantCode: http.StatusOK},
+		{name: "project required", target: "/debug/abc/project", wantCode: http.StatusBadRequest},
+		{name: "project not loaded", target: "/debug/abc/evaluate?project=emptyProjectID", wantCode: http.StatusNotFound},
+		{name: "unknown path", target: "/debug/abc/unknown", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serveDebug(t, tt.target)
+			assert.Equal(t, tt.wantCode, recorder.Code, recorder.Body.String())
+			if tt.wantCode == http.StatusOK {
+				assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+			}
+		})
+	}
+}
+
+func Test_parseDebugTags(t *testing.T) {
+	got := parseDebugTags([]string{"a=1\r\nb=2\n\ninvalid\n=3", "a=4", "c=x=y"})
+	assert.Equal(t, map[string][]string{"a": {"1", "4"}, "b": {"2"}, "c": {"x=y"}}, got)
+}
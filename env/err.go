@@ -12,4 +12,16 @@ const (
 var (
 	// ErrParamKeyNotFound Experiment parameter key not found
 	ErrParamKeyNotFound = fmt.Errorf("param key not found")
+	// ErrProjectNotFound The projectID has not been loaded into the local cache,
+	// usually Init or RegisterProjectIDs has not been called or has not finished
+	ErrProjectNotFound = fmt.Errorf("project not found")
+	// ErrRemoteConfigNotFound The remote configuration key does not exist under the project
+	ErrRemoteConfigNotFound = fmt.Errorf("remote config not found")
+	// ErrLayerNotFound The layer key does not exist under the project
+	ErrLayerNotFound = fmt.Errorf("layer not found")
+	// ErrVersionNotKept The version of the local cache data is not in the kept versions of the project,
+	// see abc.WithVersionHistory
+	ErrVersionNotKept = fmt.Errorf("version not kept")
+	// ErrUserContextNotFound No user context is attached to the context.Context, see abc.NewContext
+	ErrUserContextNotFound = fmt.Errorf("user context not found in context.Context")
 )
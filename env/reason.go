@@ -0,0 +1,33 @@
+// Package env TODO
+package env
+
+// Reason The reason why an evaluation result was returned
+type Reason string
+
+// const Evaluation reason enumeration
+const (
+	// ReasonOverride The unitID is hit by the whitelist
+	ReasonOverride Reason = "OVERRIDE"
+	// ReasonHoldout The unitID is caught by the holdout layer
+	ReasonHoldout Reason = "HOLDOUT"
+	// ReasonTargetingMatch The unitID matches the tag targeting of the group or the remote configuration condition
+	ReasonTargetingMatch Reason = "TARGETING_MATCH"
+	// ReasonSplit The unitID is hit by traffic splitting
+	ReasonSplit Reason = "SPLIT"
+	// ReasonLayerDefault No group is hit, the default group of the layer is returned
+	ReasonLayerDefault Reason = "LAYER_DEFAULT"
+	// ReasonSystemDefault No group or condition is hit and there is no layer default,
+	// the global default group or the default value of the remote configuration is returned
+	ReasonSystemDefault Reason = "SYSTEM_DEFAULT"
+	// ReasonFallback The key does not exist, the caller-supplied fallback value is returned
+	ReasonFallback Reason = "FALLBACK"
+	// ReasonError The evaluation failed, the caller-supplied fallback value is returned
+	ReasonError Reason = "ERROR"
+	// ReasonNotReady The project has not been loaded yet, the caller-supplied fallback value is returned
+	ReasonNotReady Reason = "NOT_READY"
+)
+
+// IsFallbackReason Whether the result carries the caller-supplied fallback value instead of an evaluated one
+func IsFallbackReason(reason Reason) bool {
+	return reason == ReasonFallback || reason == ReasonError || reason == ReasonNotReady
+}
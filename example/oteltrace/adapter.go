@@ -0,0 +1,76 @@
+// Package oteltrace An example adapter registering OpenTelemetry as the tracer of the SDK.
+// It is a separate module so that the SDK itself does not depend on OpenTelemetry,
+// copy it into your project or import it directly.
+//
+// example:
+//
+//	trace.RegisterTracer(oteltrace.NewTracer(otel.Tracer("github.com/abetterchoice/go-sdk")))
+package oteltrace
+
+import (
+	"context"
+
+	"github.com/abetterchoice/go-sdk/plugin/trace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Tracer Adapt the OpenTelemetry tracer into trace.Tracer
+type Tracer struct {
+	tracer oteltrace.Tracer
+}
+
+// NewTracer Create the adapter of the OpenTelemetry tracer
+func NewTracer(tracer oteltrace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// Start Start the OpenTelemetry span as a child of the span in ctx
+func (t *Tracer) Start(ctx context.Context, name string, attributes ...trace.Attribute) (context.Context, trace.Span) {
+	ctx, span := t.tracer.Start(ctx, name, oteltrace.WithAttributes(convertAttributes(attributes)...))
+	return ctx, &Span{span: span}
+}
+
+// Span Adapt the OpenTelemetry span into trace.Span
+type Span struct {
+	span oteltrace.Span
+}
+
+// SetAttributes Set the attributes of the span
+func (s *Span) SetAttributes(attributes ...trace.Attribute) {
+	s.span.SetAttributes(convertAttributes(attributes)...)
+}
+
+// RecordError Record the error and set the span status to error, nil error is ignored
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End End the span
+func (s *Span) End() {
+	s.span.End()
+}
+
+func convertAttributes(attributes []trace.Attribute) []attribute.KeyValue {
+	var result = make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		switch v := a.Value.(type) {
+		case string:
+			result = append(result, attribute.String(a.Key, v))
+		case []string:
+			result = append(result, attribute.StringSlice(a.Key, v))
+		case int64:
+			result = append(result, attribute.Int64(a.Key, v))
+		case []int64:
+			result = append(result, attribute.Int64Slice(a.Key, v))
+		case bool:
+			result = append(result, attribute.Bool(a.Key, v))
+		}
+	}
+	return result
+}
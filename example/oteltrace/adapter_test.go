@@ -0,0 +1,47 @@
+package oteltrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/plugin/trace"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	trace.RegisterTracer(NewTracer(provider.Tracer("test")))
+	defer trace.RegisterTracer(nil)
+
+	ctx, parent := trace.Start(context.Background(), trace.SpanGetExperiments,
+		trace.String(trace.AttributeProjectID, "123"))
+	_, child := trace.Start(ctx, trace.SpanGetTagValue)
+	child.RecordError(errors.New("mock err"))
+	child.End()
+	parent.SetAttributes(trace.StringSlice(trace.AttributeLayerKeys, []string{"layer"}),
+		trace.Int64Slice(trace.AttributeGroupIDs, []int64{1}), trace.Int64(trace.AttributeDMPPlatform, 1),
+		trace.Bool(trace.AttributeIsModified, true))
+	parent.RecordError(nil)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, trace.SpanGetTagValue, spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, trace.SpanGetExperiments, spans[1].Name())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String(trace.AttributeProjectID, "123"),
+		attribute.StringSlice(trace.AttributeLayerKeys, []string{"layer"}),
+		attribute.Int64Slice(trace.AttributeGroupIDs, []int64{1}),
+		attribute.Int64(trace.AttributeDMPPlatform, 1),
+		attribute.Bool(trace.AttributeIsModified, true),
+	}, spans[1].Attributes())
+}
@@ -0,0 +1,25 @@
+module github.com/abetterchoice/go-sdk/example/oteltrace
+
+go 1.19
+
+require (
+	github.com/abetterchoice/go-sdk v0.0.0
+	github.com/stretchr/testify v1.8.4
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/sdk v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/abetterchoice/go-sdk => ../..
+	github.com/golang/protobuf => github.com/golang/protobuf v1.4.3
+)
@@ -7,10 +7,11 @@ import (
 	"context"
 	"time"
 
-	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/cache"
 	"github.com/abetterchoice/go-sdk/internal/experiment"
-	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
 	"github.com/abetterchoice/protoc_event_server"
 	"github.com/pkg/errors"
 )
@@ -70,50 +71,91 @@ func (c *userContext) GetExperiment(ctx context.Context, projectID string, layer
 func (c *userContext) GetExperiments(ctx context.Context, projectID string,
 	opts ...ExperimentOption) (result *ExperimentList, err error) {
 	options := defaultExperimentOptions // copy, defaultExperimentOptions as template remains unchanged
+	// the evaluation error replaced by the fallback result, it is still reported through the monitor event
+	var fallbackErr error
+	ctx, span := trace.Start(ctx, trace.SpanGetExperiments, trace.String(trace.AttributeProjectID, projectID))
+	defer func() {
+		if trace.IsEnabled() {
+			span.SetAttributes(experimentListAttributes(options.Application, result)...)
+		}
+		span.RecordError(err)
+		span.RecordError(fallbackErr)
+		span.End()
+	}()
 	defer func(startTime time.Time) {
 		latency := time.Since(startTime)
-		if options.IsExposureLoggingAutomatic && !internal.C.IsDisableReport {
-			exposureErr := asyncExposureExperiments(projectID, result, protoc_event_server.ExposureType_EXPOSURE_TYPE_AUTOMATIC)
-			if exposureErr != nil {
-				log.Errorf("[projectID=%v]asyncExposureExperiments fail:%v", projectID, exposureErr)
-			}
+		// the fallback result is not a real assignment, no exposure is recorded
+		if options.IsExposureLoggingAutomatic && !internal.C.IsDisableReport && fallbackErr == nil {
+			asyncExposureExperiments(projectID, result, protoc_event_server.ExposureType_EXPOSURE_TYPE_AUTOMATIC)
 		}
-		exposureErr := asyncExposureExperimentEvent(projectID, result, latency, env.JSONString(&options), err)
-		if exposureErr != nil {
-			log.Errorf("[projectID=%v]asyncExposureExperimentEvent fail:%v", projectID, exposureErr)
+		eventErr := err
+		if fallbackErr != nil {
+			eventErr = fallbackErr
 		}
+		asyncExposureExperimentEvent(projectID, result, latency, optionsJSON(&options), eventErr)
+		stats.ObserveEvaluation(stats.APIGetExperiments, evaluationStatus(err, fallbackErr != nil), latency)
 	}(time.Now())
-	if c.err != nil {
-		return nil, c.err
-	}
-	c.fillOption(&options)
+	c.fillOption(projectID, &options)
 	for _, opt := range opts {
 		err = opt(&options)
 		if err != nil {
 			return nil, errors.Wrap(err, "opt")
 		}
 	}
+	experimentList, err := c.getExperiments(ctx, projectID, &options)
+	if err != nil {
+		if options.FallbackParams == nil {
+			return nil, err // the error here does not need to be wrapped, it is all GetExperiments
+		}
+		fallbackErr = err
+		return c.fallbackExperimentList(&options, fallbackReason(err)), nil
+	}
+	return c.newExperimentList(experimentList, &options), nil
+}
+
+// getExperiments The assignments of the user, the error of the construction of the user context included
+func (c *userContext) getExperiments(ctx context.Context, projectID string,
+	options *experiment.Options) (map[string]*experiment.Experiment, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return experiment.Executor.GetExperiments(ctx, projectID, options)
+}
+
+// assignments The current assignments of the user in the project and the local cache they are evaluated on,
+// no exposure or monitor event is recorded
+func (c *userContext) assignments(ctx context.Context, projectID string) (*ExperimentList, *cache.Application,
+	error) {
+	options := defaultExperimentOptions // copy, defaultExperimentOptions as template remains unchanged
+	c.fillOption(projectID, &options)
 	experimentList, err := experiment.Executor.GetExperiments(ctx, projectID, &options)
 	if err != nil {
-		return nil, err // the error here does not need to be wrapped, it is all GetExperiments
+		return nil, nil, err
 	}
-	result = &ExperimentList{
+	return c.newExperimentList(experimentList, &options), options.Application, nil
+}
+
+// newExperimentList The experiment list of the assigned groups and the holdout groups of options
+func (c *userContext) newExperimentList(experimentList map[string]*experiment.Experiment,
+	options *experiment.Options) *ExperimentList {
+	result := &ExperimentList{
 		Data: make(map[string]*Group, len(experimentList)),
 	}
 	for layerKey, group := range experimentList {
 		if group == nil {
 			continue
 		}
-		result.Data[layerKey] = convertGroup2Experiment(group)
+		result.Data[layerKey] = withVersion(convertGroup2Experiment(group), options.Application)
 	}
 	for layerKey, holdoutGroup := range options.HoldoutLayerResult {
 		if holdoutGroup == nil {
 			continue
 		}
-		result.Data[layerKey] = convertGroup2Experiment(holdoutGroup)
+		result.Data[layerKey] = withVersion(convertGroup2Experiment(holdoutGroup), options.Application)
 	}
+	result.Version = applicationVersion(options.Application)
 	result.userCtx = c
-	return result, nil
+	return result
 }
 
 // GetDefaultExperiments The default experiment on the acquisition layer does not involve any diversion process,
@@ -144,7 +186,11 @@ func GetDefaultExperiments(ctx context.Context, projectID string, opts ...Experi
 
 // fillOption the attributes with userContext into options for subsequent use of abtest offloading.
 // fill in the default value. The opt function will overwrite the following default value.
-func (c *userContext) fillOption(options *experiment.Options) {
+// The local cache pinned by WithPinnedSnapshot is filled as the snapshot of the evaluation
+func (c *userContext) fillOption(projectID string, options *experiment.Options) {
+	if c.snapshot != nil {
+		options.Application = c.snapshot.application(projectID)
+	}
 	options.AttributeTag = c.tags
 	options.UnitID = c.unitID
 	options.DecisionID = c.decisionID
@@ -185,6 +231,7 @@ func convertGroup2ExperimentWithoutHoldout(group *experiment.Experiment) *Group
 		params:         group.Params,
 		UnitIDType:     group.UnitIdType,
 		sceneIDList:    group.SceneIdList,
+		Reason:         group.Reason,
 	}
 }
 
@@ -303,6 +350,20 @@ func WithIsPreparedDMPTag(isPreparedDMPTag bool) ExperimentOption {
 	}
 }
 
+// WithFallbackParams sets the layer params returned when the project is not loaded, the layer does not exist
+// or the evaluation fails. Instead of an error, a default group carrying a copy of params is returned for each
+// requested layer key, its Reason explains why the fallback was used, and no exposure is recorded for it.
+func WithFallbackParams(params map[string]string) ExperimentOption {
+	return func(options *experiment.Options) error {
+		var fallbackParams = make(map[string]string, len(params))
+		for key, value := range params {
+			fallbackParams[key] = value
+		}
+		options.FallbackParams = fallbackParams
+		return nil
+	}
+}
+
 // WithIsDisableDMP sets whether to turn off DMP to prevent rpc operations.
 // If the DMP label is clearly not needed, it can be turned off, such as local testing, etc.
 func WithIsDisableDMP(isDisableDMP bool) ExperimentOption {
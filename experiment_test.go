@@ -92,6 +92,7 @@ func Test_userContext_GetExperiment(t *testing.T) {
 					params:         map[string]string{"key1": "100002001"},
 					sceneIDList:    nil,
 					UnitIDType:     protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
+					Reason:         env.ReasonSplit,
 				},
 				userCtx: &userContext{
 					err:           nil,
@@ -139,6 +140,7 @@ func Test_userContext_GetExperiment(t *testing.T) {
 						"key1": "200002001",
 					},
 					UnitIDType: protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
+					Reason:     env.ReasonSplit,
 				},
 			},
 			wantErr: false,
@@ -174,6 +176,7 @@ func Test_userContext_GetExperiment(t *testing.T) {
 					params:         map[string]string{"key1": "100002001"},
 					sceneIDList:    nil,
 					UnitIDType:     protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
+					Reason:         env.ReasonSplit,
 				},
 			},
 			wantErr: false,
@@ -415,3 +418,49 @@ func TestGetDefaultExperimentsWithOpts(t *testing.T) {
 		})
 	}
 }
+
+func Test_userContext_GetExperimentWithFallbackParams(t *testing.T) {
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	fallbackParams := map[string]string{"key1": "fallback1"}
+	t.Run("projectID not found", func(t *testing.T) {
+		got, err := NewUserContext("fallbackUnitID").GetExperiment(context.TODO(), "emptyProjectID", "layerKey",
+			WithFallbackParams(fallbackParams))
+		assert.Nil(t, err)
+		assert.NotNil(t, got)
+		assert.True(t, got.IsDefault)
+		assert.Equal(t, env.ReasonNotReady, got.Reason)
+		assert.Equal(t, "fallback1", got.MustGetString("key1"))
+	})
+	t.Run("invalid layerKey", func(t *testing.T) {
+		got, err := NewUserContext("fallbackUnitID").GetExperiment(context.TODO(), projectID, "emptyLayerKey",
+			WithFallbackParams(fallbackParams))
+		assert.Nil(t, err)
+		assert.NotNil(t, got)
+		assert.Equal(t, env.ReasonFallback, got.Reason)
+		assert.Equal(t, "emptyLayerKey", got.LayerKey)
+	})
+	t.Run("invalid layerKey without fallback", func(t *testing.T) {
+		got, err := NewUserContext("fallbackUnitID").GetExperiment(context.TODO(), projectID, "emptyLayerKey")
+		assert.NotNil(t, err)
+		assert.Nil(t, got)
+	})
+	t.Run("each group owns its params", func(t *testing.T) {
+		list, err := NewUserContext("fallbackUnitID").GetExperiments(context.TODO(), projectID,
+			WithLayerKeyList([]string{"emptyLayerKey1", "emptyLayerKey2"}), WithFallbackParams(fallbackParams))
+		assert.Nil(t, err)
+		list.Data["emptyLayerKey1"].params["key1"] = "changed"
+		assert.Equal(t, "fallback1", list.Data["emptyLayerKey2"].MustGetString("key1"))
+		assert.Equal(t, "fallback1", fallbackParams["key1"])
+	})
+	t.Run("invalid user context", func(t *testing.T) {
+		userCtx := NewUserContext("fallbackUnitID", WithDecisionID(""))
+		got, err := userCtx.GetExperiment(context.TODO(), projectID, "layerKey", WithFallbackParams(fallbackParams))
+		assert.Nil(t, err)
+		assert.Equal(t, env.ReasonError, got.Reason)
+		assert.Equal(t, "fallback1", got.MustGetString("key1"))
+		_, err = userCtx.GetExperiment(context.TODO(), projectID, "layerKey")
+		assert.NotNil(t, err)
+	})
+}
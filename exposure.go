@@ -14,8 +14,10 @@ import (
 	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal"
 	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/internal/stats"
 	"github.com/abetterchoice/go-sdk/plugin/log"
 	"github.com/abetterchoice/go-sdk/plugin/metrics"
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
 	"github.com/abetterchoice/protoc_event_server"
 )
 
@@ -24,6 +26,16 @@ const (
 	// it will be reported to the extended field of the exposure record and stored in kv format.
 	// The key is newIDKey and the value is newUnitID.
 	newIDKey = "new_id"
+	// The evaluation reason is reported to the extended field of the monitor event, the key is reasonKey
+	reasonKey = "reason"
+)
+
+// The keys of the expanded data of a metrics table selecting its sampling
+const (
+	// SamplingModeKey The sampling mode of the table, "random" or "hash"
+	SamplingModeKey = "sampling_mode"
+	// SamplingSaltKey The salt of the hash sampling of the table, the default is the table ID
+	SamplingSaltKey = "sampling_salt"
 )
 
 // LogExperimentsExposure When automatic exposure-logging is disabled,
@@ -76,17 +88,18 @@ func exposureExperimentEvent(ctx context.Context, projectID string, list *Experi
 		return nil
 	}
 	// Sampling first, the frequency of event reporting is not high, sampling first improves efficiency
-	if !metrics.SamplingResult(env.SamplingInterval(metricsConfig, err)) {
+	metadata := newMetricsMetadata(metricsConfig)
+	metadata.SamplingInterval = env.SamplingInterval(metricsConfig, err)
+	var unitID string
+	if list != nil && list.userCtx != nil {
+		unitID = reportedID(list.userCtx.unitID) // the same unit ID as the exposures are sampled by
+	}
+	if !metrics.SampleUnit(metadata, unitID) {
 		return nil // 采样不通过
 	}
-	return metrics.LogMonitorEvent(ctx, &metrics.Metadata{
-		MetricsPluginName: metricsConfig.PluginName,
-		TableName:         metricsConfig.Metadata.Name,
-		TableID:           metricsConfig.Metadata.Id,
-		Token:             metricsConfig.Metadata.Token,
-		SamplingInterval:  1, // 已经先采样了，这里恒上报
-	}, &protoc_event_server.MonitorEventGroup{Events: []*protoc_event_server.MonitorEvent{
-		{
+	metadata.SamplingInterval = 1 // 已经先采样了，这里恒上报
+	return metrics.LogMonitorEvent(ctx, metadata, &protoc_event_server.MonitorEventGroup{
+		Events: []*protoc_event_server.MonitorEvent{{
 			Time:       time.Now().Unix(),
 			Ip:         env.LocalIP(),
 			ProjectId:  projectID,
@@ -99,9 +112,9 @@ func exposureExperimentEvent(ctx context.Context, projectID string, list *Experi
 			InvokePath: env.InvokePath(4), // 跳过 4 层调用栈
 			InputData:  optionStr,
 			OutputData: experimentIDList(list),
-			ExtInfo:    nil,
-		},
-	}})
+			ExtInfo:    reasonExtInfo(experimentReasonList(list)),
+		}},
+	})
 }
 
 // exposureRemoteConfigEvent Report remote configuration acquisition events
@@ -116,22 +129,25 @@ func exposureRemoteConfigEvent(ctx context.Context, projectID string, config *Co
 	if metricsConfig == nil || !metricsConfig.IsEnable || metricsConfig.Metadata == nil {
 		return nil
 	}
-	if !metrics.SamplingResult(env.SamplingInterval(metricsConfig, err)) {
+	metadata := newMetricsMetadata(metricsConfig)
+	metadata.SamplingInterval = env.SamplingInterval(metricsConfig, err)
+	var unitID string
+	if config != nil && config.userCtx != nil {
+		unitID = reportedID(config.userCtx.unitID)
+	}
+	if !metrics.SampleUnit(metadata, unitID) {
 		return nil
 	}
+	metadata.SamplingInterval = 1 // 已经先采样了，这里恒上报
 	// Report data
 	var resultData string
-	if config != nil {
+	var reason env.Reason
+	if config != nil && config.Config != nil {
 		resultData = string(config.data)
+		reason = config.Reason
 	}
-	return metrics.LogMonitorEvent(ctx, &metrics.Metadata{
-		MetricsPluginName: metricsConfig.PluginName,
-		TableName:         metricsConfig.Metadata.Name,
-		TableID:           metricsConfig.Metadata.Id,
-		Token:             metricsConfig.Metadata.Token,
-		SamplingInterval:  1, // 已经先采样了，这里恒上报
-	}, &protoc_event_server.MonitorEventGroup{Events: []*protoc_event_server.MonitorEvent{
-		{
+	return metrics.LogMonitorEvent(ctx, metadata, &protoc_event_server.MonitorEventGroup{
+		Events: []*protoc_event_server.MonitorEvent{{
 			Time:       time.Now().Unix(),
 			Ip:         env.LocalIP(),
 			ProjectId:  projectID,
@@ -144,9 +160,30 @@ func exposureRemoteConfigEvent(ctx context.Context, projectID string, config *Co
 			InvokePath: env.InvokePath(4), // 跳过 4 层调用栈
 			InputData:  optionStr,
 			OutputData: resultData,
-			ExtInfo:    nil,
-		},
-	}})
+			ExtInfo:    reasonExtInfo(string(reason)),
+		}},
+	})
+}
+
+// newMetricsMetadata The metadata of the metrics table. The sampling mode and the salt of the hash sampling
+// are selected by the expanded data of the table, the mode defaults to WithSamplingMode and the salt to the table ID
+func newMetricsMetadata(metricsConfig *protoccacheserver.MetricsConfig) *metrics.Metadata {
+	metadata := &metrics.Metadata{
+		MetricsPluginName: metricsConfig.PluginName,
+		TableName:         metricsConfig.Metadata.Name,
+		TableID:           metricsConfig.Metadata.Id,
+		Token:             metricsConfig.Metadata.Token,
+		SamplingInterval:  metricsConfig.SamplingInterval,
+		SamplingMode:      metrics.SamplingMode(internal.C.SamplingMode),
+		SamplingSalt:      metricsConfig.Metadata.Id,
+	}
+	if mode, ok := metricsConfig.Metadata.ExpandedData[SamplingModeKey]; ok {
+		metadata.SamplingMode = metrics.SamplingMode(mode)
+	}
+	if salt, ok := metricsConfig.Metadata.ExpandedData[SamplingSaltKey]; ok {
+		metadata.SamplingSalt = salt
+	}
+	return metadata
 }
 
 // experimentIDList of experimental group IDs, separated by ; sign
@@ -161,6 +198,39 @@ func experimentIDList(list *ExperimentList) string {
 	return int64ListJoin(idList, ";")
 }
 
+// experimentReasonList of the evaluation reason of each layer, formatted as layerKey:reason and separated by ; sign
+func experimentReasonList(list *ExperimentList) string {
+	if list == nil {
+		return ""
+	}
+	var layerKeys = make([]string, 0, len(list.Data))
+	for layerKey, e := range list.Data {
+		if e == nil || e.Reason == "" {
+			continue
+		}
+		layerKeys = append(layerKeys, layerKey)
+	}
+	sort.Strings(layerKeys)
+	var buf bytes.Buffer
+	for i, layerKey := range layerKeys {
+		if i != 0 {
+			buf.WriteString(";")
+		}
+		buf.WriteString(layerKey)
+		buf.WriteString(":")
+		buf.WriteString(string(list.Data[layerKey].Reason))
+	}
+	return buf.String()
+}
+
+// reasonExtInfo The extended field of the monitor event carrying the evaluation reason
+func reasonExtInfo(reason string) map[string]string {
+	if reason == "" {
+		return nil
+	}
+	return map[string]string{reasonKey: reason}
+}
+
 // exposureExperiments TODO
 // Specific implementation of experimental exposure reporting
 func exposureExperiments(ctx context.Context, projectID string, list *ExperimentList,
@@ -194,15 +264,10 @@ func exposureExperiments(ctx context.Context, projectID string, list *Experiment
 		if !metricsConfig.IsEnable || metricsConfig.Metadata == nil {
 			continue
 		}
-		err := metrics.LogExposure(ctx, &metrics.Metadata{
-			MetricsPluginName: metricsConfig.PluginName,
-			TableName:         metricsConfig.Metadata.Name,
-			TableID:           metricsConfig.Metadata.Id,
-			Token:             metricsConfig.Metadata.Token,
-			SamplingInterval:  metricsConfig.SamplingInterval,
-		}, dataList)
+		err := logExposureGroup(ctx, newMetricsMetadata(metricsConfig), dataList)
 		if err != nil {
-			log.Errorf("sendData fail:%v", err)
+			log.ErrorContext(ctx, "sendData fail", application.LogFields(log.F("plugin", metricsConfig.PluginName),
+				log.F("sceneID", sceneID), log.Err(err))...)
 			return err
 		}
 	}
@@ -210,13 +275,7 @@ func exposureExperiments(ctx context.Context, projectID string, list *Experiment
 		defaultExperimentMetricsConfig.Metadata == nil {
 		return nil
 	}
-	return metrics.LogExposure(ctx, &metrics.Metadata{
-		MetricsPluginName: defaultExperimentMetricsConfig.PluginName,
-		TableName:         defaultExperimentMetricsConfig.Metadata.Name,
-		TableID:           defaultExperimentMetricsConfig.Metadata.Id,
-		Token:             defaultExperimentMetricsConfig.Metadata.Token,
-		SamplingInterval:  defaultExperimentMetricsConfig.SamplingInterval,
-	}, defaultDataList)
+	return logExposureGroup(ctx, newMetricsMetadata(defaultExperimentMetricsConfig), defaultDataList)
 }
 
 // exposureFeatureFlag TODO
@@ -231,6 +290,9 @@ func exposureFeatureFlag(ctx context.Context, projectID string, featureFlag *Fea
 		return nil
 	}
 	config := featureFlag.ConfigResult
+	if config.Config == nil || env.IsFallbackReason(config.Reason) { // The caller-supplied fallback is not reported
+		return nil
+	}
 	// Get local cache
 	application := cache.GetApplication(projectID)
 	if application == nil { // 理论上不为 nil
@@ -241,6 +303,9 @@ func exposureFeatureFlag(ctx context.Context, projectID string, featureFlag *Fea
 	if len(metricsConfigList) == 0 && defaultMetricsConfig == nil {
 		return nil
 	}
+	if isRepeatedConfig(stats.ExposureTypeFeatureFlag, projectID, config) { // Suppressed within the dedupe window
+		return nil
+	}
 	// Whether it has been reported through the specified scenario
 	isSent := false
 	data := convertRemoteConfig(projectID, config, exposureType) // Reuse remote configuration exposure reporting
@@ -252,15 +317,10 @@ func exposureFeatureFlag(ctx context.Context, projectID string, featureFlag *Fea
 		if !metricsConfig.IsEnable {
 			continue
 		}
-		err := metrics.SendData(ctx, &metrics.Metadata{
-			MetricsPluginName: metricsConfig.PluginName,
-			TableName:         metricsConfig.Metadata.Name,
-			TableID:           metricsConfig.Metadata.Id,
-			Token:             metricsConfig.Metadata.Token,
-			SamplingInterval:  metricsConfig.SamplingInterval,
-		}, [][]string{data})
+		err := sendDataRows(ctx, newMetricsMetadata(metricsConfig), [][]string{data})
 		if err != nil {
-			log.Errorf("sendData fail:%v", err)
+			log.ErrorContext(ctx, "sendData fail", application.LogFields(log.F("plugin", metricsConfig.PluginName),
+				log.F("sceneID", sceneID), log.Err(err))...)
 			return err
 		}
 		// If you have reported through specified scenarios, you will no longer need to use default metrics to report.
@@ -269,13 +329,7 @@ func exposureFeatureFlag(ctx context.Context, projectID string, featureFlag *Fea
 	if isSent || defaultMetricsConfig == nil || !defaultMetricsConfig.IsEnable || defaultMetricsConfig.Metadata == nil {
 		return nil
 	}
-	return metrics.SendData(ctx, &metrics.Metadata{
-		MetricsPluginName: defaultMetricsConfig.PluginName,
-		TableName:         defaultMetricsConfig.Metadata.Name,
-		TableID:           defaultMetricsConfig.Metadata.Id,
-		Token:             defaultMetricsConfig.Metadata.Token,
-		SamplingInterval:  defaultMetricsConfig.SamplingInterval,
-	}, [][]string{data})
+	return sendDataRows(ctx, newMetricsMetadata(defaultMetricsConfig), [][]string{data})
 }
 
 // exposureRemoteConfig 远程配置曝光上报具体实现
@@ -285,7 +339,10 @@ func exposureRemoteConfig(ctx context.Context, projectID string, config *ConfigR
 	if internal.C.IsDisableReport {
 		return nil
 	}
-	if config == nil { // 没有数据
+	if config == nil || config.Config == nil { // 没有数据
+		return nil
+	}
+	if env.IsFallbackReason(config.Reason) { // The caller-supplied fallback is not reported
 		return nil
 	}
 	// Get local cache
@@ -298,6 +355,9 @@ func exposureRemoteConfig(ctx context.Context, projectID string, config *ConfigR
 	if len(metricsConfigList) == 0 && defaultMetricsConfig == nil { // 没有监控上报配置
 		return nil
 	}
+	if isRepeatedConfig(stats.ExposureTypeRemoteConfig, projectID, config) { // Suppressed within the dedupe window
+		return nil
+	}
 	// get reported data
 	isSent := false // Whether it has been reported through the specified scenario
 	data := convertRemoteConfig(projectID, config, exposureType)
@@ -309,15 +369,10 @@ func exposureRemoteConfig(ctx context.Context, projectID string, config *ConfigR
 		if !metricsConfig.IsEnable {
 			continue
 		}
-		err := metrics.SendData(ctx, &metrics.Metadata{
-			MetricsPluginName: metricsConfig.PluginName,
-			TableName:         metricsConfig.Metadata.Name,
-			TableID:           metricsConfig.Metadata.Id,
-			SamplingInterval:  metricsConfig.SamplingInterval,
-			Token:             metricsConfig.Metadata.Token,
-		}, [][]string{data})
+		err := sendDataRows(ctx, newMetricsMetadata(metricsConfig), [][]string{data})
 		if err != nil {
-			log.Errorf("sendData fail:%v", err)
+			log.ErrorContext(ctx, "sendData fail", application.LogFields(log.F("plugin", metricsConfig.PluginName),
+				log.F("sceneID", sceneID), log.Err(err))...)
 			return err
 		}
 		isSent = true
@@ -325,13 +380,7 @@ func exposureRemoteConfig(ctx context.Context, projectID string, config *ConfigR
 	if isSent || defaultMetricsConfig == nil || !defaultMetricsConfig.IsEnable || defaultMetricsConfig.Metadata == nil {
 		return nil
 	}
-	return metrics.SendData(ctx, &metrics.Metadata{
-		MetricsPluginName: defaultMetricsConfig.PluginName,
-		TableName:         defaultMetricsConfig.Metadata.Name,
-		TableID:           defaultMetricsConfig.Metadata.Id,
-		Token:             defaultMetricsConfig.Metadata.Token,
-		SamplingInterval:  defaultMetricsConfig.SamplingInterval,
-	}, [][]string{data})
+	return sendDataRows(ctx, newMetricsMetadata(defaultMetricsConfig), [][]string{data})
 }
 
 // convertExperimentList TODO
@@ -347,6 +396,12 @@ func convertExperimentList(projectID string, list *ExperimentList, exposureType
 		if flag, ok := ignoreReportGroupID[e.ID]; ok && flag { // Filter and ignore reported experimental group IDs
 			continue
 		}
+		if env.IsFallbackReason(e.Reason) { // The caller-supplied fallback is not a real assignment
+			continue
+		}
+		if isRepeatedGroup(projectID, list.userCtx.unitID, e) { // Suppressed within the dedupe window
+			continue
+		}
 		if len(e.sceneIDList) == 0 {
 			defaultDataList.Exposures = append(defaultDataList.Exposures, convertExperimentV2(projectID, e, list.userCtx,
 				exposureType, uploadTime))
@@ -366,14 +421,14 @@ func convertExperimentList(projectID string, list *ExperimentList, exposureType
 func convertExperimentV2(projectID string, experiment *Group, userCtx *userContext,
 	exposureType protoc_event_server.ExposureType, uploadTime int64) *protoc_event_server.Exposure {
 	return &protoc_event_server.Exposure{
-		UnitId:       userCtx.unitID,
+		UnitId:       reportedID(userCtx.unitID),
 		GroupId:      experiment.ID,
 		ProjectId:    projectID,
 		Time:         uploadTime,
 		LayerKey:     experiment.LayerKey,
 		ExpKey:       experiment.ExperimentKey,
 		UnitType:     strconv.FormatInt(int64(experiment.UnitIDType), 10),
-		ClusterId:    userCtx.decisionID,
+		ClusterId:    reportedID(userCtx.decisionID),
 		SdkType:      env.SDKType,
 		SdkVersion:   env.Version,
 		ExposureType: exposureType,
@@ -384,7 +439,7 @@ func convertExperimentV2(projectID string, experiment *Group, userCtx *userConte
 func convertRemoteConfig(projectID string, config *ConfigResult,
 	exposureType protoc_event_server.ExposureType) []string {
 	return []string{
-		config.userCtx.unitID,                    // unitID
+		reportedID(config.userCtx.unitID),        // unitID
 		projectID,                                // Business unique identifier
 		config.Key,                               // Configuration name
 		env.SDKVersion,                           // sdk version information
@@ -398,17 +453,12 @@ func convertRemoteConfig(projectID string, config *ConfigResult,
 	}
 }
 
+// marshalExpandedData The reported expanded data formatted as k1=v1;k2=v2 in the order of the keys
 func marshalExpandedData(userCtx *userContext) string {
-	if len(userCtx.expandedData) == 0 && len(userCtx.newUnitID) == 0 {
+	expandedData := extraDataFromUserCtx(userCtx)
+	if len(expandedData) == 0 {
 		return ""
 	}
-	var expandedData = make(map[string]string, len(userCtx.expandedData)+1)
-	if len(userCtx.newUnitID) != 0 {
-		expandedData[newIDKey] = userCtx.newUnitID
-	}
-	for key, value := range userCtx.expandedData {
-		expandedData[key] = value
-	}
 	var keys = make([]string, 0, len(expandedData))
 	for key := range expandedData {
 		keys = append(keys, key)
@@ -424,6 +474,7 @@ func marshalExpandedData(userCtx *userContext) string {
 	return sb.String()
 }
 
+// extraDataFromUserCtx The expanded data and the newUnitID reported by the privacy policy
 func extraDataFromUserCtx(userCtx *userContext) map[string]string {
 	if len(userCtx.expandedData) == 0 && len(userCtx.newUnitID) == 0 {
 		return nil
@@ -435,7 +486,7 @@ func extraDataFromUserCtx(userCtx *userContext) map[string]string {
 	for key, value := range userCtx.expandedData {
 		extraData[key] = value
 	}
-	return extraData
+	return reportedExtraData(extraData)
 }
 
 // int64ListJoin slice to string
@@ -492,7 +543,8 @@ func manualInitEvent(projectIDList []string, latency time.Duration, err error) {
 			},
 		}})
 		if sendDataErr != nil {
-			log.Errorf("sendData fail:%v", sendDataErr)
+			log.ErrorContext(context.Background(), "logMonitorEvent fail",
+				application.LogFields(log.F("plugin", metricsConfig.PluginName), log.Err(sendDataErr))...)
 		}
 	}
 }
@@ -0,0 +1,186 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/plugin/metrics"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/pkg/errors"
+)
+
+// exposureBatcher The batcher of the exposures, nil means each exposure is handed to the plugin at once.
+// It is set by Init and flushed and cleared by Release
+var exposureBatcher *batcher
+
+// WithExposureBatch Accumulate the exposures across the calls per metrics destination,
+// which is the plugin name, the table ID and the token, and hand them to the plugin
+// in one ExposureGroup, or one SendData call for the remote config and the feature flag exposures,
+// when the batch reaches size records or interval has passed since its first record, whichever comes first.
+// The sampling of the metrics config is still applied per call before batching.
+// The pending exposures are flushed by Release
+func WithExposureBatch(size int, interval time.Duration) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		if size <= 0 {
+			return errors.Errorf("batch size should be positive")
+		}
+		if interval <= 0 {
+			return errors.Errorf("batch interval should be positive")
+		}
+		config.ExposureBatch = &internal.ExposureBatchConfig{Size: size, Interval: interval}
+		return nil
+	}
+}
+
+func initExposureBatcher(config *internal.GlobalConfig) {
+	if config.ExposureBatch == nil {
+		exposureBatcher = nil
+		return
+	}
+	exposureBatcher = newBatcher(config.ExposureBatch.Size, config.ExposureBatch.Interval)
+}
+
+// releaseExposureBatcher Hand the pending exposures to the plugins
+func releaseExposureBatcher() {
+	if exposureBatcher == nil {
+		return
+	}
+	exposureBatcher.flush(context.Background())
+	exposureBatcher = nil
+}
+
+// logExposureGroup Hand the exposures to the plugin, through the batcher if any
+func logExposureGroup(ctx context.Context, metadata *metrics.Metadata,
+	group *protoc_event_server.ExposureGroup) error {
+	b := exposureBatcher
+	if b == nil {
+		return metrics.LogExposure(ctx, metadata, group)
+	}
+	group = metrics.SampleExposures(metadata, group)
+	if group == nil {
+		return nil
+	}
+	return b.add(ctx, metadata, group.Exposures, nil)
+}
+
+// sendDataRows Hand the rows to the plugin, through the batcher if any
+func sendDataRows(ctx context.Context, metadata *metrics.Metadata, data [][]string) error {
+	b := exposureBatcher
+	if b == nil {
+		return metrics.SendData(ctx, metadata, data)
+	}
+	data = metrics.SampleData(metadata, data)
+	if len(data) == 0 {
+		return nil
+	}
+	return b.add(ctx, metadata, nil, data)
+}
+
+// batchKey The destination of a batch
+type batchKey struct {
+	isData     bool // the rows of SendData, otherwise the exposures of LogExposure
+	pluginName string
+	tableID    string
+	token      string
+}
+
+type batch struct {
+	metadata  *metrics.Metadata
+	exposures []*protoc_event_server.Exposure
+	data      [][]string
+	timer     *time.Timer
+}
+
+func (b *batch) len() int {
+	return len(b.exposures) + len(b.data)
+}
+
+// send Hand the batch to the plugin, it is sampled already when the records are added
+func (b *batch) send(ctx context.Context) error {
+	if b.data != nil {
+		return metrics.SendData(ctx, b.metadata, b.data)
+	}
+	return metrics.LogExposure(ctx, b.metadata, &protoc_event_server.ExposureGroup{Exposures: b.exposures})
+}
+
+// batcher Batches of the exposures per destination, a batch is sent by the caller of add when it is full,
+// or by its timer when the interval has passed
+type batcher struct {
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	batches map[batchKey]*batch
+}
+
+func newBatcher(size int, interval time.Duration) *batcher {
+	return &batcher{size: size, interval: interval, batches: make(map[batchKey]*batch)}
+}
+
+// add Add either the exposures or the rows to the batch of the destination of metadata
+func (b *batcher) add(ctx context.Context, metadata *metrics.Metadata,
+	exposures []*protoc_event_server.Exposure, data [][]string) error {
+	key := batchKey{
+		isData:     data != nil,
+		pluginName: metadata.MetricsPluginName,
+		tableID:    metadata.TableID,
+		token:      metadata.Token,
+	}
+	b.mu.Lock()
+	current, ok := b.batches[key]
+	if !ok {
+		batchMetadata := *metadata
+		batchMetadata.SamplingInterval = 1 // sampled per call already
+		current = &batch{metadata: &batchMetadata}
+		b.batches[key] = current
+		current.timer = time.AfterFunc(b.interval, func() { b.flushBatch(key, current) })
+	}
+	current.exposures = append(current.exposures, exposures...)
+	current.data = append(current.data, data...)
+	if current.len() < b.size {
+		b.mu.Unlock()
+		return nil
+	}
+	current.timer.Stop()
+	delete(b.batches, key)
+	b.mu.Unlock()
+	return current.send(ctx)
+}
+
+// flushBatch Send the batch when the interval has passed, unless it is sent already because it is full
+func (b *batcher) flushBatch(key batchKey, expired *batch) {
+	b.mu.Lock()
+	if b.batches[key] != expired {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.batches, key)
+	b.mu.Unlock()
+	b.logSendError(expired.send(context.Background()), expired)
+}
+
+// flush Send all the batches
+func (b *batcher) flush(ctx context.Context) {
+	b.mu.Lock()
+	batches := b.batches
+	b.batches = make(map[batchKey]*batch)
+	b.mu.Unlock()
+	for _, pending := range batches {
+		pending.timer.Stop()
+		b.logSendError(pending.send(ctx), pending)
+	}
+}
+
+func (b *batcher) logSendError(err error, failed *batch) {
+	if err == nil {
+		return
+	}
+	log.ErrorContext(context.Background(), "send exposure batch fail",
+		log.F("plugin", failed.metadata.MetricsPluginName), log.F("records", failed.len()), log.Err(err))
+}
@@ -0,0 +1,136 @@
+package abc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/plugin/metrics"
+	"github.com/abetterchoice/protoc_cache_server"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingMetricsClient The metrics plugin recording the calls
+type recordingMetricsClient struct {
+	name string
+
+	mu        sync.Mutex
+	err       error // returned by LogExposure and SendData, nothing is recorded when it is set
+	exposures []*protoc_event_server.ExposureGroup
+	data      [][][]string
+	events    []*protoc_event_server.EventGroup
+	metadata  []*metrics.Metadata
+}
+
+func (c *recordingMetricsClient) Name() string {
+	return c.name
+}
+
+func (c *recordingMetricsClient) Init(ctx context.Context, config *protoc_cache_server.MetricsInitConfig) error {
+	return nil
+}
+
+func (c *recordingMetricsClient) LogExposure(ctx context.Context, metadata *metrics.Metadata,
+	exposureGroup *protoc_event_server.ExposureGroup) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.exposures = append(c.exposures, exposureGroup)
+	c.metadata = append(c.metadata, metadata)
+	return nil
+}
+
+func (c *recordingMetricsClient) LogEvent(ctx context.Context, metadata *metrics.Metadata,
+	eventGroup *protoc_event_server.EventGroup) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.events = append(c.events, eventGroup)
+	c.metadata = append(c.metadata, metadata)
+	return nil
+}
+
+func (c *recordingMetricsClient) LogMonitorEvent(ctx context.Context, metadata *metrics.Metadata,
+	monitorEventGroup *protoc_event_server.MonitorEventGroup) error {
+	return nil
+}
+
+func (c *recordingMetricsClient) SendData(ctx context.Context, metadata *metrics.Metadata, data [][]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.data = append(c.data, data)
+	c.metadata = append(c.metadata, metadata)
+	return nil
+}
+
+func (c *recordingMetricsClient) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+func (c *recordingMetricsClient) eventCalls() ([]*protoc_event_server.EventGroup, []*metrics.Metadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.events, c.metadata
+}
+
+func (c *recordingMetricsClient) calls() ([]*protoc_event_server.ExposureGroup, [][][]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exposures, c.data
+}
+
+func TestWithExposureBatch(t *testing.T) {
+	config := &internal.GlobalConfig{}
+	assert.NotNil(t, WithExposureBatch(0, time.Second)(config))
+	assert.NotNil(t, WithExposureBatch(10, 0)(config))
+	assert.Nil(t, WithExposureBatch(10, time.Second)(config))
+	assert.Equal(t, &internal.ExposureBatchConfig{Size: 10, Interval: time.Second}, config.ExposureBatch)
+}
+
+func TestExposureBatch(t *testing.T) {
+	client := &recordingMetricsClient{name: "batch_test"}
+	metrics.RegisterClient(client)
+	initExposureBatcher(&internal.GlobalConfig{
+		ExposureBatch: &internal.ExposureBatchConfig{Size: 3, Interval: 50 * time.Millisecond}})
+	defer releaseExposureBatcher()
+	metadata := &metrics.Metadata{MetricsPluginName: client.Name(), TableID: "1", SamplingInterval: 1}
+	other := &metrics.Metadata{MetricsPluginName: client.Name(), TableID: "2", SamplingInterval: 1}
+	ctx := context.Background()
+	for _, unitID := range []string{"u1", "u2", "u3"} { // full at the third one
+		assert.Nil(t, logExposureGroup(ctx, metadata, &protoc_event_server.ExposureGroup{
+			Exposures: []*protoc_event_server.Exposure{{UnitId: unitID}}}))
+	}
+	assert.Nil(t, logExposureGroup(ctx, other, &protoc_event_server.ExposureGroup{
+		Exposures: []*protoc_event_server.Exposure{{UnitId: "u4"}}}))
+	assert.Nil(t, logExposureGroup(ctx, &metrics.Metadata{MetricsPluginName: client.Name(), SamplingInterval: 0},
+		&protoc_event_server.ExposureGroup{Exposures: []*protoc_event_server.Exposure{{UnitId: "u5"}}})) // sampled out
+	assert.Nil(t, sendDataRows(ctx, metadata, [][]string{{"u1"}}))
+	exposures, data := client.calls()
+	assert.Equal(t, 1, len(exposures))
+	assert.Equal(t, 3, len(exposures[0].Exposures))
+	assert.Equal(t, 0, len(data))
+	// the others are sent when the interval has passed
+	assert.Eventually(t, func() bool {
+		exposures, data = client.calls()
+		return len(exposures) == 2 && len(data) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "u4", exposures[1].Exposures[0].UnitId)
+	assert.Equal(t, [][]string{{"u1"}}, data[0])
+	// the pending ones are sent by release
+	assert.Nil(t, sendDataRows(ctx, metadata, [][]string{{"u2"}}))
+	releaseExposureBatcher()
+	_, data = client.calls()
+	assert.Equal(t, 2, len(data))
+}
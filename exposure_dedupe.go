@@ -0,0 +1,99 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/dedupe"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/pkg/errors"
+)
+
+// exposureFilter The filter of the repeated exposures, nil means every exposure is reported.
+// It is set by Init and cleared by Release
+var exposureFilter dedupe.Filter
+
+// DedupeOption The option of WithExposureDedupe
+type DedupeOption func(config *dedupe.Config)
+
+// WithDedupeLRU Remember the exact keys in an LRU of the capacity, the least recently seen key is forgotten
+// when the capacity is reached. This is the default, with the capacity 100000
+func WithDedupeLRU(capacity int) DedupeOption {
+	return func(config *dedupe.Config) {
+		config.Kind = dedupe.KindLRU
+		config.Capacity = capacity
+	}
+}
+
+// WithDedupeBloom Remember the keys in two rotating Bloom filters sized for the keys expected within a window,
+// the memory is fixed while a new exposure is suppressed wrongly at about the false positive rate.
+// A key is suppressed for at least the window and less than twice the window
+func WithDedupeBloom(expectedKeys int, falsePositiveRate float64) DedupeOption {
+	return func(config *dedupe.Config) {
+		config.Kind = dedupe.KindBloom
+		config.Capacity = expectedKeys
+		config.FalsePositiveRate = falsePositiveRate
+	}
+}
+
+// WithExposureDedupe Suppress the exposures repeated within the window, both automatic and manual.
+// An experiment exposure repeats when the projectID, the unitID, the layer and the group are the same,
+// a remote config or feature flag exposure repeats when the projectID, the unitID, the key and the value are the same.
+// The suppressed exposures are counted in Stats as abc_exposure_suppressed_total
+func WithExposureDedupe(window time.Duration, opts ...DedupeOption) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		if window <= 0 {
+			return errors.Errorf("dedupe window should be positive")
+		}
+		dedupeConfig := &dedupe.Config{Window: window, Kind: dedupe.KindLRU, Capacity: dedupe.DefaultCapacity}
+		for _, opt := range opts {
+			opt(dedupeConfig)
+		}
+		if dedupeConfig.Capacity <= 0 {
+			return errors.Errorf("dedupe capacity should be positive")
+		}
+		if dedupeConfig.Kind == dedupe.KindBloom &&
+			(dedupeConfig.FalsePositiveRate <= 0 || dedupeConfig.FalsePositiveRate >= 1) {
+			return errors.Errorf("dedupe false positive rate should be between 0 and 1")
+		}
+		config.ExposureDedupe = dedupeConfig
+		return nil
+	}
+}
+
+func initExposureDedupe(config *internal.GlobalConfig) {
+	if config.ExposureDedupe == nil {
+		exposureFilter = nil
+		return
+	}
+	exposureFilter = dedupe.New(config.ExposureDedupe)
+}
+
+// isRepeatedGroup Whether the exposure of the group to the unit is a repeat within the dedupe window
+func isRepeatedGroup(projectID string, unitID string, group *Group) bool {
+	return isRepeated(stats.ExposureTypeExperiment, projectID, unitID, group.LayerKey,
+		strconv.FormatInt(group.ID, 10))
+}
+
+// isRepeatedConfig Whether the exposure of the remote config or the feature flag to the unit is a repeat
+// within the dedupe window
+func isRepeatedConfig(exposureType string, projectID string, config *ConfigResult) bool {
+	return isRepeated(exposureType, projectID, config.userCtx.unitID, config.Key, string(config.data))
+}
+
+func isRepeated(exposureType string, keyParts ...string) bool {
+	filter := exposureFilter
+	if filter == nil {
+		return false
+	}
+	if !filter.Seen(exposureType + "\xff" + strings.Join(keyParts, "\xff")) {
+		return false
+	}
+	stats.IncExposureSuppressed(exposureType)
+	return true
+}
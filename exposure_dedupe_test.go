@@ -0,0 +1,68 @@
+package abc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/dedupe"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithExposureDedupe(t *testing.T) {
+	config := &internal.GlobalConfig{}
+	assert.NotNil(t, WithExposureDedupe(0)(config))
+	assert.NotNil(t, WithExposureDedupe(time.Minute, WithDedupeLRU(0))(config))
+	assert.NotNil(t, WithExposureDedupe(time.Minute, WithDedupeBloom(1000, 1))(config))
+	assert.Nil(t, config.ExposureDedupe)
+	assert.Nil(t, WithExposureDedupe(time.Minute)(config))
+	assert.Equal(t, &dedupe.Config{Window: time.Minute, Kind: dedupe.KindLRU, Capacity: dedupe.DefaultCapacity},
+		config.ExposureDedupe)
+	assert.Nil(t, WithExposureDedupe(time.Minute, WithDedupeBloom(1000, 0.01))(config))
+	assert.Equal(t, &dedupe.Config{Window: time.Minute, Kind: dedupe.KindBloom, Capacity: 1000,
+		FalsePositiveRate: 0.01}, config.ExposureDedupe)
+}
+
+func TestExposureDedupe(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+	initExposureDedupe(&internal.GlobalConfig{ExposureDedupe: &dedupe.Config{Window: time.Minute}})
+	defer initExposureDedupe(&internal.GlobalConfig{})
+	list := &ExperimentList{
+		userCtx: &userContext{unitID: "u1"},
+		Data: map[string]*Group{
+			"layer1": {ID: 1, LayerKey: "layer1"},
+			"layer2": {ID: 2, LayerKey: "layer2", sceneIDList: []int64{10}},
+		},
+	}
+	sceneDataList, defaultDataList := convertExperimentList(projectID, list,
+		protoc_event_server.ExposureType_EXPOSURE_TYPE_AUTOMATIC, nil)
+	assert.Equal(t, 1, len(defaultDataList.Exposures))
+	assert.Equal(t, 1, len(sceneDataList[10].Exposures))
+	// the same groups are suppressed, the group of another layer is reported
+	list.Data["layer3"] = &Group{ID: 3, LayerKey: "layer3"}
+	sceneDataList, defaultDataList = convertExperimentList(projectID, list,
+		protoc_event_server.ExposureType_EXPOSURE_TYPE_MANUAL, nil)
+	assert.Equal(t, 0, len(sceneDataList))
+	assert.Equal(t, int64(3), defaultDataList.Exposures[0].GroupId)
+	// another unit is reported
+	list.userCtx = &userContext{unitID: "u2"}
+	_, defaultDataList = convertExperimentList(projectID, list,
+		protoc_event_server.ExposureType_EXPOSURE_TYPE_AUTOMATIC, nil)
+	assert.Equal(t, 2, len(defaultDataList.Exposures))
+
+	config := &ConfigResult{userCtx: &userContext{unitID: "u1"},
+		Config: &Config{Key: "key1", Value: &Value{data: []byte("v1")}}}
+	assert.False(t, isRepeatedConfig(stats.ExposureTypeRemoteConfig, projectID, config))
+	assert.True(t, isRepeatedConfig(stats.ExposureTypeRemoteConfig, projectID, config))
+	assert.False(t, isRepeatedConfig(stats.ExposureTypeFeatureFlag, projectID, config))
+	config.data = []byte("v2") // the value changed
+	assert.False(t, isRepeatedConfig(stats.ExposureTypeRemoteConfig, projectID, config))
+
+	assert.Equal(t, []stats.Counter{
+		{Labels: map[string]string{stats.LabelType: stats.ExposureTypeExperiment}, Value: 2},
+		{Labels: map[string]string{stats.LabelType: stats.ExposureTypeRemoteConfig}, Value: 1},
+	}, stats.Collect(nil, nil).ExposureSuppressed)
+}
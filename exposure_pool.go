@@ -5,20 +5,41 @@ package abc
 
 import (
 	"context"
-	"fmt"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/internal/stats"
 	"github.com/abetterchoice/go-sdk/plugin/log"
 	"github.com/abetterchoice/protoc_event_server"
+	"github.com/pkg/errors"
 )
 
+// exposureTask The record waiting in an exposure channel
+type exposureTask interface {
+	report(ctx context.Context)
+}
+
 type experimentExposure struct {
 	projectID string
 	list      *ExperimentList
 	et        protoc_event_server.ExposureType
 }
 
+func (e *experimentExposure) report(ctx context.Context) {
+	if e.list == nil || len(e.list.Data) == 0 {
+		return
+	}
+	err := exposureExperiments(ctx, e.projectID, e.list, e.et)
+	if err != nil {
+		stats.IncExposureError(ChannelExperimentExposure)
+		log.ErrorContext(ctx, "exposureExperiments fail", log.F(log.FieldProjectID, e.projectID), log.Err(err))
+	}
+}
+
 type experimentEvent struct {
 	projectID string
 	list      *ExperimentList
@@ -27,12 +48,35 @@ type experimentEvent struct {
 	err       error
 }
 
+func (e *experimentEvent) report(ctx context.Context) {
+	if e.list == nil || len(e.list.Data) == 0 {
+		return
+	}
+	err := exposureExperimentEvent(ctx, e.projectID, e.list, e.latency, e.optionStr, e.err)
+	if err != nil {
+		stats.IncExposureError(ChannelExperimentEvent)
+		log.ErrorContext(ctx, "exposureExperimentEvent fail", log.F(log.FieldProjectID, e.projectID), log.Err(err))
+	}
+}
+
 type remoteConfigExposure struct {
 	projectID    string
 	configResult *ConfigResult
 	et           protoc_event_server.ExposureType
 }
 
+func (c *remoteConfigExposure) report(ctx context.Context) {
+	if c.configResult == nil {
+		return
+	}
+	err := exposureRemoteConfig(ctx, c.projectID, c.configResult, c.et)
+	if err != nil {
+		stats.IncExposureError(ChannelRemoteConfigExposure)
+		log.ErrorContext(ctx, "exposureRemoteConfig fail", log.F(log.FieldProjectID, c.projectID),
+			log.F(log.FieldConfigKey, c.configResult.Key), log.Err(err))
+	}
+}
+
 type remoteConfigEvent struct {
 	projectID    string
 	configResult *ConfigResult
@@ -41,22 +85,68 @@ type remoteConfigEvent struct {
 	err          error
 }
 
+func (c *remoteConfigEvent) report(ctx context.Context) {
+	if c.configResult == nil {
+		return
+	}
+	err := exposureRemoteConfigEvent(ctx, c.projectID, c.configResult, c.latency, c.optionStr, c.err)
+	if err != nil {
+		stats.IncExposureError(ChannelRemoteConfigEvent)
+		log.ErrorContext(ctx, "exposureRemoteConfigEvent fail", log.F(log.FieldProjectID, c.projectID),
+			log.F(log.FieldConfigKey, c.configResult.Key), log.Err(err))
+	}
+}
+
+type trackEvent struct {
+	projectID   string
+	application *cache.Application // the local cache the assignments are evaluated on
+	reports     []*trackEventReport
+}
+
+func (e *trackEvent) report(ctx context.Context) {
+	logTrackEvent(ctx, e.application, e.reports)
+}
+
 var (
-	// ExperimentExposureChanSize TODO
+	// ExperimentExposureChanSize The default buffer size of the experiment exposure channel, read by Init
 	ExperimentExposureChanSize = 1 << 19
-	// ExperimentEventChanSize TODO
+	// ExperimentEventChanSize The default buffer size of the experiment event channel, read by Init
 	ExperimentEventChanSize = 1 << 19
-	// RemoteConfigExposureChanSize TODO
+	// RemoteConfigExposureChanSize The default buffer size of the remote config exposure channel, read by Init
 	RemoteConfigExposureChanSize = 1 << 19
-	// RemoteConfigEventChanSize TODO
+	// RemoteConfigEventChanSize The default buffer size of the remote config event channel, read by Init
 	RemoteConfigEventChanSize = 1 << 19
+	// TrackEventChanSize The default buffer size of the channel of the events of Track, read by Init
+	TrackEventChanSize = 1 << 19
 )
 
-var (
-	experimentExposureChan   = make(chan *experimentExposure, ExperimentExposureChanSize)
-	experimentEventChan      = make(chan *experimentEvent, ExperimentEventChanSize)
-	remoteConfigExposureChan = make(chan *remoteConfigExposure, RemoteConfigExposureChanSize)
-	remoteConfigEventChan    = make(chan *remoteConfigEvent, RemoteConfigEventChanSize)
+// Exposure channel names of the runtime statistics and ExposureDrops
+const (
+	ChannelExperimentExposure   = "experiment_exposure"
+	ChannelExperimentEvent      = "experiment_event"
+	ChannelRemoteConfigExposure = "remote_config_exposure"
+	ChannelRemoteConfigEvent    = "remote_config_event"
+	ChannelTrackEvent           = "track_event"
+)
+
+// ExposurePipelineConfig The buffer sizes, the worker count and the overflow policies of the exposure channels,
+// see WithExposurePipeline
+type ExposurePipelineConfig = internal.ExposurePipelineConfig
+
+// ExposureChannelConfig The buffer size and the overflow policy of an exposure channel
+type ExposureChannelConfig = internal.ExposureChannelConfig
+
+// OverflowPolicy What an exposure channel does with a record when it is full
+type OverflowPolicy = internal.OverflowPolicy
+
+// The overflow policies of the exposure channels
+const (
+	// OverflowDropNewest Drop the record being added, the default
+	OverflowDropNewest = internal.OverflowDropNewest
+	// OverflowDropOldest Drop the oldest record waiting in the channel to make room
+	OverflowDropOldest = internal.OverflowDropOldest
+	// OverflowBlock Block the caller until there is room or the block timeout passes, then drop the record
+	OverflowBlock = internal.OverflowBlock
 )
 
 var (
@@ -71,129 +161,322 @@ func maxParallelism() int {
 	return defaultMaxParallelism
 }
 
+// WithExposurePipeline Configure the exposure channels between the evaluations and the metrics plugins,
+// the zero values keep the defaults. A record is dropped by the overflow policy when its channel is full,
+// the drops are counted in ExposureDrops and Stats instead of being logged on the request path
+//
+// example:
+//
+//	abc.WithExposurePipeline(abc.ExposurePipelineConfig{
+//		Workers: 8,
+//		ExperimentExposure: abc.ExposureChannelConfig{Size: 1 << 16, Overflow: abc.OverflowBlock,
+//			BlockTimeout: time.Millisecond},
+//	})
+func WithExposurePipeline(pipelineConfig ExposurePipelineConfig) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		if pipelineConfig.Workers < 0 {
+			return errors.Errorf("workers should not be negative")
+		}
+		for name, channelConfig := range map[string]ExposureChannelConfig{
+			ChannelExperimentExposure:   pipelineConfig.ExperimentExposure,
+			ChannelExperimentEvent:      pipelineConfig.ExperimentEvent,
+			ChannelRemoteConfigExposure: pipelineConfig.RemoteConfigExposure,
+			ChannelRemoteConfigEvent:    pipelineConfig.RemoteConfigEvent,
+			ChannelTrackEvent:           pipelineConfig.TrackEvent,
+		} {
+			if channelConfig.Size < 0 {
+				return errors.Errorf("size of %s should not be negative", name)
+			}
+			switch channelConfig.Overflow {
+			case "", OverflowDropNewest, OverflowDropOldest:
+			case OverflowBlock:
+				if channelConfig.BlockTimeout <= 0 {
+					return errors.Errorf("block timeout of %s should be positive", name)
+				}
+			default:
+				return errors.Errorf("unknown overflow policy %s of %s", channelConfig.Overflow, name)
+			}
+		}
+		config.ExposurePipeline = &pipelineConfig
+		return nil
+	}
+}
+
+// ExposureDrops The count of the records dropped by each exposure channel since the process started,
+// the key is the channel name such as ChannelExperimentExposure
+func ExposureDrops() map[string]uint64 {
+	return stats.ExposureDrops()
+}
+
+// exposureQueue An exposure channel with its overflow policy
+type exposureQueue struct {
+	name         string
+	tasks        chan exposureTask
+	overflow     OverflowPolicy
+	blockTimeout time.Duration
+}
+
+func newExposureQueue(name string, defaultSize int, config ExposureChannelConfig) *exposureQueue {
+	size := config.Size
+	if size == 0 {
+		size = defaultSize
+	}
+	return &exposureQueue{
+		name:         name,
+		tasks:        make(chan exposureTask, size),
+		overflow:     config.Overflow,
+		blockTimeout: config.BlockTimeout,
+	}
+}
+
+// push Add the task, apply the overflow policy if the channel is full
+func (q *exposureQueue) push(task exposureTask) {
+	select {
+	case q.tasks <- task:
+		return
+	default:
+	}
+	switch q.overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case <-q.tasks:
+				stats.IncExposureDrop(q.name)
+			default:
+			}
+			select {
+			case q.tasks <- task:
+				return
+			default: // taken by the other callers, try again
+			}
+		}
+	case OverflowBlock:
+		timer := time.NewTimer(q.blockTimeout)
+		defer timer.Stop()
+		select {
+		case q.tasks <- task:
+		case <-timer.C:
+			stats.IncExposureDrop(q.name)
+		}
+	default:
+		stats.IncExposureDrop(q.name)
+	}
+}
+
+func (q *exposureQueue) channel() stats.Channel {
+	return stats.Channel{Name: q.name, Depth: len(q.tasks), Capacity: cap(q.tasks)}
+}
+
+// exposurePipeline The exposure channels and their workers
+type exposurePipeline struct {
+	experimentExposure   *exposureQueue
+	experimentEvent      *exposureQueue
+	remoteConfigExposure *exposureQueue
+	remoteConfigEvent    *exposureQueue
+	trackEvent           *exposureQueue
+
+	mu      sync.RWMutex // guard stopped against the pushes
+	stopped bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// pipeline The *exposurePipeline, the records are ignored when it is nil.
+// It is set by Init and stopped by Release while the evaluations push to it
+var pipeline atomic.Value
+
+// currentPipeline The exposure pipeline, nil before Init and after Release
+func currentPipeline() *exposurePipeline {
+	p, _ := pipeline.Load().(*exposurePipeline)
+	return p
+}
+
+// push Add the task to the channel, the task is dropped at once if the pipeline is stopped
+func (p *exposurePipeline) push(q *exposureQueue, task exposureTask) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		stats.IncExposureDrop(q.name)
+		return
+	}
+	q.push(task)
+}
+
+// stop Stop the workers after the records waiting in the channels are reported, the later pushes are dropped
+func (p *exposurePipeline) stop() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.stopped = true
+	p.mu.Unlock()
+	close(p.done)
+	p.wg.Wait()
+}
+
 // initExposureConsumer Initialize exposure reporting consumer
-func initExposureConsumer() {
-	for i := 0; i < maxParallelism(); i++ {
-		go watchData()
+func initExposureConsumer(config *internal.GlobalConfig) {
+	var pipelineConfig ExposurePipelineConfig
+	if config.ExposurePipeline != nil {
+		pipelineConfig = *config.ExposurePipeline
+	}
+	p := &exposurePipeline{
+		experimentExposure: newExposureQueue(ChannelExperimentExposure, ExperimentExposureChanSize,
+			pipelineConfig.ExperimentExposure),
+		experimentEvent: newExposureQueue(ChannelExperimentEvent, ExperimentEventChanSize,
+			pipelineConfig.ExperimentEvent),
+		remoteConfigExposure: newExposureQueue(ChannelRemoteConfigExposure, RemoteConfigExposureChanSize,
+			pipelineConfig.RemoteConfigExposure),
+		remoteConfigEvent: newExposureQueue(ChannelRemoteConfigEvent, RemoteConfigEventChanSize,
+			pipelineConfig.RemoteConfigEvent),
+		trackEvent: newExposureQueue(ChannelTrackEvent, TrackEventChanSize, pipelineConfig.TrackEvent),
+		done:       make(chan struct{}),
+	}
+	workers := pipelineConfig.Workers
+	if workers == 0 {
+		workers = maxParallelism()
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.watchData()
+	}
+	pipeline.Store(p)
+}
+
+// releaseExposureConsumer Stop the workers after the records waiting in the channels are reported
+func releaseExposureConsumer() {
+	p, _ := pipeline.Swap((*exposurePipeline)(nil)).(*exposurePipeline)
+	if p == nil {
+		return
 	}
+	p.stop()
+}
+
+// exposureChannels The states of the exposure channels, the defaults before Init
+func exposureChannels() []stats.Channel {
+	p := currentPipeline()
+	if p == nil {
+		return []stats.Channel{
+			{Name: ChannelExperimentExposure, Capacity: ExperimentExposureChanSize},
+			{Name: ChannelExperimentEvent, Capacity: ExperimentEventChanSize},
+			{Name: ChannelRemoteConfigExposure, Capacity: RemoteConfigExposureChanSize},
+			{Name: ChannelRemoteConfigEvent, Capacity: RemoteConfigEventChanSize},
+			{Name: ChannelTrackEvent, Capacity: TrackEventChanSize},
+		}
+	}
+	return []stats.Channel{p.experimentExposure.channel(), p.experimentEvent.channel(),
+		p.remoteConfigExposure.channel(), p.remoteConfigEvent.channel(), p.trackEvent.channel()}
 }
 
 // asyncExposureExperiments asynchronous push
 // Record exposure data. If passive exposure is not enabled, you can use the Exposure API for manual exposure
 // Manual exposure can avoid the overexposure problem that may be caused by passive exposure. Users can use manual exposure to report the exposure of the experiment they hit
 func asyncExposureExperiments(projectID string, list *ExperimentList,
-	exposureType protoc_event_server.ExposureType) error {
-	select {
-	case experimentExposureChan <- &experimentExposure{
-		projectID: projectID,
-		list:      list,
-		et:        exposureType,
-	}:
-		return nil
-	default:
-		return fmt.Errorf("experimentExposureChan is full")
+	exposureType protoc_event_server.ExposureType) {
+	if p := currentPipeline(); p != nil {
+		p.push(p.experimentExposure, &experimentExposure{projectID: projectID, list: list, et: exposureType})
 	}
 }
 
 // asyncExposureExperimentEvent async exposure
 func asyncExposureExperimentEvent(projectID string, list *ExperimentList,
-	latency time.Duration, optionStr string, err error) error {
-	select {
-	case experimentEventChan <- &experimentEvent{
-		projectID: projectID,
-		list:      list,
-		latency:   latency,
-		optionStr: optionStr,
-		err:       err,
-	}:
-		return nil
-	default:
-		return fmt.Errorf("experimentEventChan is full")
+	latency time.Duration, optionStr string, err error) {
+	if p := currentPipeline(); p != nil {
+		p.push(p.experimentEvent, &experimentEvent{
+			projectID: projectID,
+			list:      list,
+			latency:   latency,
+			optionStr: optionStr,
+			err:       err,
+		})
 	}
 }
 
 // asyncExposureRemoteConfig async exposure
 func asyncExposureRemoteConfig(projectID string, configResult *ConfigResult,
-	exposureType protoc_event_server.ExposureType) error {
-	select {
-	case remoteConfigExposureChan <- &remoteConfigExposure{
-		projectID:    projectID,
-		configResult: configResult,
-		et:           exposureType,
-	}:
-		return nil
-	default:
-		return fmt.Errorf("remoteConfigExposureChan is full")
+	exposureType protoc_event_server.ExposureType) {
+	if p := currentPipeline(); p != nil {
+		p.push(p.remoteConfigExposure, &remoteConfigExposure{
+			projectID:    projectID,
+			configResult: configResult,
+			et:           exposureType,
+		})
 	}
 }
 
 // asyncExposureRemoteConfigEvent async exposure
 func asyncExposureRemoteConfigEvent(projectID string, configResult *ConfigResult,
-	latency time.Duration, optionStr string, err error) error {
-	select {
-	case remoteConfigEventChan <- &remoteConfigEvent{
-		projectID:    projectID,
-		configResult: configResult,
-		latency:      latency,
-		optionStr:    optionStr,
-		err:          err,
-	}:
-		return nil
-	default:
-		return fmt.Errorf("remoteConfigEventChan is full")
+	latency time.Duration, optionStr string, err error) {
+	if p := currentPipeline(); p != nil {
+		p.push(p.remoteConfigEvent, &remoteConfigEvent{
+			projectID:    projectID,
+			configResult: configResult,
+			latency:      latency,
+			optionStr:    optionStr,
+			err:          err,
+		})
 	}
 }
 
-func watchData() {
+// asyncTrackEvent async event of Track
+func asyncTrackEvent(projectID string, application *cache.Application, reports []*trackEventReport) {
+	if p := currentPipeline(); p != nil {
+		p.push(p.trackEvent, &trackEvent{projectID: projectID, application: application, reports: reports})
+	}
+}
+
+func (p *exposurePipeline) watchData() {
+	defer p.wg.Done()
 	for {
-		logExposure()
+		select {
+		case task := <-p.experimentExposure.tasks:
+			logExposure(task)
+		case task := <-p.experimentEvent.tasks:
+			logExposure(task)
+		case task := <-p.remoteConfigExposure.tasks:
+			logExposure(task)
+		case task := <-p.remoteConfigEvent.tasks:
+			logExposure(task)
+		case task := <-p.trackEvent.tasks:
+			logExposure(task)
+		case <-p.done:
+			for task := p.next(); task != nil; task = p.next() {
+				logExposure(task)
+			}
+			return
+		}
+	}
+}
+
+// next A waiting task of any channel, nil if all the channels are empty
+func (p *exposurePipeline) next() exposureTask {
+	select {
+	case task := <-p.experimentExposure.tasks:
+		return task
+	case task := <-p.experimentEvent.tasks:
+		return task
+	case task := <-p.remoteConfigExposure.tasks:
+		return task
+	case task := <-p.remoteConfigEvent.tasks:
+		return task
+	case task := <-p.trackEvent.tasks:
+		return task
+	default:
+		return nil
 	}
 }
 
-func logExposure() {
+func logExposure(task exposureTask) {
 	defer func() {
 		recoverErr := recover() // Prevent third-party monitoring reporting plugins from panicking
 		if recoverErr != nil {
 			body := make([]byte, 1<<10)
 			runtime.Stack(body, false)
-			log.Errorf("recoverErr:%v\n%s", recoverErr, body)
+			log.ErrorContext(context.Background(), "recover", log.F("recoverErr", recoverErr),
+				log.F("stack", string(body)))
 			return
 		}
 	}()
-	select {
-	case eExposure := <-experimentExposureChan:
-		if eExposure == nil || eExposure.list == nil || len(eExposure.list.Data) == 0 {
-			return
-		}
-		err := exposureExperiments(context.TODO(), eExposure.projectID, eExposure.list, eExposure.et)
-		if err != nil {
-			// log.Errorf("exposureExperiments fail:%v", err)
-		}
-	case eEvent := <-experimentEventChan:
-		if eEvent == nil || eEvent.list == nil || len(eEvent.list.Data) == 0 {
-			return
-		}
-		err := exposureExperimentEvent(context.TODO(), eEvent.projectID, eEvent.list, eEvent.latency, eEvent.optionStr,
-			eEvent.err)
-		if err != nil {
-			// log.Errorf("exposureExperimentEvent fail:%v", err)
-		}
-	case cExposure := <-remoteConfigExposureChan:
-		if cExposure == nil || cExposure.configResult == nil {
-			return
-		}
-		err := exposureRemoteConfig(context.TODO(), cExposure.projectID, cExposure.configResult, cExposure.et)
-		if err != nil {
-			log.Errorf("exposureRemoteConfig fail:%v", err)
-		}
-	case cEvent := <-remoteConfigEventChan:
-		if cEvent == nil || cEvent.configResult == nil {
-			return
-		}
-		err := exposureRemoteConfigEvent(context.TODO(), cEvent.projectID, cEvent.configResult, cEvent.latency,
-			cEvent.optionStr, cEvent.err)
-		if err != nil {
-			log.Errorf("exposureRemoteConfig fail:%v", err)
-		}
-	}
+	task.report(context.TODO())
 }
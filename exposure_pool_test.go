@@ -0,0 +1,131 @@
+package abc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingTask The task counting its reports
+type countingTask struct {
+	reported *int32
+}
+
+func (t *countingTask) report(ctx context.Context) {
+	atomic.AddInt32(t.reported, 1)
+}
+
+func TestWithExposurePipeline(t *testing.T) {
+	config := &internal.GlobalConfig{}
+	assert.NotNil(t, WithExposurePipeline(ExposurePipelineConfig{Workers: -1})(config))
+	assert.NotNil(t, WithExposurePipeline(ExposurePipelineConfig{
+		ExperimentEvent: ExposureChannelConfig{Size: -1}})(config))
+	assert.NotNil(t, WithExposurePipeline(ExposurePipelineConfig{
+		RemoteConfigExposure: ExposureChannelConfig{Overflow: OverflowBlock}})(config))
+	assert.NotNil(t, WithExposurePipeline(ExposurePipelineConfig{
+		RemoteConfigEvent: ExposureChannelConfig{Overflow: "unknown"}})(config))
+	assert.Nil(t, config.ExposurePipeline)
+	pipelineConfig := ExposurePipelineConfig{Workers: 2, ExperimentExposure: ExposureChannelConfig{
+		Size: 8, Overflow: OverflowBlock, BlockTimeout: time.Millisecond}}
+	assert.Nil(t, WithExposurePipeline(pipelineConfig)(config))
+	assert.Equal(t, &pipelineConfig, config.ExposurePipeline)
+}
+
+func TestExposureQueue_push(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+	var reported int32
+	first, second := &countingTask{reported: &reported}, &countingTask{reported: &reported}
+
+	dropNewest := newExposureQueue(ChannelExperimentExposure, 1, ExposureChannelConfig{})
+	dropNewest.push(first)
+	dropNewest.push(second)
+	assert.Equal(t, first, <-dropNewest.tasks)
+
+	dropOldest := newExposureQueue(ChannelExperimentEvent, 1, ExposureChannelConfig{Overflow: OverflowDropOldest})
+	dropOldest.push(first)
+	dropOldest.push(second)
+	assert.Equal(t, second, <-dropOldest.tasks)
+
+	block := newExposureQueue(ChannelRemoteConfigExposure, 0,
+		ExposureChannelConfig{Size: 1, Overflow: OverflowBlock, BlockTimeout: 100 * time.Millisecond})
+	block.push(first)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-block.tasks
+	}()
+	block.push(second) // room is made within the timeout
+	assert.Equal(t, second, <-block.tasks)
+	block.push(first)
+	start := time.Now()
+	block.push(second) // timeout
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
+
+	assert.Equal(t, map[string]uint64{
+		ChannelExperimentExposure:   1,
+		ChannelExperimentEvent:      1,
+		ChannelRemoteConfigExposure: 1,
+	}, ExposureDrops())
+}
+
+func TestExposurePipeline(t *testing.T) {
+	initExposureConsumer(&internal.GlobalConfig{ExposurePipeline: &ExposurePipelineConfig{
+		Workers: 1, RemoteConfigEvent: ExposureChannelConfig{Size: 16}}})
+	p := currentPipeline()
+	assert.Equal(t, 16, cap(p.remoteConfigEvent.tasks))
+	assert.Equal(t, ExperimentExposureChanSize, cap(p.experimentExposure.tasks))
+	var reported int32
+	for i := 0; i < 10; i++ {
+		p.remoteConfigEvent.push(&countingTask{reported: &reported})
+		p.experimentExposure.push(&countingTask{reported: &reported})
+	}
+	releaseExposureConsumer() // the waiting tasks are reported before the workers stop
+	assert.Equal(t, int32(20), atomic.LoadInt32(&reported))
+	assert.Nil(t, currentPipeline())
+	asyncExposureExperiments(projectID, &ExperimentList{}, 0) // ignored after release
+	assert.Len(t, exposureChannels(), 5)
+
+	// the push holding the stopped pipeline is dropped at once
+	stats.Reset()
+	defer stats.Reset()
+	p.push(p.experimentEvent, &countingTask{reported: &reported})
+	assert.Equal(t, 0, len(p.experimentEvent.tasks))
+	assert.Equal(t, map[string]uint64{ChannelExperimentEvent: 1}, ExposureDrops())
+	releaseExposureConsumer() // released already
+}
+
+func TestExposurePipeline_concurrentRelease(t *testing.T) {
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				asyncExposureExperiments(projectID, &ExperimentList{}, 0)
+				_ = exposureChannels()
+			}
+		}()
+	}
+	small := ExposureChannelConfig{Size: 16}
+	config := &internal.GlobalConfig{ExposurePipeline: &ExposurePipelineConfig{Workers: 1, ExperimentExposure: small,
+		ExperimentEvent: small, RemoteConfigExposure: small, RemoteConfigEvent: small, TrackEvent: small}}
+	for i := 0; i < 10; i++ {
+		initExposureConsumer(config)
+		releaseExposureConsumer()
+	}
+	close(stop)
+	wg.Wait()
+	assert.Nil(t, currentPipeline())
+}
@@ -0,0 +1,166 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/experiment"
+	"github.com/pkg/errors"
+)
+
+// PrivacyOption The option of WithPrivacy
+type PrivacyOption func(config *internal.PrivacyConfig)
+
+// WithIDHashing Report the HMAC-SHA256 of the unitID, the decisionID and the newUnitID keyed by the key,
+// hex encoded, instead of the raw IDs. The same ID is always reported as the same hash under the same key,
+// so the exposures, the rows and the events of a unit can still be joined
+func WithIDHashing(key []byte) PrivacyOption {
+	return func(config *internal.PrivacyConfig) {
+		config.HashKey = append([]byte(nil), key...)
+	}
+}
+
+// WithExtraDataAllowlist Report only these keys of the expanded data, the newUnitID is reported as new_id
+func WithExtraDataAllowlist(keys ...string) PrivacyOption {
+	return func(config *internal.PrivacyConfig) {
+		config.ExtraDataAllowlist = keySet(keys)
+	}
+}
+
+// WithExtraDataDenylist Report the expanded data except these keys
+func WithExtraDataDenylist(keys ...string) PrivacyOption {
+	return func(config *internal.PrivacyConfig) {
+		config.ExtraDataDenylist = keySet(keys)
+	}
+}
+
+// WithExtraDataLimits Report at most maxKeys keys of the expanded data, the first ones in order,
+// and at most maxValueLength characters of each value. 0 means no limit. The new_id of the newUnitID
+// is not counted nor truncated, so its hash still joins
+func WithExtraDataLimits(maxKeys int, maxValueLength int) PrivacyOption {
+	return func(config *internal.PrivacyConfig) {
+		config.MaxExtraDataKeys = maxKeys
+		config.MaxValueLength = maxValueLength
+	}
+}
+
+// WithPrivacy Hash or redact the user identifiers and the expanded data before they are reported.
+// The policy applies to the exposures of LogExposure, the rows of SendData, the events of Track and the options
+// in the InputData of the monitor events. The evaluation, the dedupe and the overrides still use the raw IDs
+func WithPrivacy(opts ...PrivacyOption) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		privacyConfig := &internal.PrivacyConfig{}
+		for _, opt := range opts {
+			opt(privacyConfig)
+		}
+		if len(privacyConfig.ExtraDataAllowlist) != 0 && len(privacyConfig.ExtraDataDenylist) != 0 {
+			return errors.Errorf("extra data allowlist and denylist should not be both set")
+		}
+		if privacyConfig.MaxExtraDataKeys < 0 || privacyConfig.MaxValueLength < 0 {
+			return errors.Errorf("extra data limits should not be negative")
+		}
+		config.Privacy = privacyConfig
+		return nil
+	}
+}
+
+func keySet(keys []string) map[string]bool {
+	if len(keys) == 0 {
+		return nil
+	}
+	var result = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		result[key] = true
+	}
+	return result
+}
+
+// reportedID The ID as reported by the privacy policy, the HMAC of the ID if the hashing is enabled.
+// The empty ID stays empty
+func reportedID(id string) string {
+	policy := internal.C.Privacy
+	if policy == nil || len(policy.HashKey) == 0 || id == "" {
+		return id
+	}
+	mac := hmac.New(sha256.New, policy.HashKey)
+	_, _ = mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// reportedExtraData The expanded data as reported by the privacy policy, nil if no key is left.
+// The limits do not apply to the new ID. The extraData is modified
+func reportedExtraData(extraData map[string]string) map[string]string {
+	policy := internal.C.Privacy
+	if policy == nil || len(extraData) == 0 {
+		return extraData
+	}
+	var keys = make([]string, 0, len(extraData))
+	for key := range extraData {
+		if len(policy.ExtraDataAllowlist) != 0 && !policy.ExtraDataAllowlist[key] {
+			delete(extraData, key)
+			continue
+		}
+		if policy.ExtraDataDenylist[key] {
+			delete(extraData, key)
+			continue
+		}
+		if key != newIDKey { // the new ID is joined on, it is out of the limits
+			keys = append(keys, key)
+		}
+	}
+	if policy.MaxExtraDataKeys > 0 && len(keys) > policy.MaxExtraDataKeys {
+		sort.Strings(keys)
+		for _, key := range keys[policy.MaxExtraDataKeys:] {
+			delete(extraData, key)
+		}
+	}
+	if value, ok := extraData[newIDKey]; ok {
+		extraData[newIDKey] = reportedID(value)
+	}
+	if policy.MaxValueLength > 0 {
+		for _, key := range keys {
+			if value, ok := extraData[key]; ok {
+				extraData[key] = truncate(value, policy.MaxValueLength)
+			}
+		}
+	}
+	if len(extraData) == 0 {
+		return nil
+	}
+	return extraData
+}
+
+// truncate The first maxLength characters of the value
+func truncate(value string, maxLength int) string {
+	if len(value) <= maxLength { // the bytes are not less than the characters
+		return value
+	}
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[:maxLength])
+}
+
+// optionsJSON The options reported in the InputData of the monitor events. With the ID hashing,
+// the IDs are hashed and the DMP tag results, keyed by the raw IDs, are dropped
+func optionsJSON(options *experiment.Options) string {
+	policy := internal.C.Privacy
+	if policy == nil || len(policy.HashKey) == 0 {
+		return env.JSONString(options)
+	}
+	reported := *options
+	reported.UnitID = reportedID(options.UnitID)
+	reported.DecisionID = reportedID(options.DecisionID)
+	reported.NewUnitID = reportedID(options.NewUnitID)
+	reported.NewDecisionID = reportedID(options.NewDecisionID)
+	reported.DMPTagValueResult = nil
+	return env.JSONString(&reported)
+}
@@ -0,0 +1,89 @@
+package abc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/experiment"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/stretchr/testify/assert"
+)
+
+func hmacHex(key string, id string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWithPrivacy(t *testing.T) {
+	config := &internal.GlobalConfig{}
+	assert.NotNil(t, WithPrivacy(WithExtraDataAllowlist("a"), WithExtraDataDenylist("b"))(config))
+	assert.NotNil(t, WithPrivacy(WithExtraDataLimits(-1, 0))(config))
+	assert.Nil(t, config.Privacy)
+	key := []byte("key")
+	assert.Nil(t, WithPrivacy(WithIDHashing(key), WithExtraDataDenylist("phone"))(config))
+	key[0] = 'K' // the key is copied
+	assert.Equal(t, []byte("key"), config.Privacy.HashKey)
+	assert.Equal(t, map[string]bool{"phone": true}, config.Privacy.ExtraDataDenylist)
+}
+
+func TestPrivacy_exposure(t *testing.T) {
+	defer func() { internal.C.Privacy = nil }()
+	userCtx := NewUserContext("u1", WithDecisionID("d1"), WithNewUnitID("n1"),
+		WithExpandedData(map[string]string{"phone": "13800000000", "channel": "advertisement"})).(*userContext)
+	group := &Group{ID: 1, LayerKey: "layer"}
+	// no policy, reported as passed in
+	exposure := convertExperimentV2("p", group, userCtx, protoc_event_server.ExposureType_EXPOSURE_TYPE_MANUAL, 0)
+	assert.Equal(t, "u1", exposure.UnitId)
+	assert.Equal(t, "d1", exposure.ClusterId)
+	assert.Equal(t, map[string]string{"phone": "13800000000", "channel": "advertisement", newIDKey: "n1"},
+		exposure.ExtraData)
+
+	assert.Nil(t, WithPrivacy(WithIDHashing([]byte("key")), WithExtraDataDenylist("phone"),
+		WithExtraDataLimits(0, 3))(internal.C))
+	exposure = convertExperimentV2("p", group, userCtx, protoc_event_server.ExposureType_EXPOSURE_TYPE_MANUAL, 0)
+	assert.Equal(t, hmacHex("key", "u1"), exposure.UnitId)
+	assert.Equal(t, hmacHex("key", "d1"), exposure.ClusterId)
+	assert.Equal(t, map[string]string{"channel": "adv", newIDKey: hmacHex("key", "n1")}, exposure.ExtraData)
+	// the rows of SendData are redacted the same way
+	assert.Equal(t, "channel=adv;new_id="+hmacHex("key", "n1"), marshalExpandedData(userCtx))
+	assert.Equal(t, "u1", userCtx.unitID) // the evaluation still uses the raw IDs
+	assert.Equal(t, "13800000000", userCtx.expandedData["phone"])
+}
+
+func TestPrivacy_extraData(t *testing.T) {
+	defer func() { internal.C.Privacy = nil }()
+	assert.Nil(t, WithPrivacy(WithExtraDataAllowlist("a", "b", "c"), WithExtraDataLimits(2, 0))(internal.C))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"},
+		reportedExtraData(map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}))
+	assert.Nil(t, reportedExtraData(map[string]string{"d": "4"}))
+	assert.Equal(t, "", reportedID("")) // no hashing
+	assert.Equal(t, "u1", reportedID("u1"))
+	assert.Equal(t, "实验", truncate("实验数据", 2))
+	assert.Equal(t, "ab", truncate("ab", 2))
+}
+
+func TestPrivacy_newIDLimits(t *testing.T) {
+	defer func() { internal.C.Privacy = nil }()
+	assert.Nil(t, WithPrivacy(WithIDHashing([]byte("key")), WithExtraDataLimits(1, 8))(internal.C))
+	// the hash of the new ID is neither truncated nor dropped by the key limit, the other keys are limited
+	assert.Equal(t, map[string]string{"a": "12345678", newIDKey: hmacHex("key", "n1")},
+		reportedExtraData(map[string]string{"a": "1234567890", "b": "2", newIDKey: "n1"}))
+	userCtx := NewUserContext("u1", WithNewUnitID("n1")).(*userContext)
+	exposure := convertExperimentV2("p", &Group{ID: 1, LayerKey: "layer"}, userCtx,
+		protoc_event_server.ExposureType_EXPOSURE_TYPE_MANUAL, 0)
+	assert.Equal(t, map[string]string{newIDKey: hmacHex("key", "n1")}, exposure.ExtraData)
+}
+
+func Test_optionsJSON(t *testing.T) {
+	defer func() { internal.C.Privacy = nil }()
+	options := &experiment.Options{UnitID: "u1", DecisionID: "d1", DMPTagValueResult: map[string]string{"u1-1-k": "1"}}
+	assert.Equal(t, `{"dmpTagValueResult":{"u1-1-k":"1"},"unitId":"u1","decisionId":"d1"}`, optionsJSON(options))
+	assert.Nil(t, WithPrivacy(WithIDHashing([]byte("key")))(internal.C))
+	assert.Equal(t, `{"dmpTagValueResult":null,"unitId":"`+hmacHex("key", "u1")+`","decisionId":"`+
+		hmacHex("key", "d1")+`"}`, optionsJSON(options))
+	assert.Equal(t, "u1", options.UnitID)
+}
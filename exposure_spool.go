@@ -0,0 +1,235 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/spool"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/plugin/metrics"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// The defaults of the exposure spool
+const (
+	DefaultSpoolMaxBytes     = spool.DefaultMaxBytes
+	DefaultSpoolSegmentBytes = spool.DefaultSegmentBytes
+	DefaultSpoolMinBackoff   = time.Second
+	DefaultSpoolMaxBackoff   = time.Minute
+)
+
+// exposureSpool The spool of the exposures whose delivery failed, nil means they are lost.
+// It is opened by Init and closed by Release
+var exposureSpool *spooler
+
+// SpoolOption The option of WithExposureSpool
+type SpoolOption func(config *internal.ExposureSpoolConfig)
+
+// WithSpoolMaxBytes The size cap of the spool, the oldest segments are evicted beyond it. Default 1GB
+func WithSpoolMaxBytes(maxBytes int64) SpoolOption {
+	return func(config *internal.ExposureSpoolConfig) {
+		config.MaxBytes = maxBytes
+	}
+}
+
+// WithSpoolSegmentBytes The size a segment file is closed at, the eviction removes a whole segment. Default 16MB
+func WithSpoolSegmentBytes(segmentBytes int64) SpoolOption {
+	return func(config *internal.ExposureSpoolConfig) {
+		config.SegmentBytes = segmentBytes
+	}
+}
+
+// WithSpoolBackoff The interval of the replay, it is doubled after each failed replay up to maxBackoff
+// and reset by a successful one. Default 1s and 1m
+func WithSpoolBackoff(minBackoff time.Duration, maxBackoff time.Duration) SpoolOption {
+	return func(config *internal.ExposureSpoolConfig) {
+		config.MinBackoff = minBackoff
+		config.MaxBackoff = maxBackoff
+	}
+}
+
+// WithExposureSpool Append the exposures and the remote config exposures to the spool directory
+// when the metrics plugin fails or panics, a background worker replays them oldest first with backoff.
+// The records are spooled as the plugin received them, after the sampling and the middlewares, and replayed
+// to the plugin directly, so a record dropped by the hash sampling is never delivered by the replay.
+// With metrics.SetRetryPolicy the retries come first, the spool keeps the deliveries whose attempts are used up
+// with a retryable error, the permanent errors go to the dead letter handler.
+// The spool survives restarts, the records left by the previous process are replayed after Init.
+// The segments hold the metadata tokens, so the directory is created with the mode 0700.
+// The spooled, replayed, evicted and dropped records are counted in Stats as abc_spool_records_total
+func WithExposureSpool(dir string, opts ...SpoolOption) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		if dir == "" {
+			return errors.Errorf("spool dir is required")
+		}
+		spoolConfig := &internal.ExposureSpoolConfig{
+			Dir:          dir,
+			MaxBytes:     DefaultSpoolMaxBytes,
+			SegmentBytes: DefaultSpoolSegmentBytes,
+			MinBackoff:   DefaultSpoolMinBackoff,
+			MaxBackoff:   DefaultSpoolMaxBackoff,
+		}
+		for _, opt := range opts {
+			opt(spoolConfig)
+		}
+		if spoolConfig.MaxBytes <= 0 || spoolConfig.SegmentBytes <= 0 {
+			return errors.Errorf("spool sizes should be positive")
+		}
+		if spoolConfig.MinBackoff <= 0 || spoolConfig.MaxBackoff < spoolConfig.MinBackoff {
+			return errors.Errorf("spool backoff should be positive and the max not less than the min")
+		}
+		config.ExposureSpool = spoolConfig
+		return nil
+	}
+}
+
+func initExposureSpool(config *internal.GlobalConfig) error {
+	if config.ExposureSpool == nil {
+		exposureSpool = nil
+		return nil
+	}
+	s, err := spool.Open(config.ExposureSpool.Dir, spool.WithMaxBytes(config.ExposureSpool.MaxBytes),
+		spool.WithSegmentBytes(config.ExposureSpool.SegmentBytes),
+		spool.WithOnEvict(func(records int) { stats.AddSpoolRecords(stats.SpoolEventEvicted, records) }))
+	if err != nil {
+		return errors.Wrap(err, "open spool")
+	}
+	stats.SetSpoolBytes(s.Size())
+	exposureSpool = &spooler{
+		spool:      s,
+		minBackoff: config.ExposureSpool.MinBackoff,
+		maxBackoff: config.ExposureSpool.MaxBackoff,
+		done:       make(chan struct{}),
+	}
+	exposureSpool.wg.Add(1)
+	go exposureSpool.run()
+	metrics.RegisterSpoolHandler(exposureSpool.spoolLetter)
+	return nil
+}
+
+// releaseExposureSpool Stop the replay and close the spool, the records are kept for the next Init
+func releaseExposureSpool() {
+	if exposureSpool == nil {
+		return
+	}
+	metrics.RegisterSpoolHandler(nil)
+	close(exposureSpool.done)
+	exposureSpool.wg.Wait()
+	err := exposureSpool.spool.Close()
+	if err != nil {
+		log.ErrorContext(context.Background(), "close spool fail", log.Err(err))
+	}
+	exposureSpool = nil
+}
+
+// spoolRecord The record of the spool, one failed delivery as sampled and rewritten by the middlewares
+type spoolRecord struct {
+	Metadata *metrics.Metadata `json:"metadata"`
+	// Exposures The protobuf encoded ExposureGroup
+	Exposures []byte     `json:"exposures,omitempty"`
+	Data      [][]string `json:"data,omitempty"`
+}
+
+var errNotSpooled = errors.New("method is not spooled")
+
+// spoolLetter Append the failed delivery to the spool, it is registered as the spool handler of the metrics plugins.
+// The exposures and the rows of SendData are kept, the other methods are left to the dead letter handler
+func (s *spooler) spoolLetter(letter *metrics.DeadLetter) error {
+	record := &spoolRecord{Metadata: letter.Metadata}
+	var err error
+	switch letter.Method {
+	case metrics.MethodLogExposure:
+		record.Exposures, err = proto.Marshal(letter.Exposures)
+	case metrics.MethodSendData:
+		record.Data = letter.Data
+	default:
+		return errNotSpooled
+	}
+	n := letter.Len()
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(record)
+	}
+	if err == nil {
+		err = s.spool.Append(data)
+	}
+	if err != nil {
+		stats.AddSpoolRecords(stats.SpoolEventDropped, n)
+		log.ErrorContext(context.Background(), "spool fail", log.F("plugin", letter.Metadata.MetricsPluginName),
+			log.F("records", n), log.Err(err))
+		return err
+	}
+	stats.AddSpoolRecords(stats.SpoolEventSpooled, n)
+	stats.SetSpoolBytes(s.spool.Size())
+	return nil
+}
+
+// spooler The spool with its replay worker
+type spooler struct {
+	spool      *spool.Spool
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+func (s *spooler) run() {
+	defer s.wg.Done()
+	backoff := s.minBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-timer.C:
+		}
+		_, err := s.spool.Replay(s.replay)
+		stats.SetSpoolBytes(s.spool.Size())
+		if err != nil {
+			log.WarnContext(context.Background(), "replay spool fail", log.F("backoff", backoff), log.Err(err))
+			backoff *= 2
+			if backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		} else {
+			backoff = s.minBackoff
+		}
+		timer.Reset(backoff)
+	}
+}
+
+// replay Deliver the record to the plugin again as it was spooled, a record that cannot be decoded is skipped
+func (s *spooler) replay(data []byte) error {
+	record := &spoolRecord{}
+	err := json.Unmarshal(data, record)
+	if err != nil || record.Metadata == nil {
+		log.ErrorContext(context.Background(), "skip invalid spool record", log.Err(err))
+		return nil
+	}
+	letter := &metrics.DeadLetter{Metadata: record.Metadata,
+		Payload: metrics.Payload{Method: metrics.MethodSendData, Data: record.Data}}
+	if record.Exposures != nil {
+		group := &protoc_event_server.ExposureGroup{}
+		err = proto.Unmarshal(record.Exposures, group)
+		if err != nil {
+			log.ErrorContext(context.Background(), "skip invalid spool record", log.Err(err))
+			return nil
+		}
+		letter.Payload = metrics.Payload{Method: metrics.MethodLogExposure, Exposures: group}
+	}
+	err = metrics.Redeliver(context.Background(), letter)
+	if err == nil {
+		stats.AddSpoolRecords(stats.SpoolEventReplayed, letter.Len())
+	}
+	return err
+}
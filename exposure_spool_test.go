@@ -0,0 +1,134 @@
+package abc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/metrics"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithExposureSpool(t *testing.T) {
+	config := &internal.GlobalConfig{}
+	assert.NotNil(t, WithExposureSpool("")(config))
+	assert.NotNil(t, WithExposureSpool("dir", WithSpoolMaxBytes(0))(config))
+	assert.NotNil(t, WithExposureSpool("dir", WithSpoolBackoff(time.Second, time.Millisecond))(config))
+	assert.Nil(t, config.ExposureSpool)
+	assert.Nil(t, WithExposureSpool("dir", WithSpoolSegmentBytes(1<<20))(config))
+	assert.Equal(t, &internal.ExposureSpoolConfig{Dir: "dir", MaxBytes: DefaultSpoolMaxBytes, SegmentBytes: 1 << 20,
+		MinBackoff: DefaultSpoolMinBackoff, MaxBackoff: DefaultSpoolMaxBackoff}, config.ExposureSpool)
+}
+
+func TestExposureSpool(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+	client := &recordingMetricsClient{name: "spool_test", err: errors.New("unavailable")}
+	metrics.RegisterClient(client)
+	config := &internal.GlobalConfig{}
+	assert.Nil(t, WithExposureSpool(t.TempDir(), WithSpoolBackoff(10*time.Millisecond, 20*time.Millisecond))(config))
+	assert.Nil(t, initExposureSpool(config))
+	defer releaseExposureSpool()
+
+	metadata := &metrics.Metadata{MetricsPluginName: client.Name(), TableName: "t", SamplingInterval: 1}
+	ctx := context.Background()
+	assert.NotNil(t, metrics.LogExposure(ctx, metadata, &protoc_event_server.ExposureGroup{
+		Exposures: []*protoc_event_server.Exposure{{UnitId: "u1"}, {UnitId: "u2"}}}))
+	assert.NotNil(t, metrics.SendData(ctx, metadata, [][]string{{"u3"}}))
+	snapshot := stats.Collect(nil, nil)
+	assert.Equal(t, []stats.Counter{
+		{Labels: map[string]string{stats.LabelEvent: stats.SpoolEventSpooled}, Value: 3},
+	}, snapshot.SpoolRecords)
+	assert.NotZero(t, snapshot.SpoolBytes)
+
+	// replayed in order once the plugin recovers
+	time.Sleep(30 * time.Millisecond)
+	client.setErr(nil)
+	assert.Eventually(t, func() bool {
+		exposures, data := client.calls()
+		return len(exposures) == 1 && len(data) == 1
+	}, time.Second, 5*time.Millisecond)
+	exposures, data := client.calls()
+	assert.Equal(t, "u2", exposures[0].Exposures[1].UnitId)
+	assert.Equal(t, [][]string{{"u3"}}, data[0])
+	assert.Eventually(t, func() bool {
+		return stats.Collect(nil, nil).SpoolBytes == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestExposureSpool_hashSampling(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+	client := &recordingMetricsClient{name: "spool_hash_test", err: errors.New("unavailable")}
+	metrics.RegisterClient(client)
+	config := &internal.GlobalConfig{}
+	assert.Nil(t, WithExposureSpool(t.TempDir(), WithSpoolBackoff(10*time.Millisecond, 20*time.Millisecond))(config))
+	assert.Nil(t, initExposureSpool(config))
+	defer releaseExposureSpool()
+
+	metadata := &metrics.Metadata{MetricsPluginName: client.Name(), TableName: "t", SamplingInterval: 4,
+		SamplingMode: metrics.SamplingModeHash, SamplingSalt: "salt"}
+	var exposures []*protoc_event_server.Exposure
+	var sampledIn []string
+	for i := 0; i < 100; i++ {
+		unitID := fmt.Sprintf("u%d", i)
+		exposures = append(exposures, &protoc_event_server.Exposure{UnitId: unitID})
+		if metrics.HashSamplingResult(unitID, metadata.SamplingSalt, metadata.SamplingInterval) {
+			sampledIn = append(sampledIn, unitID)
+		}
+	}
+	assert.NotEmpty(t, sampledIn)
+	assert.NotNil(t, metrics.LogExposure(context.Background(), metadata,
+		&protoc_event_server.ExposureGroup{Exposures: exposures}))
+	assert.Equal(t, []stats.Counter{
+		{Labels: map[string]string{stats.LabelEvent: stats.SpoolEventSpooled}, Value: uint64(len(sampledIn))},
+	}, stats.Collect(nil, nil).SpoolRecords)
+
+	// the replay delivers the sampled-in units only
+	client.setErr(nil)
+	assert.Eventually(t, func() bool {
+		groups, _ := client.calls()
+		return len(groups) == 1
+	}, time.Second, 5*time.Millisecond)
+	groups, _ := client.calls()
+	var replayed []string
+	for _, exposure := range groups[0].Exposures {
+		replayed = append(replayed, exposure.UnitId)
+	}
+	assert.Equal(t, sampledIn, replayed)
+}
+
+func TestExposureSpool_retryPolicy(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+	client := &recordingMetricsClient{name: "spool_retry_test", err: errors.New("unavailable")}
+	metrics.RegisterClient(client)
+	assert.Nil(t, metrics.SetRetryPolicy(&metrics.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}))
+	defer func() { _ = metrics.SetRetryPolicy(nil) }()
+	config := &internal.GlobalConfig{}
+	assert.Nil(t, WithExposureSpool(t.TempDir(), WithSpoolBackoff(10*time.Millisecond, 20*time.Millisecond))(config))
+	assert.Nil(t, initExposureSpool(config))
+	defer releaseExposureSpool()
+
+	// the retry is scheduled, the caller sees no error
+	metadata := &metrics.Metadata{MetricsPluginName: client.Name(), TableName: "t", SamplingInterval: 1}
+	assert.Nil(t, metrics.LogExposure(context.Background(), metadata, &protoc_event_server.ExposureGroup{
+		Exposures: []*protoc_event_server.Exposure{{UnitId: "u1"}}}))
+	// the attempts are used up, the exposure is spooled and replayed once the plugin recovers
+	assert.Eventually(t, func() bool {
+		return len(stats.Collect(nil, nil).SpoolRecords) != 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []stats.Counter{
+		{Labels: map[string]string{stats.LabelEvent: stats.SpoolEventSpooled}, Value: 1},
+	}, stats.Collect(nil, nil).SpoolRecords)
+	client.setErr(nil)
+	assert.Eventually(t, func() bool {
+		exposures, _ := client.calls()
+		return len(exposures) == 1
+	}, time.Second, 5*time.Millisecond)
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/plugin/metrics"
 	"github.com/abetterchoice/go-sdk/testdata"
 	"github.com/abetterchoice/protoc_cache_server"
 	"github.com/stretchr/testify/assert"
@@ -283,6 +286,41 @@ func TestExposureRemoteConfig(t *testing.T) {
 	}
 }
 
+func Test_experimentReasonList(t *testing.T) {
+	tests := []struct {
+		name string
+		list *ExperimentList
+		want string
+	}{
+		{
+			name: "nil",
+			list: nil,
+			want: "",
+		},
+		{
+			name: "normal",
+			list: &ExperimentList{
+				Data: map[string]*Group{
+					"layerB": {LayerKey: "layerB", Reason: env.ReasonLayerDefault},
+					"layerA": {LayerKey: "layerA", Reason: env.ReasonSplit},
+					"layerC": {LayerKey: "layerC"},
+				},
+			},
+			want: "layerA:SPLIT;layerB:LAYER_DEFAULT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, experimentReasonList(tt.list))
+			if tt.want == "" {
+				assert.Nil(t, reasonExtInfo(experimentReasonList(tt.list)))
+				return
+			}
+			assert.Equal(t, map[string]string{reasonKey: tt.want}, reasonExtInfo(experimentReasonList(tt.list)))
+		})
+	}
+}
+
 func BenchmarkInt64Join(b *testing.B) {
 	var source = []int64{1, 2, 3, 4, 4, 5, 6, 3, 3, 4, 5, 5, 523424, 23, 4}
 	b.ResetTimer()
@@ -292,3 +330,19 @@ func BenchmarkInt64Join(b *testing.B) {
 		}
 	})
 }
+
+func Test_newMetricsMetadata(t *testing.T) {
+	defer func() { internal.C.SamplingMode = "" }()
+	config := &protoc_cache_server.MetricsConfig{PluginName: "p", SamplingInterval: 10,
+		Metadata: &protoc_cache_server.MetricsMetadata{Name: "t", Id: "1", Token: "token"}}
+	assert.Equal(t, &metrics.Metadata{MetricsPluginName: "p", TableName: "t", TableID: "1", Token: "token",
+		SamplingInterval: 10, SamplingSalt: "1"}, newMetricsMetadata(config))
+	assert.Nil(t, WithSamplingMode(metrics.SamplingModeHash)(internal.C))
+	assert.NotNil(t, WithSamplingMode("unknown")(internal.C))
+	assert.Equal(t, metrics.SamplingModeHash, newMetricsMetadata(config).SamplingMode)
+	// selected by the expanded data of the table
+	config.Metadata.ExpandedData = map[string]string{SamplingModeKey: "random", SamplingSaltKey: "salt"}
+	metadata := newMetricsMetadata(config)
+	assert.Equal(t, metrics.SamplingModeRandom, metadata.SamplingMode)
+	assert.Equal(t, "salt", metadata.SamplingSalt)
+}
@@ -0,0 +1,91 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/experiment"
+	"github.com/pkg/errors"
+)
+
+// fallbackReason Map the evaluation error to the reason of the fallback result
+func fallbackReason(err error) env.Reason {
+	switch {
+	case errors.Is(err, env.ErrProjectNotFound):
+		return env.ReasonNotReady
+	case errors.Is(err, env.ErrRemoteConfigNotFound), errors.Is(err, env.ErrLayerNotFound):
+		return env.ReasonFallback
+	default:
+		return env.ReasonError
+	}
+}
+
+// fallbackConfigResult Build the remote configuration result carrying the caller-supplied fallback value
+func (c *userContext) fallbackConfigResult(key string, options *experiment.Options, reason env.Reason) *ConfigResult {
+	return &ConfigResult{
+		userCtx: c,
+		Config: &Config{
+			Key:       key,
+			Value:     &Value{data: options.FallbackValue},
+			IsDefault: true,
+			Reason:    reason,
+		},
+	}
+}
+
+// fallbackExperimentList Build a default group carrying the caller-supplied fallback params for each requested layer
+func (c *userContext) fallbackExperimentList(options *experiment.Options, reason env.Reason) *ExperimentList {
+	result := &ExperimentList{
+		userCtx: c,
+		Data:    make(map[string]*Group, len(options.LayerKeys)),
+	}
+	for layerKey := range options.LayerKeys {
+		params := make(map[string]string, len(options.FallbackParams)) // each group owns its params
+		for key, value := range options.FallbackParams {
+			params[key] = value
+		}
+		result.Data[layerKey] = &Group{
+			LayerKey:  layerKey,
+			IsDefault: true,
+			params:    params,
+			Reason:    reason,
+		}
+	}
+	return result
+}
+
+// fallbackBytes Convert the caller-supplied fallback value into the raw bytes of the remote configuration
+func fallbackBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		var result = make([]byte, len(v))
+		copy(result, v)
+		return result, nil
+	case string:
+		return []byte(v), nil
+	case bool:
+		return []byte(strconv.FormatBool(v)), nil
+	case int:
+		return []byte(strconv.FormatInt(int64(v), 10)), nil
+	case int32:
+		return []byte(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case uint32:
+		return []byte(strconv.FormatUint(uint64(v), 10)), nil
+	case uint64:
+		return []byte(strconv.FormatUint(v, 10)), nil
+	case float32:
+		return []byte(strconv.FormatFloat(float64(v), 'f', -1, 32)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/testdata"
 	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
 	"github.com/stretchr/testify/assert"
@@ -66,6 +67,7 @@ func Test_userContext_GetFeatureFlag(t *testing.T) {
 				Config: &Config{
 					Key:          "remoteConfig1",
 					Value:        &Value{data: []byte("remoteConfig1-condition1")},
+					Reason:       env.ReasonSplit,
 					remoteConfig: testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["remoteConfig1"],
 					unitIDType:   protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 				},
@@ -18,6 +18,8 @@ type ExperimentList struct {
 	// The experimental group hit by unitID in each layer, the key is layerKey,
 	// and the value is the experimental group hit under the layer.
 	Data map[string]*Group
+	// The version of the local cache the groups are evaluated from, empty for the caller-supplied fallback params
+	Version string
 }
 
 // ExperimentResult Experimental offloading results,
@@ -63,7 +65,15 @@ type Group struct {
 	sceneIDList []int64
 
 	// Account system
-	UnitIDType  protoc_cache_server.UnitIDType `json:"unitIdType"`
+	UnitIDType protoc_cache_server.UnitIDType `json:"unitIdType"`
+
+	// The reason why this group is returned, such as split, targeting match, layer default
+	// or the caller-supplied fallback params are used
+	Reason env.Reason `json:"reason,omitempty"`
+
+	// The version of the local cache the group is evaluated from, empty for the caller-supplied fallback params
+	Version string `json:"version,omitempty"`
+
 	holdoutData map[string]*Group
 }
 
@@ -13,8 +13,10 @@ import (
 	"github.com/RoaringBitmap/roaring"
 	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal/client"
+	"github.com/abetterchoice/go-sdk/internal/stats"
 	"github.com/abetterchoice/go-sdk/plugin/log"
 	metrics2 "github.com/abetterchoice/go-sdk/plugin/metrics"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
 	protoctabcacheserver "github.com/abetterchoice/protoc_cache_server"
 	"github.com/abetterchoice/protoc_event_server"
 	"github.com/pkg/errors"
@@ -73,6 +75,22 @@ const (
 
 var localApplicationCache sync.Map
 
+// RefreshStatus The result of the local cache refreshes of a project
+type RefreshStatus struct {
+	LastRefreshTime     time.Time `json:"lastRefreshTime"`  // start time of the last refresh
+	LastSuccessTime     time.Time `json:"lastSuccessTime"`  // start time of the last successful refresh
+	LastModifiedTime    time.Time `json:"lastModifiedTime"` // start time of the last refresh that updated the data
+	LastError           string    `json:"lastError"`        // error of the last refresh, empty if it succeeded
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	// Findings The findings of the validation of the last refreshed version, see ValidateApplication
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+var (
+	refreshStatusLock  sync.Mutex
+	refreshStatusIndex = map[string]RefreshStatus{}
+)
+
 // InitLocalCache Initialize the local cache, and start an independent asynchronous refresh coroutine for
 // each projectID, and regularly pull the latest data from the remote background cache service to the local
 // Can be initialized multiple times, concurrent and safe
@@ -110,18 +128,19 @@ func asyncRefreshLocalCache(projectIDList []string) {
 
 // continuousFetch Infinite loop refresh local cache
 func continuousFetch(projectID string) {
+	ctx := log.WithFields(context.Background(), log.F(log.FieldProjectID, projectID))
 	for {
 		application := GetApplication(projectID)
 		if application == nil { // The local cache does not exist, exit the refresh coroutine
-			log.Warnf("stop refresh %v", projectID)
+			log.WarnContext(ctx, "stop refresh")
 			return
 		}
-		log.Debugf("[projectID=%v] alive", projectID)
+		log.DebugContext(ctx, "alive")
 		start := time.Now()
-		_, err := NewAndSetApplication(context.Background(), projectID)
+		_, err := NewAndSetApplication(ctx, projectID)
 		latency := time.Since(start)
 		if err != nil {
-			log.Errorf("[projectID=%v,latency=%s]newApplication fail:%v", projectID, latency.String(), err)
+			log.ErrorContext(ctx, "newApplication fail", log.F(log.FieldLatency, latency), log.Err(err))
 		}
 		manualFetchEvent(projectID, latency, err)
 		time.Sleep(time.Duration(refreshInterval(projectID)) * time.Second)
@@ -162,7 +181,8 @@ func manualFetchEvent(projectID string, latency time.Duration, err error) {
 		},
 	}})
 	if sendDataErr != nil {
-		log.Errorf("logMonitorEvent fail:%v", sendDataErr)
+		log.ErrorContext(context.Background(), "logMonitorEvent fail", log.F(log.FieldProjectID, projectID),
+			log.Err(sendDataErr))
 	}
 }
 
@@ -179,31 +199,54 @@ func refreshInterval(projectID string) uint32 {
 	return controlData.RefreshInterval
 }
 
+// LogFields The projectID and the version of the application followed by the fields, for the structured logs
+func (a *Application) LogFields(fields ...log.Field) []log.Field {
+	if a == nil {
+		return fields
+	}
+	return append([]log.Field{log.F(log.FieldProjectID, a.ProjectID), log.F(log.FieldVersion, a.Version)}, fields...)
+}
+
 // NewAndSetApplication builds a new application, obtains the latest data from the background cache service,
 // and automatically updates the local cache after the data is obtained normally
 // If the returned application is not empty, it can ensure that ExperimentData, ConfigData,
 // and ControlData under TabConfig are not nil,
 // Avoid multiple empty judgments in the place where it is used
 func NewAndSetApplication(ctx context.Context, projectID string) (application *Application, err error) {
+	var modified = true
+	var startTime = time.Now()
+	ctx = log.WithFields(ctx, log.F(log.FieldProjectID, projectID))
+	ctx, span := trace.Start(ctx, trace.SpanRefresh, trace.String(trace.AttributeProjectID, projectID),
+		trace.String(trace.AttributeSDKVersion, env.SDKVersion))
+	defer func() {
+		stats.ObserveRefresh(projectID, time.Since(startTime), err)
+		recordRefreshStatus(projectID, startTime, modified, err)
+		if application != nil {
+			span.SetAttributes(trace.String(trace.AttributeVersion, application.Version),
+				trace.Bool(trace.AttributeIsModified, modified))
+		}
+		span.RecordError(err)
+		span.End()
+	}()
 	defer func() {
 		recoverErr := recover()
 		if recoverErr != nil {
 			body := make([]byte, 1<<10)
 			runtime.Stack(body, false)
-			log.Errorf("recoverErr:%v\n%s", recoverErr, body)
+			log.ErrorContext(ctx, "recover", log.F("recoverErr", recoverErr), log.F("stack", string(body)))
 			err = fmt.Errorf("recoverErr:%v\n%s", recoverErr, body)
 			return
 		}
 	}()
-	var modified = true
 	application, modified, err = refreshApplication(ctx, projectID)
 	if err != nil {
 		return nil, errors.Wrap(err, "refreshApplication")
 	}
 	if modified { // The local cache needs to be updated only when data changes
-		log.Infof("[projectID=%v] version=%v", application.ProjectID, application.Version)
-		setApplication(application)
+		log.InfoContext(ctx, "local cache updated", log.F(log.FieldVersion, application.Version))
+		keepApplication(application)
 	}
+	selectVersion(ctx, projectID) // the pinned version or the newest version passing the check is served
 	return application, nil
 }
 
@@ -245,6 +288,15 @@ func refreshApplication(ctx context.Context, projectID string) (*Application, bo
 	}
 	setupMetricsInitConfigIndex(application)
 	setupVariantKeyLayerKeyMap(application)
+	findings := ValidateApplication(application)
+	recordFindings(projectID, findings)
+	for _, finding := range findings {
+		log.WarnContext(ctx, "invalid tabConfig", application.LogFields(log.F("finding", finding.String()))...)
+	}
+	err = checkFindings(application, findings)
+	if err != nil { // the served version is kept
+		return nil, false, err
+	}
 	return application, true, nil
 }
 
@@ -558,19 +610,28 @@ func setupExperimentBucketInfo(ctx context.Context, application *Application) er
 	if experimentBucketInfo.Code != protoctabcacheserver.Code_CODE_SUCCESS {
 		return errors.Errorf("invalid code:%v, message=%s", experimentBucketInfo.Code, experimentBucketInfo.Message)
 	}
+	var copied bool // the indexes are shared with the current version until the first change
 	for experimentID, bucketInfo := range experimentBucketInfo.BucketIndex {
-		if bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_DELETE ||
-			bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_UNKNOWN {
-			delete(application.ExperimentIDBucketInfoIndex, experimentID) // this is safe
-			delete(application.ExperimentIDRoaringBitmapIndex, experimentID)
+		curBucketInfo, ok := application.ExperimentIDBucketInfoIndex[experimentID]
+		isDelete := bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_DELETE ||
+			bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_UNKNOWN
+		if (isDelete && !ok) || (!isDelete && ok && isSameBucketInfo(curBucketInfo, bucketInfo)) {
+			continue // the bucket info and the bitmap of the current version are shared
+		}
+		if !copied {
+			copyExperimentBucketIndex(application)
+			copied = true
+		}
+		delete(application.ExperimentIDRoaringBitmapIndex, experimentID)
+		if isDelete {
+			delete(application.ExperimentIDBucketInfoIndex, experimentID)
 			continue
 		}
 		application.ExperimentIDBucketInfoIndex[experimentID] = bucketInfo
 		if bucketInfo.BucketType != protoctabcacheserver.BucketType_BUCKET_TYPE_BITMAP {
 			continue
 		}
-		bitmap := roaring.New()
-		_, err = bitmap.FromBuffer(bucketInfo.Bitmap)
+		bitmap, err := loadBitmap(bucketInfo.Bitmap)
 		if err != nil {
 			return errors.Wrapf(err, "[experimentID=%d]new bitmap fromBuffer", experimentID)
 		}
@@ -598,19 +659,28 @@ func setupGroupBucketInfo(ctx context.Context, application *Application) error {
 	if groupBucketInfo.Code != protoctabcacheserver.Code_CODE_SUCCESS {
 		return errors.Errorf("invalid code:%v, message=%s", groupBucketInfo.Code, groupBucketInfo.Message)
 	}
+	var copied bool // the indexes are shared with the current version until the first change
 	for groupID, bucketInfo := range groupBucketInfo.BucketIndex {
-		if bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_DELETE ||
-			bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_UNKNOWN {
-			delete(application.GroupIDBucketInfoIndex, groupID) // this is safe
-			delete(application.GroupIDRoaringBitmapIndex, groupID)
+		curBucketInfo, ok := application.GroupIDBucketInfoIndex[groupID]
+		isDelete := bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_DELETE ||
+			bucketInfo.ModifyType == protoctabcacheserver.ModifyType_MODIFY_UNKNOWN
+		if (isDelete && !ok) || (!isDelete && ok && isSameBucketInfo(curBucketInfo, bucketInfo)) {
+			continue // the bucket info and the bitmap of the current version are shared
+		}
+		if !copied {
+			copyGroupBucketIndex(application)
+			copied = true
+		}
+		delete(application.GroupIDRoaringBitmapIndex, groupID)
+		if isDelete {
+			delete(application.GroupIDBucketInfoIndex, groupID)
 			continue
 		}
 		application.GroupIDBucketInfoIndex[groupID] = bucketInfo
 		if bucketInfo.BucketType != protoctabcacheserver.BucketType_BUCKET_TYPE_BITMAP {
 			continue
 		}
-		bitmap := roaring.New()
-		_, err = bitmap.FromBuffer(bucketInfo.Bitmap)
+		bitmap, err := loadBitmap(bucketInfo.Bitmap)
 		if err != nil {
 			return errors.Wrapf(err, "[groupID=%d]new bitmap fromBuffer", groupID)
 		}
@@ -619,6 +689,43 @@ func setupGroupBucketInfo(ctx context.Context, application *Application) error {
 	return nil
 }
 
+// isSameBucketInfo Whether the refreshed bucket info is the same version as the current one,
+// so that the current bucket info and its bitmap are kept
+func isSameBucketInfo(cur *protoctabcacheserver.BucketInfo, refreshed *protoctabcacheserver.BucketInfo) bool {
+	return cur != nil && cur.Version != "" && cur.Version == refreshed.Version && cur.BucketType == refreshed.BucketType
+}
+
+// loadBitmap Build the bitmap of the buffer of a bucket info without a copy. The bitmap is run-optimized,
+// if it gets smaller it is built on the compacted bytes owned by the bitmap index. The buffer is not changed,
+// the bucket info is served as is to the dumps and the snapshots
+func loadBitmap(buffer []byte) (*roaring.Bitmap, error) {
+	bitmap := roaring.New()
+	_, err := bitmap.FromBuffer(buffer)
+	if err != nil {
+		return nil, err
+	}
+	bitmap.RunOptimize()
+	if bitmap.GetSerializedSizeInBytes() >= uint64(len(buffer)) {
+		bitmap = roaring.New() // the optimized containers are dropped, the buffer is smaller
+		_, err = bitmap.FromBuffer(buffer)
+		return bitmap, err
+	}
+	compacted, err := bitmap.ToBytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "toBytes")
+	}
+	bitmap = roaring.New()
+	_, err = bitmap.FromBuffer(compacted)
+	return bitmap, err
+}
+
+// isBuiltOnBuffer Whether the bitmap loaded by loadBitmap is built on the buffer of the bucket info,
+// not on the compacted bytes
+func isBuiltOnBuffer(bitmap *roaring.Bitmap, bucketInfo *protoctabcacheserver.BucketInfo) bool {
+	return bucketInfo != nil && bucketInfo.BucketType == protoctabcacheserver.BucketType_BUCKET_TYPE_BITMAP &&
+		bitmap.GetSerializedSizeInBytes() == uint64(len(bucketInfo.Bitmap))
+}
+
 func setupTabConfig(ctx context.Context, application *Application) error {
 	tabConfigData, err := client.CacheClient.GetTabConfigData(ctx, &protoctabcacheserver.GetTabConfigReq{
 		ProjectId:  application.ProjectID,
@@ -638,6 +745,8 @@ func setupTabConfig(ctx context.Context, application *Application) error {
 	}
 	if tabConfigData.Code == protoctabcacheserver.Code_CODE_SUCCESS {
 		if !validateTabConfig(tabConfigData) {
+			log.ErrorContext(ctx, "invalid tabConfig, experimentData, configData and controlData are required",
+				log.F("message", tabConfigData.Message))
 			return errors.Errorf("invalid tabConfig")
 		}
 		application.retryTime = 0
@@ -655,12 +764,10 @@ func setupTabConfig(ctx context.Context, application *Application) error {
 func validateTabConfig(tabConfigData *protoctabcacheserver.GetTabConfigResp) bool {
 	if tabConfigData.TabConfigManager == nil ||
 		tabConfigData.TabConfigManager.TabConfig == nil {
-		log.Errorf("invalid tabConfig111:%+v", tabConfigData)
 		return false
 	}
 	tabConfig := tabConfigData.TabConfigManager.TabConfig
 	if tabConfig.ExperimentData == nil || tabConfig.ConfigData == nil || tabConfig.ControlData == nil {
-		log.Errorf("invalid data111")
 		return false
 	}
 	return true
@@ -742,7 +849,7 @@ func genGroupVersionIndex(application *Application) map[int64]string {
 }
 
 func getLocalCacheWithDefault(projectID string) *Application {
-	curApplication := GetApplication(projectID)
+	curApplication := liveApplication(projectID) // the served version may be pinned or rolled back
 	if curApplication == nil {
 		return &Application{
 			ProjectID:                      projectID,
@@ -761,20 +868,20 @@ func getLocalCacheWithDefault(projectID string) *Application {
 	return getNewApplication(curApplication)
 }
 
-// getNewApplication gets a concurrently safe application, regenerates a new map object,
-// but retains the original pointer of value
+// getNewApplication gets a concurrently safe application that shares the indexes of the current one.
 // Here you can directly use json marshal unmarshal to make a deep copy of the data to ensure concurrent safety,
 // but the data volume is relatively large and the deep copy performance is poor
-// So here we make a shallow copy for the fields with concurrency issues
+// So the indexes with concurrency issues are copied by copyExperimentBucketIndex and copyGroupBucketIndex
+// before the first change, the unchanged bucket infos and bitmaps are shared between the versions
 func getNewApplication(curApplication *Application) *Application {
 	return &Application{
 		ProjectID:                      curApplication.ProjectID,
 		Version:                        curApplication.Version,
 		TabConfig:                      curApplication.TabConfig,
-		ExperimentIDBucketInfoIndex:    getNewBucketInfoIndex(curApplication.ExperimentIDBucketInfoIndex),
-		ExperimentIDRoaringBitmapIndex: getNewRoaringBitmapIndex(curApplication.ExperimentIDRoaringBitmapIndex),
-		GroupIDBucketInfoIndex:         getNewBucketInfoIndex(curApplication.GroupIDBucketInfoIndex),
-		GroupIDRoaringBitmapIndex:      getNewRoaringBitmapIndex(curApplication.GroupIDRoaringBitmapIndex),
+		ExperimentIDBucketInfoIndex:    curApplication.ExperimentIDBucketInfoIndex,
+		ExperimentIDRoaringBitmapIndex: curApplication.ExperimentIDRoaringBitmapIndex,
+		GroupIDBucketInfoIndex:         curApplication.GroupIDBucketInfoIndex,
+		GroupIDRoaringBitmapIndex:      curApplication.GroupIDRoaringBitmapIndex,
 		FullFlowLayerIndex:             curApplication.FullFlowLayerIndex,
 		LayerIndex:                     curApplication.LayerIndex,
 		DMPTagInfo:                     curApplication.DMPTagInfo,
@@ -785,6 +892,18 @@ func getNewApplication(curApplication *Application) *Application {
 	}
 }
 
+// copyExperimentBucketIndex Copy the experiment indexes shared with the current version before changing them
+func copyExperimentBucketIndex(application *Application) {
+	application.ExperimentIDBucketInfoIndex = getNewBucketInfoIndex(application.ExperimentIDBucketInfoIndex)
+	application.ExperimentIDRoaringBitmapIndex = getNewRoaringBitmapIndex(application.ExperimentIDRoaringBitmapIndex)
+}
+
+// copyGroupBucketIndex Copy the group indexes shared with the current version before changing them
+func copyGroupBucketIndex(application *Application) {
+	application.GroupIDBucketInfoIndex = getNewBucketInfoIndex(application.GroupIDBucketInfoIndex)
+	application.GroupIDRoaringBitmapIndex = getNewRoaringBitmapIndex(application.GroupIDRoaringBitmapIndex)
+}
+
 func getNewBucketInfoIndex(
 	curIndex map[int64]*protoctabcacheserver.BucketInfo) map[int64]*protoctabcacheserver.BucketInfo {
 	var result = make(map[int64]*protoctabcacheserver.BucketInfo, len(curIndex))
@@ -822,7 +941,59 @@ func setApplication(application *Application) {
 	localApplicationCache.Store(application.ProjectID, application)
 }
 
+// ProjectIDList The projectIDs in the local cache, sorted
+func ProjectIDList() []string {
+	var result []string
+	localApplicationCache.Range(func(key, value interface{}) bool {
+		result = append(result, key.(string))
+		return true
+	})
+	sort.Strings(result)
+	return result
+}
+
+// GetRefreshStatus The refresh result of projectID, false if it has never been refreshed
+func GetRefreshStatus(projectID string) (RefreshStatus, bool) {
+	refreshStatusLock.Lock()
+	defer refreshStatusLock.Unlock()
+	status, ok := refreshStatusIndex[projectID]
+	return status, ok
+}
+
+func recordRefreshStatus(projectID string, startTime time.Time, modified bool, err error) {
+	refreshStatusLock.Lock()
+	defer refreshStatusLock.Unlock()
+	status := refreshStatusIndex[projectID]
+	status.LastRefreshTime = startTime
+	if err != nil {
+		status.LastError = err.Error()
+		status.ConsecutiveFailures++
+	} else {
+		status.LastError = ""
+		status.ConsecutiveFailures = 0
+		status.LastSuccessTime = startTime
+		if modified {
+			status.LastModifiedTime = startTime
+		}
+	}
+	refreshStatusIndex[projectID] = status
+}
+
+// recordFindings Record the findings of the validation of the refreshed version
+func recordFindings(projectID string, findings []Finding) {
+	refreshStatusLock.Lock()
+	defer refreshStatusLock.Unlock()
+	status := refreshStatusIndex[projectID]
+	status.Findings = findings
+	refreshStatusIndex[projectID] = status
+}
+
 // Release TODO
 func Release() {
 	localApplicationCache = sync.Map{}
+	releaseHistory()
+	SetLenientValidation(false)
+	refreshStatusLock.Lock()
+	refreshStatusIndex = map[string]RefreshStatus{}
+	refreshStatusLock.Unlock()
 }
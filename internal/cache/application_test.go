@@ -3,7 +3,9 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -873,3 +875,49 @@ func Test_validateTabConfig(t *testing.T) {
 		})
 	}
 }
+
+func Test_recordRefreshStatus(t *testing.T) {
+	defer Release()
+	statusProjectID := "recordRefreshStatus" // not refreshed by the fetch goroutines of other tests
+	if _, ok := GetRefreshStatus(statusProjectID); ok {
+		t.Errorf("GetRefreshStatus() ok = true, want false")
+	}
+	first := time.Unix(100, 0)
+	second := time.Unix(200, 0)
+	third := time.Unix(300, 0)
+	recordRefreshStatus(statusProjectID, first, true, nil)
+	recordRefreshStatus(statusProjectID, second, false, errors.New("timeout"))
+	status, _ := GetRefreshStatus(statusProjectID)
+	want := RefreshStatus{LastRefreshTime: second, LastSuccessTime: first, LastModifiedTime: first,
+		LastError: "timeout", ConsecutiveFailures: 1}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("GetRefreshStatus() = %+v, want %+v", status, want)
+	}
+	recordRefreshStatus(statusProjectID, third, false, nil)
+	status, _ = GetRefreshStatus(statusProjectID)
+	want = RefreshStatus{LastRefreshTime: third, LastSuccessTime: third, LastModifiedTime: first}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("GetRefreshStatus() = %+v, want %+v", status, want)
+	}
+	Release()
+	if _, ok := GetRefreshStatus(statusProjectID); ok {
+		t.Errorf("GetRefreshStatus() after Release ok = true, want false")
+	}
+}
+
+func TestProjectIDList(t *testing.T) {
+	defer Release()
+	setApplication(&Application{ProjectID: "b"})
+	setApplication(&Application{ProjectID: "a"})
+	got := ProjectIDList()
+	if !sort.StringsAreSorted(got) {
+		t.Errorf("ProjectIDList() = %v, want sorted", got)
+	}
+	var index = make(map[string]bool)
+	for _, id := range got {
+		index[id] = true
+	}
+	if !index["a"] || !index["b"] {
+		t.Errorf("ProjectIDList() = %v, want a and b", got)
+	}
+}
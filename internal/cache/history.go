@@ -0,0 +1,265 @@
+// Package cache Local cache implementation
+package cache
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/pkg/errors"
+)
+
+// VersionCheck The check of a version of the local cache data of the project, it is called before the version is
+// served and at each refresh while it is served. The version failing the check is rolled back to the newest kept
+// version passing the check, and is not served again until a newer version is refreshed
+type VersionCheck func(projectID string, version string) error
+
+// DefaultVersionHistory The versions kept per project by default, the served one included
+const DefaultVersionHistory = 1
+
+// VersionStatus The versions of the local cache data of a project
+type VersionStatus struct {
+	Served   string   `json:"served"`             // the version the evaluations are served from
+	Live     string   `json:"live"`               // the latest refreshed version
+	Kept     []string `json:"kept"`               // the kept versions passing the check, the oldest first
+	Pinned   string   `json:"pinned,omitempty"`   // the version pinned by PinVersion
+	Rejected string   `json:"rejected,omitempty"` // the latest refreshed version if it fails the check
+}
+
+// versionHistory The kept versions of a project
+type versionHistory struct {
+	live     *Application   // the latest refreshed version, the base of the next refresh
+	versions []*Application // the kept versions passing the check, the oldest first
+	rejected bool           // whether live fails the check
+	pinned   string
+}
+
+var (
+	historyLock  sync.Mutex
+	historySize  = DefaultVersionHistory
+	versionCheck VersionCheck
+	historyIndex = map[string]*versionHistory{}
+)
+
+// SetVersionHistory Keep the size latest versions per project, the served one included, and check the versions
+// by check before and while they are served, nil means no check
+func SetVersionHistory(size int, check VersionCheck) {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	if size < 1 {
+		size = DefaultVersionHistory
+	}
+	historySize = size
+	versionCheck = check
+}
+
+// PinVersion Serve the kept version of the project until Unpin, even if newer versions are refreshed.
+// The pinned version is not checked
+func PinVersion(projectID string, version string) error {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	h, ok := historyIndex[projectID]
+	if !ok {
+		return errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
+	}
+	application := h.find(version)
+	if application == nil {
+		return errors.Wrapf(env.ErrVersionNotKept, "version [%s] of projectID [%s]", version, projectID)
+	}
+	h.pinned = version
+	setApplication(application)
+	return nil
+}
+
+// Unpin Serve the newest version passing the check again
+func Unpin(projectID string) error {
+	historyLock.Lock()
+	h, ok := historyIndex[projectID]
+	if ok {
+		h.pinned = ""
+	}
+	historyLock.Unlock()
+	if !ok {
+		return errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
+	}
+	selectVersion(log.WithFields(context.Background(), log.F(log.FieldProjectID, projectID)), projectID)
+	return nil
+}
+
+// GetVersionStatus The versions of the local cache data of the project, false if it is not loaded
+func GetVersionStatus(projectID string) (VersionStatus, bool) {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	h, ok := historyIndex[projectID]
+	if !ok {
+		return VersionStatus{}, false
+	}
+	status := VersionStatus{Pinned: h.pinned}
+	if served := GetApplication(projectID); served != nil {
+		status.Served = served.Version
+	}
+	if h.live != nil {
+		status.Live = h.live.Version
+		if h.rejected {
+			status.Rejected = h.live.Version
+		}
+	}
+	for _, application := range h.versions {
+		status.Kept = append(status.Kept, application.Version)
+	}
+	return status, true
+}
+
+// liveApplication The latest refreshed application of the project, the served one if there is no history
+func liveApplication(projectID string) *Application {
+	historyLock.Lock()
+	h, ok := historyIndex[projectID]
+	historyLock.Unlock()
+	if ok && h.live != nil {
+		return h.live
+	}
+	return GetApplication(projectID)
+}
+
+// keepApplication Record the refreshed application as the live version, it is served by selectVersion
+func keepApplication(application *Application) {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	h, ok := historyIndex[application.ProjectID]
+	if !ok {
+		h = &versionHistory{}
+		historyIndex[application.ProjectID] = h
+	}
+	if h.live == nil || h.live.Version != application.Version {
+		h.rejected = false
+	}
+	h.live = application
+}
+
+// selectVersion Serve the pinned version, otherwise the newest version passing the check.
+// The version failing the check is rolled back, it is called after each refresh
+func selectVersion(ctx context.Context, projectID string) {
+	for {
+		historyLock.Lock()
+		h, ok := historyIndex[projectID]
+		check := versionCheck
+		if !ok {
+			historyLock.Unlock()
+			return
+		}
+		if h.pinned != "" {
+			if application := h.find(h.pinned); application != nil {
+				setApplication(application)
+			}
+			historyLock.Unlock()
+			return
+		}
+		candidate := h.candidate()
+		historyLock.Unlock()
+		if candidate == nil {
+			return
+		}
+		var err error
+		if check != nil {
+			err = callVersionCheck(check, projectID, candidate.Version)
+		}
+		historyLock.Lock()
+		if err == nil || (GetApplication(projectID) == nil && len(h.versions) == 0) {
+			if err != nil { // nothing to roll back to, the first version is served anyway
+				log.ErrorContext(ctx, "version check fail, no version to roll back to",
+					candidate.LogFields(log.Err(err))...)
+			}
+			h.keep(candidate)
+			if h.pinned == "" {
+				setApplication(candidate)
+			}
+			historyLock.Unlock()
+			return
+		}
+		h.reject(candidate)
+		historyLock.Unlock()
+		stats.IncVersionRollback(projectID)
+		log.WarnContext(ctx, "version check fail, roll back", candidate.LogFields(log.Err(err))...)
+	}
+}
+
+// candidate The live version if it is not rejected, otherwise the newest kept version
+func (h *versionHistory) candidate() *Application {
+	if h.live != nil && !h.rejected {
+		return h.live
+	}
+	if len(h.versions) == 0 {
+		return nil
+	}
+	return h.versions[len(h.versions)-1]
+}
+
+// find The kept or live application of the version
+func (h *versionHistory) find(version string) *Application {
+	for i := len(h.versions) - 1; i >= 0; i-- {
+		if h.versions[i].Version == version {
+			return h.versions[i]
+		}
+	}
+	if h.live != nil && h.live.Version == version && !h.rejected {
+		return h.live
+	}
+	return nil
+}
+
+// keep Keep the application passing the check, the oldest versions beyond the history size are dropped
+// except the pinned one
+func (h *versionHistory) keep(application *Application) {
+	for i, kept := range h.versions {
+		if kept.Version == application.Version {
+			h.versions = append(h.versions[:i], h.versions[i+1:]...)
+			break
+		}
+	}
+	h.versions = append(h.versions, application)
+	for i := 0; len(h.versions) > historySize && i < len(h.versions); {
+		if h.versions[i].Version == h.pinned {
+			i++
+			continue
+		}
+		h.versions = append(h.versions[:i], h.versions[i+1:]...)
+	}
+}
+
+// reject Drop the application failing the check
+func (h *versionHistory) reject(application *Application) {
+	if application == h.live {
+		h.rejected = true
+	}
+	for i, kept := range h.versions {
+		if kept == application {
+			h.versions = append(h.versions[:i], h.versions[i+1:]...)
+			break
+		}
+	}
+}
+
+// callVersionCheck Call the check, the panic is returned as the error
+func callVersionCheck(check VersionCheck, projectID string, version string) (err error) {
+	defer func() {
+		recoverErr := recover()
+		if recoverErr != nil {
+			body := make([]byte, 1<<10)
+			runtime.Stack(body, false)
+			err = fmt.Errorf("recoverErr:%v\n%s", recoverErr, body)
+		}
+	}()
+	return check(projectID, version)
+}
+
+func releaseHistory() {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	historySize = DefaultVersionHistory
+	versionCheck = nil
+	historyIndex = map[string]*versionHistory{}
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/env"
+)
+
+// refreshVersion Simulate a refresh of the project to the version
+func refreshVersion(version string) {
+	keepApplication(&Application{ProjectID: projectID, Version: version})
+	selectVersion(context.Background(), projectID)
+}
+
+func servedVersion() string {
+	if application := GetApplication(projectID); application != nil {
+		return application.Version
+	}
+	return ""
+}
+
+func TestPinVersion(t *testing.T) {
+	defer Release()
+	SetVersionHistory(3, nil)
+	if err := PinVersion(projectID, "v1"); !errors.Is(err, env.ErrProjectNotFound) {
+		t.Errorf("PinVersion() error = %v, want ErrProjectNotFound when the project is not loaded", err)
+	}
+	if err := Unpin(projectID); !errors.Is(err, env.ErrProjectNotFound) {
+		t.Errorf("Unpin() error = %v, want ErrProjectNotFound when the project is not loaded", err)
+	}
+	for _, version := range []string{"v1", "v2", "v3", "v4"} {
+		refreshVersion(version)
+	}
+	status, _ := GetVersionStatus(projectID)
+	if !reflect.DeepEqual(status, VersionStatus{Served: "v4", Live: "v4", Kept: []string{"v2", "v3", "v4"}}) {
+		t.Errorf("GetVersionStatus() = %+v", status)
+	}
+	if err := PinVersion(projectID, "v1"); !errors.Is(err, env.ErrVersionNotKept) {
+		t.Errorf("PinVersion() error = %v, want ErrVersionNotKept when the version is not kept", err)
+	}
+	if err := PinVersion(projectID, "v2"); err != nil {
+		t.Fatalf("PinVersion() error = %v", err)
+	}
+	// the newer versions are kept but not served, the pinned one is not dropped
+	refreshVersion("v5")
+	refreshVersion("v6")
+	status, _ = GetVersionStatus(projectID)
+	if status.Served != "v2" || status.Live != "v6" || status.Pinned != "v2" {
+		t.Errorf("GetVersionStatus() = %+v", status)
+	}
+	if err := Unpin(projectID); err != nil {
+		t.Fatalf("Unpin() error = %v", err)
+	}
+	status, _ = GetVersionStatus(projectID)
+	if !reflect.DeepEqual(status, VersionStatus{Served: "v6", Live: "v6", Kept: []string{"v3", "v4", "v6"}}) {
+		t.Errorf("GetVersionStatus() = %+v", status)
+	}
+	if liveApplication(projectID).Version != "v6" {
+		t.Errorf("liveApplication() = %v", liveApplication(projectID).Version)
+	}
+}
+
+func TestSetVersionHistory_rollback(t *testing.T) {
+	defer Release()
+	var bad = map[string]bool{"v2": true}
+	SetVersionHistory(2, func(projectID string, version string) error {
+		if bad[version] {
+			return errors.New("unhealthy")
+		}
+		return nil
+	})
+	refreshVersion("v1")
+	// the refreshed version failing the check is not served
+	refreshVersion("v2")
+	status, _ := GetVersionStatus(projectID)
+	if !reflect.DeepEqual(status, VersionStatus{Served: "v1", Live: "v2", Kept: []string{"v1"}, Rejected: "v2"}) {
+		t.Errorf("GetVersionStatus() = %+v", status)
+	}
+	refreshVersion("v3")
+	if servedVersion() != "v3" {
+		t.Errorf("served version = %s, want v3", servedVersion())
+	}
+	// the served version failing the check later is rolled back
+	bad["v3"] = true
+	selectVersion(context.Background(), projectID)
+	if servedVersion() != "v1" {
+		t.Errorf("served version = %s, want v1", servedVersion())
+	}
+	// the first version is served even if it fails the check, there is nothing to roll back to
+	Release()
+	SetVersionHistory(2, func(projectID string, version string) error { panic("check panic") })
+	refreshVersion("v1")
+	if servedVersion() != "v1" {
+		t.Errorf("served version = %s, want v1", servedVersion())
+	}
+}
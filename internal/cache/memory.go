@@ -0,0 +1,209 @@
+// Package cache Local cache implementation
+package cache
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/RoaringBitmap/roaring"
+	protoctabcacheserver "github.com/abetterchoice/protoc_cache_server"
+)
+
+// mapEntryOverhead The estimated bytes of a map entry besides its key and value, the hash and the bucket slack
+const mapEntryOverhead = 8
+
+// IndexMemory The estimated memory of an index of the local cache data
+type IndexMemory struct {
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// MemoryUsage The estimated heap bytes of the local cache data of a project by index.
+// The buffers of the bucket infos are counted in the bucket info indexes. The bitmaps built on them without a copy
+// only count their own structure, the compacted bitmaps count their bytes too.
+// The data shared with the served version is not counted again in History
+type MemoryUsage struct {
+	ProjectID            string      `json:"projectId"`
+	Version              string      `json:"version"`   // the served version
+	TabConfig            IndexMemory `json:"tabConfig"` // the entries are the layers
+	ExperimentBucketInfo IndexMemory `json:"experimentBucketInfo"`
+	ExperimentBitmaps    IndexMemory `json:"experimentBitmaps"`
+	GroupBucketInfo      IndexMemory `json:"groupBucketInfo"`
+	GroupBitmaps         IndexMemory `json:"groupBitmaps"`
+	// Lookups The indexes built from the tab config by each version, the layer, the domain metadata,
+	// the variant key and the DMP tag indexes
+	Lookups IndexMemory `json:"lookups"`
+	Total   int64       `json:"total"` // the bytes of the served version
+	// KeptVersions The other kept versions, see SetVersionHistory
+	KeptVersions int `json:"keptVersions"`
+	// History The bytes of the other kept versions not shared with the served version
+	History int64 `json:"history"`
+}
+
+// GetMemoryUsage The estimated memory of the local cache data of the project, false if it is not loaded.
+// It walks the whole data, so it is meant for the diagnostics rather than the hot path
+func GetMemoryUsage(projectID string) (*MemoryUsage, bool) {
+	served := GetApplication(projectID)
+	if served == nil {
+		return nil, false
+	}
+	w := &memoryWalker{seen: map[uintptr]bool{}}
+	usage := w.application(served)
+	for _, application := range keptApplications(projectID) {
+		if application == served {
+			continue
+		}
+		usage.KeptVersions++
+		usage.History += w.application(application).Total
+	}
+	return usage, true
+}
+
+// keptApplications The kept and the live versions of the project
+func keptApplications(projectID string) []*Application {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	h, ok := historyIndex[projectID]
+	if !ok {
+		return nil
+	}
+	result := append([]*Application{}, h.versions...)
+	if h.live != nil && h.find(h.live.Version) != h.live {
+		result = append(result, h.live)
+	}
+	return result
+}
+
+// memoryWalker Estimate the bytes of the data, the data seen by the walker is counted once
+type memoryWalker struct {
+	seen map[uintptr]bool
+}
+
+func (w *memoryWalker) visit(pointer uintptr) bool {
+	if pointer == 0 || w.seen[pointer] {
+		return false
+	}
+	w.seen[pointer] = true
+	return true
+}
+
+func (w *memoryWalker) application(application *Application) *MemoryUsage {
+	usage := &MemoryUsage{
+		ProjectID:            application.ProjectID,
+		Version:              application.Version,
+		TabConfig:            IndexMemory{Entries: len(application.LayerIndex)},
+		ExperimentBucketInfo: w.bucketInfoIndex(application.ExperimentIDBucketInfoIndex),
+		ExperimentBitmaps: w.bitmapIndex(application.ExperimentIDRoaringBitmapIndex,
+			application.ExperimentIDBucketInfoIndex),
+		GroupBucketInfo: w.bucketInfoIndex(application.GroupIDBucketInfoIndex),
+		GroupBitmaps:    w.bitmapIndex(application.GroupIDRoaringBitmapIndex, application.GroupIDBucketInfoIndex),
+	}
+	usage.TabConfig.Bytes = w.value(reflect.ValueOf(application.TabConfig))
+	for _, index := range []interface{}{application.LayerIndex, application.FullFlowLayerIndex,
+		application.LayerDomainMetadataListIndex, application.VariantKeyLayerMap, application.DMPTagInfo} {
+		v := reflect.ValueOf(index)
+		usage.Lookups.Entries += v.Len()
+		usage.Lookups.Bytes += w.value(v)
+	}
+	usage.Total = usage.TabConfig.Bytes + usage.ExperimentBucketInfo.Bytes + usage.ExperimentBitmaps.Bytes +
+		usage.GroupBucketInfo.Bytes + usage.GroupBitmaps.Bytes + usage.Lookups.Bytes
+	return usage
+}
+
+func (w *memoryWalker) bucketInfoIndex(index map[int64]*protoctabcacheserver.BucketInfo) IndexMemory {
+	result := IndexMemory{Entries: len(index)}
+	if !w.visit(reflect.ValueOf(index).Pointer()) {
+		return result
+	}
+	result.Bytes = int64(len(index)) * (8 + 8 + mapEntryOverhead)
+	for _, bucketInfo := range index {
+		if bucketInfo == nil || !w.visit(uintptr(unsafe.Pointer(bucketInfo))) {
+			continue
+		}
+		result.Bytes += int64(unsafe.Sizeof(*bucketInfo)) + int64(len(bucketInfo.Version)) +
+			int64(cap(bucketInfo.Bitmap))
+		if bucketInfo.TrafficRange != nil {
+			result.Bytes += int64(unsafe.Sizeof(*bucketInfo.TrafficRange))
+		}
+	}
+	return result
+}
+
+// bitmapIndex The bitmaps of the index, the buffers of the bucket infos they are built on are not counted
+func (w *memoryWalker) bitmapIndex(index map[int64]*roaring.Bitmap,
+	bucketInfoIndex map[int64]*protoctabcacheserver.BucketInfo) IndexMemory {
+	result := IndexMemory{Entries: len(index)}
+	if !w.visit(reflect.ValueOf(index).Pointer()) {
+		return result
+	}
+	result.Bytes = int64(len(index)) * (8 + 8 + mapEntryOverhead)
+	for id, bitmap := range index {
+		if bitmap == nil || !w.visit(uintptr(unsafe.Pointer(bitmap))) {
+			continue
+		}
+		result.Bytes += int64(unsafe.Sizeof(*bitmap))
+		if !isBuiltOnBuffer(bitmap, bucketInfoIndex[id]) {
+			result.Bytes += int64(bitmap.GetSizeInBytes())
+		}
+	}
+	return result
+}
+
+// value The bytes referenced by v, v itself excluded. The unexported fields, the internal state of the messages,
+// are skipped
+func (w *memoryWalker) value(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || !w.visit(v.Pointer()) {
+			return 0
+		}
+		return int64(v.Type().Elem().Size()) + w.value(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return w.value(v.Elem())
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() || !w.visit(v.Pointer()) {
+			return 0
+		}
+		bytes := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		if hasReferences(v.Type().Elem()) {
+			for i := 0; i < v.Len(); i++ {
+				bytes += w.value(v.Index(i))
+			}
+		}
+		return bytes
+	case reflect.Map:
+		if v.IsNil() || !w.visit(v.Pointer()) {
+			return 0
+		}
+		bytes := int64(v.Len()) * int64(v.Type().Key().Size()+v.Type().Elem().Size()+mapEntryOverhead)
+		iter := v.MapRange()
+		for iter.Next() {
+			bytes += w.value(iter.Key()) + w.value(iter.Value())
+		}
+		return bytes
+	case reflect.Struct:
+		var bytes int64
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			bytes += w.value(v.Field(i))
+		}
+		return bytes
+	}
+	return 0
+}
+
+// hasReferences Whether the values of the type may reference other memory
+func hasReferences(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.String, reflect.Slice, reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/abetterchoice/go-sdk/internal/client"
+	"github.com/abetterchoice/go-sdk/testdata"
+	protoctabcacheserver "github.com/abetterchoice/protoc_cache_server"
+)
+
+// uncompactedBitmap The buffer of the bitmap of [left, right) without the run containers
+func uncompactedBitmap(left uint32, right uint32) []byte {
+	bitmap := roaring.New()
+	for i := left; i < right; i++ {
+		bitmap.Add(i)
+	}
+	buffer, _ := bitmap.ToBytes()
+	return buffer
+}
+
+func TestLoadBitmap(t *testing.T) {
+	buffer := uncompactedBitmap(1, 5001)
+	bucketInfo := &protoctabcacheserver.BucketInfo{BucketType: protoctabcacheserver.BucketType_BUCKET_TYPE_BITMAP,
+		Bitmap: buffer}
+	served := append([]byte{}, buffer...)
+	bitmap, err := loadBitmap(bucketInfo.Bitmap)
+	if err != nil {
+		t.Fatalf("loadBitmap() error = %v", err)
+	}
+	if bitmap.GetCardinality() != 5000 || !bitmap.Contains(5000) || bitmap.Contains(5001) {
+		t.Errorf("loadBitmap() = %v", bitmap)
+	}
+	if bitmap.GetSerializedSizeInBytes() >= uint64(len(buffer)) || isBuiltOnBuffer(bitmap, bucketInfo) {
+		t.Errorf("the bitmap is not compacted, %d >= %d bytes", bitmap.GetSerializedSizeInBytes(), len(buffer))
+	}
+	// the buffer of the bucket info is served as is
+	if &bucketInfo.Bitmap[0] != &buffer[0] || !reflect.DeepEqual(bucketInfo.Bitmap, served) {
+		t.Errorf("the buffer of the bucket info should not be changed")
+	}
+	// the compacted buffer is not compacted again, the bitmap is built on it
+	compacted, _ := bitmap.ToBytes()
+	bucketInfo.Bitmap = compacted
+	if bitmap, err = loadBitmap(compacted); err != nil || !isBuiltOnBuffer(bitmap, bucketInfo) {
+		t.Errorf("loadBitmap() error = %v, the bitmap should be built on the compacted buffer", err)
+	}
+}
+
+func TestSetupGroupBucketInfo_sharing(t *testing.T) {
+	defer func() {
+		client.CacheClient = nil
+	}()
+	groupBucketInfo := map[int64]*protoctabcacheserver.BucketInfo{
+		1: {BucketType: protoctabcacheserver.BucketType_BUCKET_TYPE_BITMAP, Bitmap: uncompactedBitmap(1, 500),
+			Version: "1", ModifyType: protoctabcacheserver.ModifyType_MODIFY_UPDATE},
+	}
+	client.CacheClient = testdata.MockCacheClientWithData(t, nil, nil, groupBucketInfo)
+	application := getLocalCacheWithDefault(projectID)
+	application.LayerIndex = map[string]*protoctabcacheserver.Layer{
+		"layer": {GroupIndex: map[int64]*protoctabcacheserver.Group{1: {Id: 1}}},
+	}
+	if err := setupGroupBucketInfo(context.Background(), application); err != nil {
+		t.Fatalf("setupGroupBucketInfo() error = %v", err)
+	}
+	bitmap := application.GroupIDRoaringBitmapIndex[1]
+	// the same version, the indexes and the bitmap are shared
+	refreshed := getNewApplication(application)
+	if err := setupGroupBucketInfo(context.Background(), refreshed); err != nil {
+		t.Fatalf("setupGroupBucketInfo() error = %v", err)
+	}
+	if reflect.ValueOf(refreshed.GroupIDRoaringBitmapIndex).Pointer() !=
+		reflect.ValueOf(application.GroupIDRoaringBitmapIndex).Pointer() {
+		t.Errorf("the unchanged bitmap index should be shared")
+	}
+	// a new version, the indexes are copied, the current version is not changed
+	groupBucketInfo[1] = &protoctabcacheserver.BucketInfo{BucketType: protoctabcacheserver.BucketType_BUCKET_TYPE_RANGE,
+		TrafficRange: &protoctabcacheserver.TrafficRange{Left: 1, Right: 10}, Version: "2",
+		ModifyType: protoctabcacheserver.ModifyType_MODIFY_UPDATE}
+	if err := setupGroupBucketInfo(context.Background(), refreshed); err != nil {
+		t.Fatalf("setupGroupBucketInfo() error = %v", err)
+	}
+	if _, ok := refreshed.GroupIDRoaringBitmapIndex[1]; ok || refreshed.GroupIDBucketInfoIndex[1].Version != "2" {
+		t.Errorf("the bitmap of the new version should be dropped")
+	}
+	if application.GroupIDRoaringBitmapIndex[1] != bitmap || application.GroupIDBucketInfoIndex[1].Version != "1" {
+		t.Errorf("the current version should not be changed")
+	}
+}
+
+func TestGetMemoryUsage(t *testing.T) {
+	defer Release()
+	if _, ok := GetMemoryUsage(projectID); ok {
+		t.Errorf("GetMemoryUsage() ok = true, want false")
+	}
+	SetVersionHistory(2, nil)
+	application := getLocalCacheWithDefault(projectID)
+	application.Version = "v1"
+	application.TabConfig = testdata.NormalTabConfig
+	application.GroupIDBucketInfoIndex[1] = &protoctabcacheserver.BucketInfo{
+		BucketType: protoctabcacheserver.BucketType_BUCKET_TYPE_BITMAP, Bitmap: uncompactedBitmap(1, 500)}
+	bitmap, _ := loadBitmap(application.GroupIDBucketInfoIndex[1].Bitmap)
+	application.GroupIDRoaringBitmapIndex[1] = bitmap
+	keepApplication(application)
+	selectVersion(context.Background(), projectID)
+	refreshed := getNewApplication(application)
+	refreshed.Version = "v2"
+	keepApplication(refreshed)
+	selectVersion(context.Background(), projectID)
+	usage, ok := GetMemoryUsage(projectID)
+	if !ok {
+		t.Fatalf("GetMemoryUsage() ok = false")
+	}
+	if usage.Version != "v2" || usage.GroupBitmaps.Entries != 1 || usage.TabConfig.Bytes == 0 ||
+		usage.GroupBitmaps.Bytes < int64(bitmap.GetSizeInBytes()) || usage.Total != usage.TabConfig.Bytes+
+		usage.GroupBucketInfo.Bytes+usage.GroupBitmaps.Bytes+usage.ExperimentBucketInfo.Bytes+
+		usage.ExperimentBitmaps.Bytes+usage.Lookups.Bytes {
+		t.Errorf("GetMemoryUsage() = %+v", usage)
+	}
+	// the data of v1 is shared with v2
+	if usage.KeptVersions != 1 || usage.History != 0 {
+		t.Errorf("GetMemoryUsage() kept = %d, history = %d, want 1 and 0", usage.KeptVersions, usage.History)
+	}
+}
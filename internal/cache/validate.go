@@ -0,0 +1,442 @@
+// Package cache Local cache implementation
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	protoctabcacheserver "github.com/abetterchoice/protoc_cache_server"
+)
+
+// Severity The severity of a finding of the validation of the local cache data
+type Severity string
+
+// The severities of the findings
+const (
+	// SeverityWarning The finding is reported, the version is still applied
+	SeverityWarning Severity = "warning"
+	// SeverityFatal The evaluation of the entity is broken. The version is rejected and the served version is kept,
+	// with SetLenientValidation it is reported like a warning
+	SeverityFatal Severity = "fatal"
+)
+
+// The codes of the findings
+const (
+	FindingTrafficOverlap        = "traffic_overlap"       // the traffic ranges of the sibling domains overlap
+	FindingTrafficOutOfBucket    = "traffic_out_of_bucket" // a traffic range is outside [1, bucketSize]
+	FindingInvalidBucketSize     = "invalid_bucket_size"   // the bucket size of a hashed entity is not positive
+	FindingMissingBucketInfo     = "missing_bucket_info"   // a group has no bucket info, so it is never hit
+	FindingMissingGroup          = "missing_group"         // the GroupIdIndex of an experiment has a missing group
+	FindingMissingHoldoutLayer   = "missing_holdout_layer" // the holdout layer key is not in the HoldoutLayerIndex
+	FindingHoldoutCycle          = "holdout_cycle"         // the holdout layers hold each other out
+	FindingDuplicateLayerKey     = "duplicate_layer_key"   // the layer key is used by more than one layer
+	FindingUnsupportedHashMethod = "unsupported_hash_method"
+)
+
+// Finding A problem of the local cache data found by ValidateApplication
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Path     string   `json:"path"` // the entity, for example domain=d1/multiLayerDomain=d2/layer=l1/experiment=12
+	Message  string   `json:"message"`
+}
+
+// String The finding in one line, for the logs and the errors
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s]%s %s: %s", f.Severity, f.Code, f.Path, f.Message)
+}
+
+// ValidationError The error of the version rejected by the fatal findings of the validation
+type ValidationError struct {
+	ProjectID string
+	Version   string
+	Findings  []Finding // all the findings, the warnings included
+}
+
+func (e *ValidationError) Error() string {
+	var fatal []string
+	for _, finding := range e.Findings {
+		if finding.Severity == SeverityFatal {
+			fatal = append(fatal, finding.String())
+		}
+	}
+	return fmt.Sprintf("invalid tabConfig of projectID [%s] version [%s], %d fatal findings: %s",
+		e.ProjectID, e.Version, len(fatal), strings.Join(fatal, "; "))
+}
+
+// lenientValidation Whether the fatal findings are only reported, see SetLenientValidation
+var lenientValidation int32
+
+// SetLenientValidation Apply the refreshed version with a fatal finding instead of rejecting it,
+// the findings are only reported. By default the version is rejected and the served one is kept
+func SetLenientValidation(lenient bool) {
+	var value int32
+	if lenient {
+		value = 1
+	}
+	atomic.StoreInt32(&lenientValidation, value)
+}
+
+// checkFindings The ValidationError of the findings of the application if any of them is fatal,
+// nil if none is fatal or the validation is lenient
+func checkFindings(application *Application, findings []Finding) error {
+	if atomic.LoadInt32(&lenientValidation) == 1 {
+		return nil
+	}
+	for _, finding := range findings {
+		if finding.Severity == SeverityFatal {
+			return &ValidationError{ProjectID: application.ProjectID, Version: application.Version, Findings: findings}
+		}
+	}
+	return nil
+}
+
+// supportedHashMethods The hash methods of hashutil, the unknown one falls back to BKDR
+var supportedHashMethods = map[protoctabcacheserver.HashMethod]bool{
+	protoctabcacheserver.HashMethod_HASH_METHOD_UNKNOWN: true,
+	protoctabcacheserver.HashMethod_HASH_METHOD_BKDR:    true,
+	protoctabcacheserver.HashMethod_HASH_METHOD_AP:      true,
+	protoctabcacheserver.HashMethod_HASH_METHOD_DJB:     true,
+	protoctabcacheserver.HashMethod_HASH_METHOD_NEW:     true,
+	protoctabcacheserver.HashMethod_HASH_METHOD_NEW_MD5: true,
+}
+
+// ValidateApplication Check the structure of the local cache data built by a refresh, the findings are sorted by
+// the walk of the domains, the holdout layers and then the remote configs.
+// The nil checks of validateTabConfig and of the index setups are not repeated
+func ValidateApplication(application *Application) []Finding {
+	if application == nil || application.TabConfig == nil || application.TabConfig.ExperimentData == nil {
+		return nil
+	}
+	v := &validator{application: application, layerPaths: map[string]string{}}
+	experimentData := application.TabConfig.ExperimentData
+	if globalDomain := experimentData.GlobalDomain; globalDomain != nil && globalDomain.Metadata != nil {
+		v.domain(globalDomain, "domain="+globalDomain.Metadata.Key)
+	}
+	if experimentData.HoldoutData != nil {
+		holdoutLayerIndex := experimentData.HoldoutData.HoldoutLayerIndex
+		for _, key := range sortedLayerKeys(holdoutLayerIndex) {
+			v.layer(holdoutLayerIndex[key], "holdout/layer="+key)
+		}
+		v.holdoutCycles(holdoutLayerIndex)
+	}
+	if application.TabConfig.ConfigData != nil {
+		remoteConfigIndex := application.TabConfig.ConfigData.RemoteConfigIndex
+		keys := make([]string, 0, len(remoteConfigIndex))
+		for key := range remoteConfigIndex {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			v.remoteConfig(remoteConfigIndex[key], "remoteConfig="+key)
+		}
+	}
+	return v.findings
+}
+
+type validator struct {
+	application *Application
+	findings    []Finding
+	layerPaths  map[string]string // the path of each layer key of the domains
+}
+
+func (v *validator) add(severity Severity, code string, path string, format string, args ...interface{}) {
+	v.findings = append(v.findings, Finding{Severity: severity, Code: code, Path: path,
+		Message: fmt.Sprintf(format, args...)})
+}
+
+// hashedMetadata Check the metadata of the domain hashing its children. Only the domains, the top-level one and the
+// subdomains, hash the unit, the holdout and the multi-layer domains only match their traffic ranges
+func (v *validator) hashedMetadata(metadata *protoctabcacheserver.DomainMetadata, path string) bool {
+	v.hashMethod(metadata.HashMethod, path)
+	if metadata.BucketSize <= 0 {
+		v.add(SeverityFatal, FindingInvalidBucketSize, path, "bucketSize=%d", metadata.BucketSize)
+		return false
+	}
+	return true
+}
+
+func (v *validator) hashMethod(hashMethod protoctabcacheserver.HashMethod, path string) {
+	if !supportedHashMethods[hashMethod] {
+		v.add(SeverityFatal, FindingUnsupportedHashMethod, path, "hashMethod=%d", hashMethod)
+	}
+}
+
+// domain Check the domain and its children, the traffic ranges of the children are in the buckets of the domain
+func (v *validator) domain(domain *protoctabcacheserver.Domain, path string) {
+	hashed := v.hashedMetadata(domain.Metadata, path)
+	var holdoutRanges, multiLayerRanges, subdomainRanges []pathRange
+	for _, holdoutDomain := range domain.HoldoutDomainList {
+		if holdoutDomain == nil || holdoutDomain.Metadata == nil {
+			continue
+		}
+		childPath := path + "/holdoutDomain=" + holdoutDomain.Metadata.Key
+		holdoutRanges = v.childRanges(holdoutRanges, domain.Metadata, holdoutDomain.Metadata, hashed, childPath)
+		v.layers(holdoutDomain.LayerList, childPath)
+	}
+	for _, multiLayerDomain := range domain.MultiLayerDomainList {
+		if multiLayerDomain == nil || multiLayerDomain.Metadata == nil {
+			continue
+		}
+		childPath := path + "/multiLayerDomain=" + multiLayerDomain.Metadata.Key
+		multiLayerRanges = v.childRanges(multiLayerRanges, domain.Metadata, multiLayerDomain.Metadata, hashed,
+			childPath)
+		v.layers(multiLayerDomain.LayerList, childPath)
+	}
+	for _, subdomain := range domain.DomainList {
+		if subdomain == nil || subdomain.Metadata == nil {
+			continue
+		}
+		childPath := path + "/domain=" + subdomain.Metadata.Key
+		subdomainRanges = v.childRanges(subdomainRanges, domain.Metadata, subdomain.Metadata, hashed, childPath)
+		v.domain(subdomain, childPath)
+	}
+	// The first hit holdout domain and multi-layer domain wins, all the hit subdomains are merged,
+	// so an overlap shadows a sibling or mixes the layers of two subdomains, the evaluation still works
+	v.overlaps(holdoutRanges)
+	v.overlaps(multiLayerRanges)
+	v.overlaps(subdomainRanges)
+}
+
+// pathRange The traffic range of the entity of the path
+type pathRange struct {
+	path string
+	*protoctabcacheserver.TrafficRange
+}
+
+// childRanges Append the traffic ranges of the child to ranges, checking that they are in the buckets of the parent
+func (v *validator) childRanges(ranges []pathRange, parent *protoctabcacheserver.DomainMetadata,
+	child *protoctabcacheserver.DomainMetadata, hashed bool, path string) []pathRange {
+	for _, r := range child.TrafficRangeList {
+		if r == nil {
+			continue
+		}
+		if hashed {
+			v.trafficRange(r, parent.BucketSize, path)
+		}
+		ranges = append(ranges, pathRange{path: path, TrafficRange: r})
+	}
+	return ranges
+}
+
+func (v *validator) trafficRange(r *protoctabcacheserver.TrafficRange, bucketSize int64, path string) {
+	if r.Left < 1 || r.Left > r.Right || r.Right > bucketSize {
+		v.add(SeverityWarning, FindingTrafficOutOfBucket, path, "range=[%d,%d], bucketSize=%d", r.Left, r.Right,
+			bucketSize)
+	}
+}
+
+// overlaps Report the overlapping ranges of different entities
+func (v *validator) overlaps(ranges []pathRange) {
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Left < ranges[j].Left
+	})
+	for i := 1; i < len(ranges); i++ {
+		for j := 0; j < i; j++ { // the previous ranges reaching the current one
+			if ranges[j].path == ranges[i].path || ranges[j].Right < ranges[i].Left {
+				continue
+			}
+			v.add(SeverityWarning, FindingTrafficOverlap, ranges[i].path, "range=[%d,%d] overlaps [%d,%d] of %s",
+				ranges[i].Left, ranges[i].Right, ranges[j].Left, ranges[j].Right, ranges[j].path)
+		}
+	}
+}
+
+// layers Check the layers of a domain, their keys should be unique in the domain tree. The evaluation keeps
+// the layer of the first hit domain, so a duplicate key is a warning
+func (v *validator) layers(layerList []*protoctabcacheserver.Layer, path string) {
+	for _, layer := range layerList {
+		if layer == nil || layer.Metadata == nil {
+			continue
+		}
+		layerPath := path + "/layer=" + layer.Metadata.Key
+		if firstPath, ok := v.layerPaths[layer.Metadata.Key]; ok {
+			v.add(SeverityWarning, FindingDuplicateLayerKey, layerPath, "the key is also used by %s", firstPath)
+		} else {
+			v.layerPaths[layer.Metadata.Key] = layerPath
+		}
+		v.layer(layer, layerPath)
+	}
+}
+
+// layer Check the holdout references, the hashing and the groups of the layer
+func (v *validator) layer(layer *protoctabcacheserver.Layer, path string) {
+	if layer == nil || layer.Metadata == nil {
+		return
+	}
+	v.holdoutLayerKeys(layer.Metadata.HoldoutLayerKeys, path)
+	if layer.Metadata.BucketSize <= 0 { // the layer is skipped by the layer index
+		return
+	}
+	v.hashMethod(layer.Metadata.HashMethod, path)
+	isDoubleHash := layer.Metadata.HashType == protoctabcacheserver.HashType_HASH_TYPE_DOUBLE
+	for _, experimentID := range sortedIDs(layer.ExperimentIndex) {
+		experiment := layer.ExperimentIndex[experimentID]
+		if experiment == nil || experiment.Id == 0 { // the placeholder experiment of the layer
+			continue
+		}
+		experimentPath := fmt.Sprintf("%s/experiment=%d", path, experiment.Id)
+		for _, groupID := range sortedGroupIDs(experiment.GroupIdIndex) {
+			if group, ok := layer.GroupIndex[groupID]; !ok || group == nil {
+				v.add(SeverityFatal, FindingMissingGroup, experimentPath, "groupID=%d is not in the layer", groupID)
+			}
+		}
+		if !isDoubleHash {
+			continue
+		}
+		v.hashMethod(experiment.HashMethod, experimentPath)
+		if experiment.BucketSize <= 0 {
+			v.add(SeverityFatal, FindingInvalidBucketSize, experimentPath, "bucketSize=%d", experiment.BucketSize)
+		}
+		v.bucketInfo(v.application.ExperimentIDBucketInfoIndex[experiment.Id], layer.Metadata.BucketSize,
+			experimentPath)
+	}
+	for _, groupID := range sortedGroupIDs(groupSet(layer.GroupIndex)) {
+		group := layer.GroupIndex[groupID]
+		if group == nil || group.IsDefault {
+			continue
+		}
+		groupPath := fmt.Sprintf("%s/group=%d", path, group.Id)
+		bucketInfo, ok := v.application.GroupIDBucketInfoIndex[group.Id]
+		if !ok {
+			v.add(SeverityWarning, FindingMissingBucketInfo, groupPath, "the group is never hit")
+			continue
+		}
+		bucketSize := layer.Metadata.BucketSize
+		if isDoubleHash {
+			experiment, ok := layer.ExperimentIndex[group.ExperimentId]
+			if !ok || experiment == nil || experiment.BucketSize <= 0 {
+				continue
+			}
+			bucketSize = experiment.BucketSize
+		}
+		v.bucketInfo(bucketInfo, bucketSize, groupPath)
+	}
+}
+
+// bucketInfo Check that the traffic range of the bucket info is in the buckets
+func (v *validator) bucketInfo(bucketInfo *protoctabcacheserver.BucketInfo, bucketSize int64, path string) {
+	if bucketInfo == nil || bucketInfo.BucketType != protoctabcacheserver.BucketType_BUCKET_TYPE_RANGE ||
+		bucketInfo.TrafficRange == nil {
+		return
+	}
+	v.trafficRange(bucketInfo.TrafficRange, bucketSize, path)
+}
+
+func (v *validator) holdoutLayerKeys(holdoutLayerKeys []string, path string) {
+	if len(holdoutLayerKeys) == 0 {
+		return
+	}
+	var holdoutLayerIndex map[string]*protoctabcacheserver.Layer
+	if holdoutData := v.application.TabConfig.ExperimentData.HoldoutData; holdoutData != nil {
+		holdoutLayerIndex = holdoutData.HoldoutLayerIndex
+	}
+	for _, key := range holdoutLayerKeys {
+		if _, ok := holdoutLayerIndex[key]; !ok {
+			v.add(SeverityFatal, FindingMissingHoldoutLayer, path, "holdout layerKey=%s", key)
+		}
+	}
+}
+
+// holdoutCycles Report each cycle of the holdout layers once, by the holdout layer closing it
+func (v *validator) holdoutCycles(holdoutLayerIndex map[string]*protoctabcacheserver.Layer) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(holdoutLayerIndex))
+	var stack []string
+	var visit func(key string)
+	visit = func(key string) {
+		state[key] = visiting
+		stack = append(stack, key)
+		if layer := holdoutLayerIndex[key]; layer != nil && layer.Metadata != nil {
+			for _, next := range layer.Metadata.HoldoutLayerKeys {
+				if _, ok := holdoutLayerIndex[next]; !ok {
+					continue // reported as a missing holdout layer
+				}
+				switch state[next] {
+				case visiting:
+					cycle := append([]string{}, stack[indexOf(stack, next):]...)
+					v.add(SeverityFatal, FindingHoldoutCycle, "holdout/layer="+key, "cycle=%s",
+						strings.Join(append(cycle, next), "->"))
+				case 0:
+					visit(next)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[key] = visited
+	}
+	for _, key := range sortedLayerKeys(holdoutLayerIndex) {
+		if state[key] == 0 {
+			visit(key)
+		}
+	}
+}
+
+// remoteConfig Check the holdout references and the hashing of the conditions of the remote config
+func (v *validator) remoteConfig(remoteConfig *protoctabcacheserver.RemoteConfig, path string) {
+	if remoteConfig == nil {
+		return
+	}
+	v.holdoutLayerKeys(remoteConfig.HoldoutLayerKeys, path)
+	for _, condition := range remoteConfig.ConditionList {
+		if condition == nil {
+			continue
+		}
+		conditionPath := fmt.Sprintf("%s/condition=%d", path, condition.Id)
+		v.hashMethod(condition.HashMethod, conditionPath)
+		if condition.BucketSize <= 0 {
+			v.add(SeverityFatal, FindingInvalidBucketSize, conditionPath, "bucketSize=%d", condition.BucketSize)
+			continue
+		}
+		v.bucketInfo(condition.BucketInfo, condition.BucketSize, conditionPath)
+	}
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortedLayerKeys(layerIndex map[string]*protoctabcacheserver.Layer) []string {
+	keys := make([]string, 0, len(layerIndex))
+	for key := range layerIndex {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedIDs(experimentIndex map[int64]*protoctabcacheserver.Experiment) []int64 {
+	ids := make([]int64, 0, len(experimentIndex))
+	for id := range experimentIndex {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func groupSet(groupIndex map[int64]*protoctabcacheserver.Group) map[int64]bool {
+	result := make(map[int64]bool, len(groupIndex))
+	for id := range groupIndex {
+		result[id] = true
+	}
+	return result
+}
+
+func sortedGroupIDs(groupIDIndex map[int64]bool) []int64 {
+	ids := make([]int64, 0, len(groupIDIndex))
+	for id := range groupIDIndex {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
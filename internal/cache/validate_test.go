@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/internal/client"
+	"github.com/abetterchoice/go-sdk/testdata"
+	protoctabcacheserver "github.com/abetterchoice/protoc_cache_server"
+	"github.com/pkg/errors"
+)
+
+func validationLayer(key string, holdoutLayerKeys ...string) *protoctabcacheserver.Layer {
+	return &protoctabcacheserver.Layer{
+		Metadata: &protoctabcacheserver.LayerMetadata{Key: key, BucketSize: 100,
+			HashType: protoctabcacheserver.HashType_HASH_TYPE_SINGLE, HoldoutLayerKeys: holdoutLayerKeys},
+		GroupIndex: map[int64]*protoctabcacheserver.Group{},
+		ExperimentIndex: map[int64]*protoctabcacheserver.Experiment{
+			0: {}, // the placeholder experiment
+		},
+	}
+}
+
+func validationDomain(key string, left int64, right int64, layers ...*protoctabcacheserver.Layer) (
+	*protoctabcacheserver.DomainMetadata, []*protoctabcacheserver.Layer) {
+	return &protoctabcacheserver.DomainMetadata{Key: key, BucketSize: 100,
+		TrafficRangeList: []*protoctabcacheserver.TrafficRange{{Left: left, Right: right}}}, layers
+}
+
+func TestValidateApplication(t *testing.T) {
+	layer1 := validationLayer("layer1", "holdout1")
+	layer1.GroupIndex[11] = &protoctabcacheserver.Group{Id: 11}
+	layer1.GroupIndex[12] = &protoctabcacheserver.Group{Id: 12}
+	layer1.ExperimentIndex[1] = &protoctabcacheserver.Experiment{Id: 1,
+		GroupIdIndex: map[int64]bool{11: true, 12: true, 13: true}}
+	holdoutMetadata, holdoutLayers := validationDomain("holdout", 1, 10, validationLayer("layer2"))
+	multiMetadata1, multiLayers1 := validationDomain("multi1", 11, 60, layer1)
+	multiMetadata2, multiLayers2 := validationDomain("multi2", 50, 120, validationLayer("layer1"))
+	// the multi-layer domains do not hash, their bucket size is not used
+	multiMetadata1.BucketSize = 0
+	holdout1 := validationLayer("holdout1", "holdout2")
+	holdout2 := validationLayer("holdout2", "holdout1", "missing")
+	holdout2.Metadata.HashMethod = 100
+	application := &Application{
+		ProjectID: projectID,
+		Version:   "v1",
+		TabConfig: &protoctabcacheserver.TabConfig{
+			ExperimentData: &protoctabcacheserver.ExperimentData{
+				GlobalDomain: &protoctabcacheserver.Domain{
+					Metadata: &protoctabcacheserver.DomainMetadata{Key: "global", BucketSize: 100},
+					HoldoutDomainList: []*protoctabcacheserver.HoldoutDomain{
+						{Metadata: holdoutMetadata, LayerList: holdoutLayers},
+					},
+					MultiLayerDomainList: []*protoctabcacheserver.MultiLayerDomain{
+						{Metadata: multiMetadata1, LayerList: multiLayers1},
+						{Metadata: multiMetadata2, LayerList: multiLayers2},
+					},
+				},
+				HoldoutData: &protoctabcacheserver.HoldoutData{HoldoutLayerIndex: map[string]*protoctabcacheserver.Layer{
+					"holdout1": holdout1,
+					"holdout2": holdout2,
+				}},
+			},
+		},
+		GroupIDBucketInfoIndex: map[int64]*protoctabcacheserver.BucketInfo{
+			11: {BucketType: protoctabcacheserver.BucketType_BUCKET_TYPE_RANGE,
+				TrafficRange: &protoctabcacheserver.TrafficRange{Left: 1, Right: 50}},
+		},
+	}
+	findings := ValidateApplication(application)
+	var codes []string
+	for _, finding := range findings {
+		codes = append(codes, finding.Code)
+	}
+	want := []string{
+		FindingMissingGroup,       // group 13 of experiment 1
+		FindingMissingBucketInfo,  // group 12
+		FindingTrafficOutOfBucket, // multi2 reaches 120
+		FindingDuplicateLayerKey,  // layer1 of multi2
+		FindingTrafficOverlap,     // multi1 and multi2
+		FindingMissingHoldoutLayer,
+		FindingUnsupportedHashMethod,
+		FindingHoldoutCycle,
+	}
+	if !reflect.DeepEqual(codes, want) {
+		t.Fatalf("ValidateApplication() codes = %v, want %v", codes, want)
+	}
+	if findings[1].Severity != SeverityWarning ||
+		findings[1].Path != "domain=global/multiLayerDomain=multi1/layer=layer1/group=12" {
+		t.Errorf("unexpected finding %v", findings[1])
+	}
+	if findings[7].Message != "cycle=holdout1->holdout2->holdout1" {
+		t.Errorf("unexpected finding %v", findings[7])
+	}
+	for _, finding := range findings[2:5] { // tolerated by the evaluation
+		if finding.Severity != SeverityWarning {
+			t.Errorf("unexpected finding %v, want a warning", finding)
+		}
+	}
+	err := checkFindings(application, findings)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Version != "v1" {
+		t.Errorf("checkFindings() error = %v, want the ValidationError", err)
+	}
+	if err = checkFindings(application, findings[1:2]); err != nil {
+		t.Errorf("checkFindings() error = %v, the warnings are not fatal", err)
+	}
+	SetLenientValidation(true)
+	defer SetLenientValidation(false)
+	if err = checkFindings(application, findings); err != nil {
+		t.Errorf("checkFindings() error = %v, the lenient validation only reports the findings", err)
+	}
+}
+
+// TestValidateApplication_testdata Every fixture of testdata loads as it did before the validation, with the lenient
+// validation too. EmptyTabConfig has no experiment data, it is rejected by validateTabConfig
+func TestValidateApplication_testdata(t *testing.T) {
+	defer func() {
+		client.CacheClient = nil
+	}()
+	fixtures := []struct {
+		name      string
+		tabConfig *protoctabcacheserver.TabConfig
+		wantErr   bool
+	}{
+		{name: "EmptyTabConfig", tabConfig: testdata.EmptyTabConfig, wantErr: true},
+		{name: "NormalTabConfig", tabConfig: testdata.NormalTabConfig},
+	}
+	for _, lenient := range []bool{false, true} {
+		for _, fixture := range fixtures {
+			SetLenientValidation(lenient)
+			client.CacheClient = testdata.MockCacheClientWithData(t, fixture.tabConfig,
+				testdata.NormalExperimentBucketInfo, testdata.NormalGroupBucketInfo)
+			application, err := NewAndSetApplication(context.Background(), projectID)
+			var validationErr *ValidationError
+			if (err != nil) != fixture.wantErr || errors.As(err, &validationErr) {
+				t.Errorf("%s lenient=%v: NewAndSetApplication() error = %v, wantErr %v", fixture.name, lenient, err,
+					fixture.wantErr)
+			} else if findings := ValidateApplication(application); len(findings) != 0 {
+				t.Errorf("%s lenient=%v: ValidateApplication() = %v", fixture.name, lenient, findings)
+			}
+			Release()
+		}
+	}
+}
@@ -5,7 +5,7 @@ package config
 import (
 	"context"
 
-	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal/experiment"
 	"github.com/abetterchoice/go-sdk/plugin/log"
 	"github.com/abetterchoice/hashutil"
@@ -26,6 +26,7 @@ type Value struct {
 	IsOverrideList bool
 	IsDefault      bool
 	IsHoldout      bool
+	Reason         env.Reason                        // The reason why the value is returned
 	Experiment     *experiment.Experiment            // Configuration Binding Experiment
 	RemoteConfig   *protoc_cache_server.RemoteConfig // Remote configuration details
 	UnitIDType     protoc_cache_server.UnitIDType    // ID Account System
@@ -35,14 +36,14 @@ type Value struct {
 func (e *executor) GetRemoteConfig(ctx context.Context, projectID string, key string,
 	options *experiment.Options) (*Value,
 	error) {
-	application := cache.GetApplication(projectID)
+	application := options.GetApplication(projectID)
 	if application == nil {
-		return nil, errors.Errorf("projectID [%s] not found", projectID)
+		return nil, errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
 	}
 	options.Application = application
 	remoteConfig, ok := application.TabConfig.ConfigData.RemoteConfigIndex[key]
 	if !ok || remoteConfig == nil {
-		return nil, errors.Errorf("remoteConfig[%s] not found", key)
+		return nil, errors.Wrapf(env.ErrRemoteConfigNotFound, "remoteConfig[%s]", key)
 	}
 	return e.getRemoteConfigValue(ctx, remoteConfig, options)
 }
@@ -51,7 +52,7 @@ func (e *executor) getRemoteConfigValue(ctx context.Context, config *protoc_cach
 	options *experiment.Options) (*Value, error) {
 	data, unitIDType, ok := e.processOverrideList(config, options)
 	if ok {
-		return &Value{Data: data, IsOverrideList: true, RemoteConfig: config,
+		return &Value{Data: data, IsOverrideList: true, Reason: env.ReasonOverride, RemoteConfig: config,
 			UnitIDType: unitIDType}, nil
 	}
 	holdoutExp, err := e.checkCaughtByHoldout(ctx, config.HoldoutLayerKeys, options)
@@ -65,6 +66,7 @@ func (e *executor) getRemoteConfigValue(ctx context.Context, config *protoc_cach
 			IsOverrideList: false,
 			IsDefault:      false,
 			IsHoldout:      true,
+			Reason:         env.ReasonHoldout,
 			Experiment:     holdoutExp,
 			RemoteConfig:   config,
 			UnitIDType:     holdoutExp.UnitIdType,
@@ -83,7 +85,8 @@ func (e *executor) getRemoteConfigValue(ctx context.Context, config *protoc_cach
 			return value, err
 		}
 	}
-	return &Value{Data: config.DefaultValue, IsDefault: true, RemoteConfig: config, UnitIDType: unitIDType}, nil
+	return &Value{Data: config.DefaultValue, IsDefault: true, Reason: env.ReasonSystemDefault, RemoteConfig: config,
+		UnitIDType: unitIDType}, nil
 }
 
 func (e *executor) checkCaughtByHoldout(ctx context.Context, holdoutLayerKeys []string,
@@ -136,7 +139,7 @@ func (e *executor) processCondition(ctx context.Context, condition *protoc_cache
 	}
 	switch condition.IssueInfo.IssueType {
 	case protoc_cache_server.IssueType_ISSUE_TYPE_PERCENTAGE:
-		value, err := processConditionExperiment(ctx, condition, options)
+		value, err := processConditionExperiment(ctx, condition, options, env.ReasonSplit)
 		return value, true, err
 	case protoc_cache_server.IssueType_ISSUE_TYPE_TAG, protoc_cache_server.IssueType_ISSUE_TYPE_CITY_TAG:
 		hit, err := experiment.IsHitTag(ctx, condition.IssueInfo.TagListGroup, options)
@@ -146,16 +149,19 @@ func (e *executor) processCondition(ctx context.Context, condition *protoc_cache
 		if !hit {
 			return nil, false, nil
 		}
-		value, err := processConditionExperiment(ctx, condition, options)
+		value, err := processConditionExperiment(ctx, condition, options, env.ReasonTargetingMatch)
 		return value, true, err
 	}
 	return nil, false, nil
 }
 
+// processConditionExperiment The reason is the one of the hit condition,
+// if the value comes from the bound experiment, the reason of the experiment group is used instead
 func processConditionExperiment(ctx context.Context, condition *protoc_cache_server.Condition,
-	options *experiment.Options) (*Value, error) {
+	options *experiment.Options, reason env.Reason) (*Value, error) {
 	value := &Value{}
 	value.Data = condition.Value
+	value.Reason = reason
 	if condition.ExperimentKey == "" {
 		return value, nil
 	}
@@ -177,6 +183,9 @@ func processConditionExperiment(ctx context.Context, condition *protoc_cache_ser
 		}
 		value.Experiment = e
 		value.Data = []byte(result)
+		if e.Reason != "" {
+			value.Reason = e.Reason
+		}
 		return value, nil
 	}
 	return value, nil
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal/cache"
 	"github.com/abetterchoice/go-sdk/internal/client"
 	"github.com/abetterchoice/go-sdk/internal/experiment"
@@ -68,6 +69,7 @@ func Test_executor_GetRemoteConfig(t *testing.T) {
 				Data:           []byte("remoteConfig1-condition1"),
 				IsOverrideList: false,
 				IsDefault:      false,
+				Reason:         env.ReasonSplit,
 				RemoteConfig:   testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["remoteConfig1"],
 				UnitIDType:     protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
@@ -88,6 +90,7 @@ func Test_executor_GetRemoteConfig(t *testing.T) {
 				Data:           []byte("hitOverrideResult"),
 				IsOverrideList: true,
 				IsDefault:      false,
+				Reason:         env.ReasonOverride,
 				RemoteConfig:   testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["remoteConfig1"],
 				UnitIDType:     protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
@@ -106,6 +109,7 @@ func Test_executor_GetRemoteConfig(t *testing.T) {
 			want: &Value{
 				Data:         []byte("bitmapTestDefaultValue"),
 				IsDefault:    true,
+				Reason:       env.ReasonSystemDefault,
 				RemoteConfig: testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["bitmapTest"],
 				UnitIDType:   protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
@@ -124,6 +128,7 @@ func Test_executor_GetRemoteConfig(t *testing.T) {
 			want: &Value{
 				Data:         []byte("withTagDefaultValue"),
 				IsDefault:    true,
+				Reason:       env.ReasonSystemDefault,
 				RemoteConfig: testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["withTag"],
 				UnitIDType:   protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
@@ -145,6 +150,7 @@ func Test_executor_GetRemoteConfig(t *testing.T) {
 			want: &Value{
 				Data:         []byte("withTag-condition1"),
 				IsDefault:    false,
+				Reason:       env.ReasonTargetingMatch,
 				RemoteConfig: testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["withTag"],
 				UnitIDType:   protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
@@ -166,6 +172,7 @@ func Test_executor_GetRemoteConfig(t *testing.T) {
 			want: &Value{
 				Data:         []byte("withTagDefaultValue"),
 				IsDefault:    true,
+				Reason:       env.ReasonSystemDefault,
 				RemoteConfig: testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["withTag"],
 				UnitIDType:   protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
@@ -187,6 +194,7 @@ func Test_executor_GetRemoteConfig(t *testing.T) {
 			want: &Value{
 				Data:         []byte("withExperiment-condition1"),
 				IsDefault:    false,
+				Reason:       env.ReasonSplit,
 				RemoteConfig: testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["withExperiment"],
 				UnitIDType:   protoc_cache_server.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
@@ -356,6 +364,7 @@ func Test_processConditionExperiment(t *testing.T) {
 		ctx       context.Context
 		condition *protoc_cache_server.Condition
 		options   *experiment.Options
+		reason    env.Reason
 	}
 	tests := []struct {
 		name    string
@@ -363,11 +372,20 @@ func Test_processConditionExperiment(t *testing.T) {
 		want    *Value
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "without experiment",
+			args: args{
+				ctx:       context.TODO(),
+				condition: &protoc_cache_server.Condition{Value: []byte("value")},
+				options:   &experiment.Options{},
+				reason:    env.ReasonTargetingMatch,
+			},
+			want: &Value{Data: []byte("value"), Reason: env.ReasonTargetingMatch},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := processConditionExperiment(tt.args.ctx, tt.args.condition, tt.args.options)
+			got, err := processConditionExperiment(tt.args.ctx, tt.args.condition, tt.args.options, tt.args.reason)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("processConditionExperiment() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,202 @@
+// Package dedupe Filters suppressing the exposures repeated within a time window,
+// a bounded LRU remembering the exact keys or a rotating Bloom filter of fixed memory
+package dedupe
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// The kinds of the filter
+const (
+	KindLRU   = "lru"
+	KindBloom = "bloom"
+)
+
+// The defaults of the filters
+const (
+	DefaultCapacity          = 100000
+	DefaultFalsePositiveRate = 0.001
+)
+
+// Config The configuration of the filter
+type Config struct {
+	// Window The repeats within the window are suppressed
+	Window time.Duration `json:"window"`
+	// Kind lru or bloom, default lru
+	Kind string `json:"kind"`
+	// Capacity The number of the keys the LRU remembers at most,
+	// or the number of the keys expected within a window of the Bloom filter
+	Capacity int `json:"capacity"`
+	// FalsePositiveRate The rate of the new keys suppressed wrongly by the Bloom filter
+	FalsePositiveRate float64 `json:"falsePositiveRate"`
+}
+
+// Filter Remember the keys and tell the repeats
+type Filter interface {
+	// Seen Whether the key is seen within the window, the key is remembered if not
+	Seen(key string) bool
+}
+
+// New The filter of the config, the zero values are replaced by the defaults
+func New(config *Config) Filter {
+	capacity := config.Capacity
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	if config.Kind == KindBloom {
+		falsePositiveRate := config.FalsePositiveRate
+		if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+			falsePositiveRate = DefaultFalsePositiveRate
+		}
+		return NewBloom(config.Window, capacity, falsePositiveRate)
+	}
+	return NewLRU(config.Window, capacity)
+}
+
+// LRU The filter remembering the exact keys, the least recently seen key is forgotten
+// when the capacity is reached, so a key may be reported again within the window under heavy load.
+// A key is reported again once the window has passed since it was last reported
+type LRU struct {
+	window   time.Duration
+	capacity int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List // front is the most recently seen
+}
+
+type lruItem struct {
+	key      string
+	reported time.Time
+}
+
+// NewLRU The LRU filter of the window and the capacity
+func NewLRU(window time.Duration, capacity int) *LRU {
+	return &LRU{
+		window:   window,
+		capacity: capacity,
+		now:      time.Now,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Seen Filter
+func (l *LRU) Seen(key string) bool {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if element, ok := l.items[key]; ok {
+		item := element.Value.(*lruItem)
+		l.order.MoveToFront(element)
+		if now.Sub(item.reported) < l.window {
+			return true
+		}
+		item.reported = now
+		return false
+	}
+	l.items[key] = l.order.PushFront(&lruItem{key: key, reported: now})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+	return false
+}
+
+// Len The number of the keys remembered
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// Bloom The filter of two Bloom filters rotated every window, the keys are added to the current one
+// and looked up in both. A key is suppressed for at least the window and less than twice the window,
+// and a new key is suppressed wrongly at about the false positive rate. The memory does not grow with the keys
+type Bloom struct {
+	window time.Duration
+	bits   uint64
+	hashes int
+	now    func() time.Time
+
+	mu        sync.Mutex
+	current   []uint64
+	previous  []uint64
+	rotatedAt time.Time
+}
+
+// NewBloom The Bloom filter sized for the keys expected within a window at the false positive rate
+func NewBloom(window time.Duration, expectedKeys int, falsePositiveRate float64) *Bloom {
+	// m = -n*ln(p)/ln(2)^2, k = m/n*ln(2)
+	bits := uint64(math.Ceil(-float64(expectedKeys) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if bits < 64 {
+		bits = 64
+	}
+	hashes := int(math.Round(float64(bits) / float64(expectedKeys) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	b := &Bloom{window: window, bits: bits, hashes: hashes, now: time.Now}
+	b.current, b.previous = b.newBitset(), b.newBitset()
+	b.rotatedAt = b.now()
+	return b
+}
+
+func (b *Bloom) newBitset() []uint64 {
+	return make([]uint64, (b.bits+63)/64)
+}
+
+// Seen Filter
+func (b *Bloom) Seen(key string) bool {
+	h1, h2 := hashKey(key)
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rotate(now)
+	positions := make([]uint64, b.hashes)
+	inCurrent, inPrevious := true, true
+	for i := range positions {
+		// double hashing, the i-th position is h1 + i*h2
+		positions[i] = (h1 + uint64(i)*h2) % b.bits
+		inCurrent = inCurrent && b.current[positions[i]/64]&(1<<(positions[i]%64)) != 0
+		inPrevious = inPrevious && b.previous[positions[i]/64]&(1<<(positions[i]%64)) != 0
+	}
+	if inCurrent || inPrevious {
+		// the repeats are not added, so that a key is reported again after the window however often it is seen
+		return true
+	}
+	for _, position := range positions {
+		b.current[position/64] |= 1 << (position % 64)
+	}
+	return false
+}
+
+// rotate Drop the previous filter each window, the current one becomes the previous one
+func (b *Bloom) rotate(now time.Time) {
+	elapsed := now.Sub(b.rotatedAt)
+	if elapsed < b.window {
+		return
+	}
+	if elapsed >= 2*b.window { // both are out of the window
+		b.previous = b.newBitset()
+	} else {
+		b.previous = b.current
+	}
+	b.current = b.newBitset()
+	b.rotatedAt = now
+}
+
+func hashKey(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	h1 := h.Sum64()
+	_, _ = h.Write([]byte{0xff})
+	h2 := h.Sum64() | 1 // odd, so that the positions do not collapse
+	return h1, h2
+}
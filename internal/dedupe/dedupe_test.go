@@ -0,0 +1,68 @@
+package dedupe
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := NewLRU(time.Minute, 2)
+	l.now = func() time.Time { return now }
+	assert.False(t, l.Seen("a"))
+	assert.True(t, l.Seen("a"))
+	now = now.Add(59 * time.Second)
+	assert.True(t, l.Seen("a")) // the repeats do not extend the window
+	now = now.Add(time.Second)
+	assert.False(t, l.Seen("a"))
+	assert.True(t, l.Seen("a"))
+	// a is the least recently seen when c is added, it is forgotten
+	assert.False(t, l.Seen("b"))
+	assert.False(t, l.Seen("c"))
+	assert.Equal(t, 2, l.Len())
+	assert.False(t, l.Seen("a"))
+	assert.True(t, l.Seen("c"))
+}
+
+func TestBloom(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := NewBloom(time.Minute, 1000, 0.001)
+	b.now = func() time.Time { return now }
+	b.rotatedAt = now
+	assert.Equal(t, 10, b.hashes)
+	assert.False(t, b.Seen("a"))
+	assert.True(t, b.Seen("a"))
+	now = now.Add(time.Minute) // rotated, a is in the previous filter
+	assert.True(t, b.Seen("a"))
+	now = now.Add(time.Minute) // rotated again, a is forgotten although it is seen in the last window
+	assert.False(t, b.Seen("a"))
+	assert.True(t, b.Seen("a"))
+	now = now.Add(3 * time.Minute) // both filters are out of the window
+	assert.False(t, b.Seen("a"))
+}
+
+func TestBloom_falsePositiveRate(t *testing.T) {
+	b := NewBloom(time.Hour, 10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		b.Seen("seen" + strconv.Itoa(i))
+	}
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if b.Seen("new" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 30) // about 1%, the new keys added on the way raise it a little
+}
+
+func TestNew(t *testing.T) {
+	lru, ok := New(&Config{Window: time.Minute}).(*LRU)
+	assert.True(t, ok)
+	assert.Equal(t, DefaultCapacity, lru.capacity)
+	bloom, ok := New(&Config{Window: time.Minute, Kind: KindBloom, Capacity: 1000}).(*Bloom)
+	assert.True(t, ok)
+	assert.Equal(t, 10, bloom.hashes) // the default false positive rate 0.001
+}
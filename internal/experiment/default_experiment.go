@@ -3,7 +3,7 @@ package experiment
 import (
 	"context"
 
-	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/protoc_cache_server"
 	"github.com/pkg/errors"
 )
@@ -13,9 +13,9 @@ import (
 func (e *executor) GetDefaultExperiments(ctx context.Context, projectID string,
 	options *Options) (map[string]*Experiment,
 	error) {
-	application := cache.GetApplication(projectID)
+	application := options.GetApplication(projectID)
 	if application == nil {
-		return nil, errors.Errorf("projectID [%s] not found", projectID)
+		return nil, errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
 	}
 	options.Application = application
 	return e.getDomainDefaultExperiments(ctx, application.TabConfig.ExperimentData.GlobalDomain, options)
@@ -66,7 +66,7 @@ func (e *executor) getMultiLayerDomainDefaultExperiments(ctx context.Context,
 			if !e.isLayerFilterPass(ctx, layer, options) {
 				continue
 			}
-			result[layer.Metadata.Key] = &Experiment{Group: layer.Metadata.DefaultGroup}
+			result[layer.Metadata.Key] = &Experiment{Group: layer.Metadata.DefaultGroup, Reason: env.ReasonLayerDefault}
 		}
 	}
 	return nil
@@ -82,7 +82,7 @@ func (e *executor) getHoldoutDomainDefaultExperiments(ctx context.Context,
 			if !e.isLayerFilterPass(ctx, layer, options) {
 				continue
 			}
-			result[layer.Metadata.Key] = &Experiment{Group: layer.Metadata.DefaultGroup}
+			result[layer.Metadata.Key] = &Experiment{Group: layer.Metadata.DefaultGroup, Reason: env.ReasonLayerDefault}
 		}
 	}
 	return nil
@@ -4,11 +4,14 @@ package experiment
 import (
 	"context"
 	"strconv"
+	"time"
 
 	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal/cache"
 	"github.com/abetterchoice/go-sdk/internal/client"
+	"github.com/abetterchoice/go-sdk/internal/stats"
 	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
 	"github.com/abetterchoice/hashutil"
 	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
 	"github.com/abetterchoice/protoc_dmp_proxy_server"
@@ -26,17 +29,19 @@ var (
 // Experiment Experimental information, encapsulates the group in the protocol,
 // and adds status attributes during the diversion process
 // IsOverrideList Whether it is the experimental group hit by the whitelist
+// Reason The reason why the group is returned, such as split, targeting match or layer default
 type Experiment struct {
 	*protoccacheserver.Group // The hit experiment group, Group must be not empty
 	IsOverrideList           bool
 	HoldoutData              map[string]*Experiment
+	Reason                   env.Reason
 }
 
 // VariantKey2LayerKey Get the layer where the parameter key is located according to the parameter key
-func (e *executor) VariantKey2LayerKey(projectID, variantKey string) ([]string, error) {
-	application := cache.GetApplication(projectID)
+func (e *executor) VariantKey2LayerKey(projectID, variantKey string, options *Options) ([]string, error) {
+	application := options.GetApplication(projectID)
 	if application == nil {
-		return nil, errors.Errorf("projectID [%s] not found", projectID)
+		return nil, errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
 	}
 	return application.VariantKeyLayerMap[variantKey], nil
 }
@@ -45,7 +50,7 @@ func (e *executor) VariantKey2LayerKey(projectID, variantKey string) ([]string,
 func (e *executor) GetVariantValue(projectID, layerKey, variantKey string) ([]byte, error) {
 	application := cache.GetApplication(projectID)
 	if application == nil {
-		return nil, errors.Errorf("projectID [%s] not found", projectID)
+		return nil, errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
 	}
 	layer, ok := application.LayerIndex[layerKey]
 	if !ok {
@@ -65,9 +70,9 @@ func (e *executor) GetVariantValue(projectID, layerKey, variantKey string) ([]by
 // GetExperiments Get the set of experiment information that the user hits under the conditions specified by options
 func (e *executor) GetExperiments(ctx context.Context, projectID string, options *Options) (map[string]*Experiment,
 	error) {
-	application := cache.GetApplication(projectID)
+	application := options.GetApplication(projectID)
 	if application == nil {
-		return nil, errors.Errorf("projectID [%s] not found", projectID)
+		return nil, errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
 	}
 	err := e.fillOptions(ctx, application, options)
 	if err != nil {
@@ -95,7 +100,8 @@ func (e *executor) setOverrideGroup(ctx context.Context, application *cache.Appl
 	for layerKey, groupID := range options.OverrideList {
 		layer, ok := application.LayerIndex[layerKey]
 		if !ok {
-			log.Error("layer[%s] not found", layerKey)
+			log.ErrorContext(ctx, "override layer not found",
+				application.LogFields(log.F(log.FieldLayerKey, layerKey))...)
 			continue
 		}
 		if !e.isLayerFilterPass(ctx, layer, options) {
@@ -107,6 +113,7 @@ func (e *executor) setOverrideGroup(ctx context.Context, application *cache.Appl
 				result[layerKey] = &Experiment{
 					Group:          group,
 					IsOverrideList: true,
+					Reason:         env.ReasonOverride,
 				}
 			}
 		}
@@ -140,7 +147,7 @@ func (e *executor) checkLayerCanBeHit(ctx context.Context, application *cache.Ap
 	}
 	layer, ok = application.LayerIndex[layerKey]
 	if !ok || layer == nil {
-		return nil, errors.Errorf("invalid layerKey=%s", layerKey)
+		return nil, errors.Wrapf(env.ErrLayerNotFound, "invalid layerKey=%s", layerKey)
 	}
 	holdoutExp, err := e.checkCaughtByHoldout(ctx, application, layer, options)
 	if err != nil {
@@ -191,7 +198,8 @@ func (e *executor) checkCaughtByHoldout(ctx context.Context, application *cache.
 func isHitLayer(application *cache.Application, layerKey string, options *Options) (bool, error) {
 	domainMetadataList, ok := application.LayerDomainMetadataListIndex[layerKey]
 	if !ok {
-		log.Warnf("domainMetadata not found[invalid layerKey=%v]", layerKey)
+		log.WarnContext(context.Background(), "domainMetadata not found",
+			application.LogFields(log.F(log.FieldLayerKey, layerKey))...)
 		return false, nil
 	}
 	bucketNum := int64(0)
@@ -234,13 +242,18 @@ func (e *executor) preparedDMP(ctx context.Context, application *cache.Applicati
 				DmpPlatformCode: protoc_dmp_proxy_server.DMPPlatform(platformCode),
 				TagList:         convertMap2Array(tagSet),
 			}
+			startTime := time.Now()
 			resp, err := client.DC.BatchGetTagValue(ctx, req)
+			observeDMP(startTime, resp, err)
 			if err != nil {
-				log.Errorf("[req=%+v]BatchGetTagValue fail:%v", req, err)
+				log.ErrorContext(ctx, "BatchGetTagValue fail", application.LogFields(log.F("unitIDType", unitIDType),
+					log.F("platformCode", platformCode), log.Err(err))...)
 				continue
 			}
 			if resp.RetCode != protoc_dmp_proxy_server.RetCode_RET_CODE_SUCCESS {
-				log.Errorf("[req=%+v]BatchGetTagValue invalid code=%v, message=%v", req, resp.RetCode, resp.Message)
+				log.ErrorContext(ctx, "BatchGetTagValue invalid code", application.LogFields(
+					log.F("unitIDType", unitIDType), log.F("platformCode", platformCode), log.F("code", resp.RetCode),
+					log.F("message", resp.Message))...)
 				continue
 			}
 			if options.DMPTagValueResult == nil {
@@ -419,7 +432,7 @@ func (e *executor) getLayerExperimentWithDefault(ctx context.Context, layer *pro
 		return experiment, nil
 	}
 	if layer.Metadata.DefaultGroup != nil {
-		return &Experiment{Group: layer.Metadata.DefaultGroup}, nil
+		return &Experiment{Group: layer.Metadata.DefaultGroup, Reason: env.ReasonLayerDefault}, nil
 	}
 	if len(layer.GroupIndex) == 0 {
 		return nil, nil
@@ -433,6 +446,7 @@ func (e *executor) getLayerExperimentWithDefault(ctx context.Context, layer *pro
 			LayerKey:  layer.Metadata.Key,
 		},
 		IsOverrideList: false,
+		Reason:         env.ReasonSystemDefault,
 	}, nil
 }
 
@@ -455,7 +469,13 @@ func (e *executor) GetLayerExperiment(ctx context.Context, layer *protoccacheser
 		return nil, errors.Wrap(err, "checkCaughtByHoldout")
 	}
 	if holdoutExp != nil && !holdoutExp.IsDefault && holdoutExp.IsControl {
-		return holdoutExp, nil
+		// copy, the holdout layer result is shared by all layers in options.HoldoutLayerResult
+		return &Experiment{
+			Group:          holdoutExp.Group,
+			IsOverrideList: holdoutExp.IsOverrideList,
+			HoldoutData:    holdoutExp.HoldoutData,
+			Reason:         env.ReasonHoldout,
+		}, nil
 	}
 	switch layer.Metadata.HashType {
 	case protoccacheserver.HashType_HASH_TYPE_DOUBLE:
@@ -477,6 +497,7 @@ func (e *executor) getLayerOverrideExperiment(layer *protoccacheserver.Layer, op
 		return &Experiment{
 			Group:          group,
 			IsOverrideList: true,
+			Reason:         env.ReasonOverride,
 		}
 	}
 	return nil
@@ -501,10 +522,10 @@ func (e *executor) getSingleHashLayerExperiment(ctx context.Context, layer *prot
 				return nil, errors.Wrap(err, "isHitTag")
 			}
 			if tagFlag {
-				return &Experiment{Group: group}, nil
+				return &Experiment{Group: group, Reason: env.ReasonTargetingMatch}, nil
 			}
 		case protoccacheserver.IssueType_ISSUE_TYPE_PERCENTAGE:
-			return &Experiment{Group: group}, nil
+			return &Experiment{Group: group, Reason: env.ReasonSplit}, nil
 		}
 	}
 	return nil, nil
@@ -524,12 +545,14 @@ func IsHitTag(ctx context.Context, tagListGroup []*protoccacheserver.TagList, op
 				}
 				dmpFlagStr, err := getTagValue(ctx, tag, options)
 				if err != nil {
-					log.Errorf("[tag=%v]getTagValue fail:%v", err)
+					log.ErrorContext(ctx, "getTagValue fail",
+						options.Application.LogFields(log.F(log.FieldTagKey, tag.Key), log.Err(err))...)
 					return false, nil
 				}
 				dmpFlag, err := strconv.ParseBool(dmpFlagStr)
 				if err != nil {
-					log.Errorf("dmpFlag=%v needs to be convertible to bool type:%v", dmpFlagStr, err)
+					log.ErrorContext(ctx, "dmpFlag needs to be convertible to bool type", options.Application.LogFields(
+						log.F(log.FieldTagKey, tag.Key), log.F("dmpFlag", dmpFlagStr), log.Err(err))...)
 					return false, nil
 				}
 				if dmpFlag && tag.Operator == protoccacheserver.Operator_OPERATOR_FALSE ||
@@ -545,11 +568,13 @@ func IsHitTag(ctx context.Context, tagListGroup []*protoccacheserver.TagList, op
 				}
 				value, err := getTagValue(ctx, tag, options)
 				if err != nil {
-					log.Errorf("[tag=%v]getTagValue fail:%v", tag.Key, err)
+					log.ErrorContext(ctx, "getTagValue fail",
+						options.Application.LogFields(log.F(log.FieldTagKey, tag.Key), log.Err(err))...)
 					return false, nil
 				}
 				if v, ok := options.AttributeTag[tag.Key]; ok {
-					log.Warnf("replace tagValue[key=%s, value=%s]", tag.Key, v)
+					log.WarnContext(ctx, "replace tagValue",
+						options.Application.LogFields(log.F(log.FieldTagKey, tag.Key), log.F("value", v))...)
 				}
 				if options.AttributeTag == nil {
 					options.AttributeTag = make(map[string][]string)
@@ -568,12 +593,20 @@ func IsHitTag(ctx context.Context, tagListGroup []*protoccacheserver.TagList, op
 	return false, nil
 }
 
-func getTagValue(ctx context.Context, tag *protoccacheserver.Tag, options *Options) (string, error) {
+func getTagValue(ctx context.Context, tag *protoccacheserver.Tag, options *Options) (value string, err error) {
 	key := dmpTagResultKeyFormat(tag.UnitIdType, tag.DmpPlatform, tag.Key, options)
 	value, ok := options.DMPTagValueResult[key]
 	if ok {
 		return value, nil
 	}
+	ctx, span := trace.Start(ctx, trace.SpanGetTagValue,
+		trace.String(trace.AttributeProjectID, options.Application.ProjectID),
+		trace.String(trace.AttributeTagKey, tag.Key), trace.Int64(trace.AttributeDMPPlatform, tag.DmpPlatform))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	startTime := time.Now()
 	resp, err := client.DC.BatchGetTagValue(ctx, &protoc_dmp_proxy_server.BatchGetTagValueReq{
 		ProjectId:       options.Application.ProjectID,
 		UnitId:          options.UnitID,
@@ -582,6 +615,7 @@ func getTagValue(ctx context.Context, tag *protoccacheserver.Tag, options *Optio
 		DmpPlatformCode: protoc_dmp_proxy_server.DMPPlatform(tag.DmpPlatform),
 		TagList:         []string{tag.Key},
 	})
+	observeDMP(startTime, resp, err)
 	if err != nil {
 		return "", err
 	}
@@ -599,6 +633,14 @@ func getTagValue(ctx context.Context, tag *protoccacheserver.Tag, options *Optio
 	return value, nil
 }
 
+// observeDMP Record the DMP tag query in the runtime statistics, the invalid code is counted as an error
+func observeDMP(startTime time.Time, resp *protoc_dmp_proxy_server.BatchGetTagValueResp, err error) {
+	if err == nil && resp != nil && resp.RetCode != protoc_dmp_proxy_server.RetCode_RET_CODE_SUCCESS {
+		err = errors.Errorf("invalid code=%v", resp.RetCode)
+	}
+	stats.ObserveDMP(time.Since(startTime), err)
+}
+
 func dmpTagResultKeyFormat(unitIDType protoccacheserver.UnitIDType, dmpPlatformCode int64,
 	dmpTagKey string, options *Options) string {
 	return getUnitID(unitIDType, options) + "-" + strconv.FormatInt(dmpPlatformCode, 10) + "-" + dmpTagKey
@@ -615,7 +657,8 @@ func (e *executor) isHitGroupBucketInfo(group *protoccacheserver.Group, bucketNu
 	case protoccacheserver.BucketType_BUCKET_TYPE_BITMAP:
 		bitmap, ok := options.Application.GroupIDRoaringBitmapIndex[group.Id]
 		if !ok {
-			log.Warnf("groupID[%d] bitmap not found", group.Id)
+			log.WarnContext(context.Background(), "group bitmap not found", options.Application.LogFields(
+				log.F(log.FieldLayerKey, group.LayerKey), log.F(log.FieldGroupID, group.Id))...)
 			return false
 		}
 		return bitmap.ContainsInt(int(bucketNum))
@@ -639,7 +682,8 @@ func (e *executor) isHitExperimentBucketInfo(experiment *protoccacheserver.Exper
 	case protoccacheserver.BucketType_BUCKET_TYPE_BITMAP:
 		bitmap, ok := options.Application.ExperimentIDRoaringBitmapIndex[experiment.Id]
 		if !ok {
-			log.Warnf("experimentID[%d] bitmap not found", experiment.Id)
+			log.WarnContext(context.Background(), "experiment bitmap not found",
+				options.Application.LogFields(log.F(log.FieldExperimentID, experiment.Id))...)
 			return false
 		}
 		return bitmap.ContainsInt(int(bucketNum))
@@ -689,7 +733,7 @@ func (e *executor) getPercentageExperimentGroup(experiment *protoccacheserver.Ex
 		if !e.isHitGroupBucketInfo(group, expBucketNum, options) {
 			continue
 		}
-		return &Experiment{Group: group}, nil
+		return &Experiment{Group: group, Reason: env.ReasonSplit}, nil
 	}
 	return nil, nil
 }
@@ -715,7 +759,7 @@ func (e *executor) getTagExperimentGroup(ctx context.Context, experiment *protoc
 		if !e.isHitGroupBucketInfo(group, expBucketNum, options) {
 			continue
 		}
-		return &Experiment{Group: group}, nil
+		return &Experiment{Group: group, Reason: env.ReasonTargetingMatch}, nil
 	}
 	return nil, nil
 }
@@ -737,7 +781,7 @@ func (e *executor) getCityTagExperimentGroup(ctx context.Context, experiment *pr
 		if !e.isHitGroupBucketInfo(group, expBucketNum, options) {
 			return nil, nil
 		}
-		return &Experiment{Group: group}, nil
+		return &Experiment{Group: group, Reason: env.ReasonTargetingMatch}, nil
 	}
 	return nil, nil
 }
@@ -82,6 +82,7 @@ func Test_executor_GetExperiments(t *testing.T) {
 						},
 						UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 					},
+					Reason: env.ReasonSplit,
 				},
 			},
 			wantErr: false,
@@ -162,6 +163,7 @@ func Test_executor_GetExperiments(t *testing.T) {
 						UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 					},
 					IsOverrideList: true,
+					Reason:         env.ReasonOverride,
 				},
 			},
 			wantErr: false,
@@ -246,6 +248,7 @@ var (
 				SceneIdList: nil,
 				UnitIdType:  protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonTargetingMatch,
 		},
 		"doubleHashLayerPercentage": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -260,6 +263,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonSplit,
 		},
 		"doubleHashLayerTag": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -288,6 +292,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonTargetingMatch,
 		},
 		"multiLayer2": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -305,6 +310,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonSplit,
 		},
 		"overrideLayer": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -323,6 +329,7 @@ var (
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
 			IsOverrideList: true,
+			Reason:         env.ReasonOverride,
 		},
 		"subDomain-holdoutDomain1-singleLayer": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -340,6 +347,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonSplit,
 		},
 		"subDomain-multiDomain1-multiLayer1": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -357,6 +365,7 @@ var (
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
 			IsOverrideList: true,
+			Reason:         env.ReasonOverride,
 		},
 		"doubleHashLayerTDMPagValue": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -387,6 +396,7 @@ var (
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
 			IsOverrideList: false,
+			Reason:         env.ReasonTargetingMatch,
 		},
 	}
 	// decisionID is 123, hash hit subDomain-multiDomain
@@ -419,6 +429,7 @@ var (
 				SceneIdList: nil,
 				UnitIdType:  protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonTargetingMatch,
 		},
 		"doubleHashLayerPercentage": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -433,6 +444,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonSplit,
 		},
 		"doubleHashLayerTag": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -461,6 +473,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonTargetingMatch,
 		},
 		"multiLayer2": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -469,6 +482,7 @@ var (
 				IsDefault: true,
 				LayerKey:  "multiLayer2",
 			},
+			Reason: env.ReasonSystemDefault,
 		},
 		"overrideLayer": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -486,6 +500,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonLayerDefault,
 		},
 		"subDomain-multiDomain1-multiLayer1": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -503,6 +518,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonLayerDefault,
 		},
 		"subDomain-multiDomain1-multiLayer2": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -520,6 +536,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonLayerDefault,
 		},
 		"doubleHashLayerTDMPagValue": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -550,6 +567,7 @@ var (
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
 			IsOverrideList: false,
+			Reason:         env.ReasonTargetingMatch,
 		},
 	}
 	globalAbtestResultTagValue = map[string]*Experiment{
@@ -581,6 +599,7 @@ var (
 				SceneIdList: nil,
 				UnitIdType:  protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonTargetingMatch,
 		},
 		"doubleHashLayerPercentage": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -595,6 +614,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonSplit,
 		},
 		"doubleHashLayerTag": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -623,6 +643,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonTargetingMatch,
 		},
 		"multiLayer2": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -640,6 +661,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonSplit,
 		},
 		"overrideLayer": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -658,6 +680,7 @@ var (
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
 			IsOverrideList: true,
+			Reason:         env.ReasonOverride,
 		},
 		"subDomain-holdoutDomain1-singleLayer": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -675,6 +698,7 @@ var (
 				},
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
+			Reason: env.ReasonSplit,
 		},
 		"subDomain-multiDomain1-multiLayer1": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -692,6 +716,7 @@ var (
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
 			IsOverrideList: true,
+			Reason:         env.ReasonOverride,
 		},
 		"doubleHashLayerTDMPagValue": &Experiment{
 			Group: &protoccacheserver.Group{
@@ -722,6 +747,7 @@ var (
 				UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 			},
 			IsOverrideList: false,
+			Reason:         env.ReasonTargetingMatch,
 		},
 	}
 )
@@ -789,6 +815,7 @@ func Test_executor_GetExperiments3(t *testing.T) {
 						},
 						UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 					},
+					Reason: env.ReasonTargetingMatch,
 				},
 			},
 		},
@@ -871,6 +898,7 @@ func Test_executor_GetExperiments2(t *testing.T) {
 						},
 						UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 					},
+					Reason: env.ReasonTargetingMatch,
 				},
 			},
 		},
@@ -948,6 +976,7 @@ func Test_executor_GetExperiments1(t *testing.T) {
 						},
 						UnitIdType: protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 					},
+					Reason: env.ReasonTargetingMatch,
 				},
 			},
 		},
@@ -48,8 +48,26 @@ type Options struct {
 	// NewDecisionID will be used as the input of hashing. The same NewUnitID and the same NewDecisionID
 	// will stably hit the same experimental group.
 	NewDecisionID string `json:"newDecisionId,omitempty"`
-	// Cache data snapshot
+	// Cache data snapshot. If it is set before the evaluation, the evaluation is served from it
+	// instead of the current local cache, see GetApplication
 	Application *cache.Application `json:"-"`
 	// The result of the holdout layer hit. If it is nil, it means that it is not held out.
 	HoldoutLayerResult map[string]*Experiment `json:"-"`
+	// Whether the caller supplied a fallback value. If set, when the project is not loaded, the key does not exist
+	// or the evaluation fails, the fallback value is returned instead of an error
+	HasFallbackValue bool `json:"hasFallbackValue,omitempty"`
+	// The caller-supplied fallback value of remote configuration
+	FallbackValue []byte `json:"-"`
+	// The caller-supplied fallback layer params. If not nil, when the project is not loaded, the layer does not exist
+	// or the evaluation fails, a group carrying these params is returned instead of an error
+	FallbackParams map[string]string `json:"-"`
+}
+
+// GetApplication The cache data snapshot of the options if it is of the project,
+// otherwise the current local cache of the project
+func (o *Options) GetApplication(projectID string) *cache.Application {
+	if o != nil && o.Application != nil && o.Application.ProjectID == projectID {
+		return o.Application
+	}
+	return cache.GetApplication(projectID)
 }
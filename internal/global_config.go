@@ -2,7 +2,10 @@
 package internal
 
 import (
+	"time"
+
 	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/dedupe"
 	"github.com/abetterchoice/protoc_cache_server"
 )
 
@@ -31,6 +34,106 @@ type GlobalConfig struct {
 	RegionCode string `json:"regionCode"`
 	// secretKey, used for authentication
 	SecretKey string `json:"secretKey"`
+	// The repeated exposures within the window are suppressed, nil means every exposure is reported
+	ExposureDedupe *dedupe.Config `json:"exposureDedupe,omitempty"`
+	// The exposures are batched per metrics destination, nil means each exposure is handed to the plugin at once
+	ExposureBatch *ExposureBatchConfig `json:"exposureBatch,omitempty"`
+	// The buffer sizes, the worker count and the overflow policies of the exposure channels,
+	// nil means the defaults
+	ExposurePipeline *ExposurePipelineConfig `json:"exposurePipeline,omitempty"`
+	// The exposures whose delivery failed are spooled on disk and replayed, nil means they are lost
+	ExposureSpool *ExposureSpoolConfig `json:"exposureSpool,omitempty"`
+	// The sampling mode of the metrics tables whose expanded data does not select one, empty means random
+	SamplingMode string `json:"samplingMode,omitempty"`
+	// The hashing and the redaction of the user identifiers and the expanded data before they are reported,
+	// nil means they are reported as passed in
+	Privacy *PrivacyConfig `json:"privacy,omitempty"`
+	// The versions of the local cache data kept per project, the served one included, 0 means 1
+	VersionHistory int `json:"versionHistory,omitempty"`
+	// The check of the versions of the local cache data before and while they are served,
+	// nil means no automatic rollback
+	VersionCheck func(projectID string, version string) error `json:"-"`
+	// Whether a version of the local cache data with a fatal finding of the validation is applied
+	// instead of being rejected
+	LenientValidation bool `json:"lenientValidation,omitempty"`
+}
+
+// PrivacyConfig The privacy policy of the reported records
+type PrivacyConfig struct {
+	// HashKey The key of the HMAC-SHA256 of the unitID, the decisionID and the newUnitID, empty means no hashing.
+	// It is not exported by GetGlobalConfig
+	HashKey []byte `json:"-"`
+	// ExtraDataAllowlist The only keys of the expanded data reported, empty means all
+	ExtraDataAllowlist map[string]bool `json:"extraDataAllowlist,omitempty"`
+	// ExtraDataDenylist The keys of the expanded data not reported
+	ExtraDataDenylist map[string]bool `json:"extraDataDenylist,omitempty"`
+	// MaxExtraDataKeys The keys of the expanded data reported at most, the first ones in order are kept,
+	// 0 means no limit
+	MaxExtraDataKeys int `json:"maxExtraDataKeys,omitempty"`
+	// MaxValueLength The characters of a value of the expanded data reported at most, the rest are truncated,
+	// 0 means no limit
+	MaxValueLength int `json:"maxValueLength,omitempty"`
+}
+
+// ExposureSpoolConfig The spool directory of the exposures whose delivery failed
+type ExposureSpoolConfig struct {
+	// Dir The spool directory
+	Dir string `json:"dir"`
+	// MaxBytes The size cap of the spool, the oldest segments are evicted beyond it
+	MaxBytes int64 `json:"maxBytes"`
+	// SegmentBytes The size a segment file is closed at
+	SegmentBytes int64 `json:"segmentBytes"`
+	// MinBackoff The interval of the replay, it is doubled after each failed replay up to MaxBackoff
+	MinBackoff time.Duration `json:"minBackoff"`
+	// MaxBackoff The max interval of the replay
+	MaxBackoff time.Duration `json:"maxBackoff"`
+}
+
+// ExposureBatchConfig The batching of the exposures
+type ExposureBatchConfig struct {
+	// Size The batch is sent when it reaches the number of records
+	Size int `json:"size"`
+	// Interval The batch is sent when the interval has passed since its first record
+	Interval time.Duration `json:"interval"`
+}
+
+// OverflowPolicy What an exposure channel does with a record when it is full
+type OverflowPolicy string
+
+// The overflow policies
+const (
+	// OverflowDropNewest Drop the record being added, the default
+	OverflowDropNewest OverflowPolicy = "dropNewest"
+	// OverflowDropOldest Drop the oldest record waiting in the channel to make room
+	OverflowDropOldest OverflowPolicy = "dropOldest"
+	// OverflowBlock Block the caller until there is room or the block timeout passes, then drop the record
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// ExposurePipelineConfig The exposure pipeline between the evaluations and the metrics plugins
+type ExposurePipelineConfig struct {
+	// Workers The number of the goroutines reporting the records, 0 means max(GOMAXPROCS, 4)
+	Workers int `json:"workers"`
+	// ExperimentExposure The channel of the experiment exposures
+	ExperimentExposure ExposureChannelConfig `json:"experimentExposure"`
+	// ExperimentEvent The channel of the experiment monitor events
+	ExperimentEvent ExposureChannelConfig `json:"experimentEvent"`
+	// RemoteConfigExposure The channel of the remote config exposures
+	RemoteConfigExposure ExposureChannelConfig `json:"remoteConfigExposure"`
+	// RemoteConfigEvent The channel of the remote config monitor events
+	RemoteConfigEvent ExposureChannelConfig `json:"remoteConfigEvent"`
+	// TrackEvent The channel of the conversions and the custom events of Track
+	TrackEvent ExposureChannelConfig `json:"trackEvent"`
+}
+
+// ExposureChannelConfig The configuration of an exposure channel
+type ExposureChannelConfig struct {
+	// Size The buffer size, 0 means the default 1<<19
+	Size int `json:"size"`
+	// Overflow The overflow policy, empty means OverflowDropNewest
+	Overflow OverflowPolicy `json:"overflow"`
+	// BlockTimeout How long OverflowBlock blocks the caller at most
+	BlockTimeout time.Duration `json:"blockTimeout"`
 }
 
 // C global configuration related instances, no need to lock,
@@ -0,0 +1,295 @@
+// Package recorder Record the evaluation results of abc.Context within a request,
+// used by the middleware to echo the assignments and to defer the automatic exposure until the request finishes
+package recorder
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/experiment"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+)
+
+type contextKey struct{}
+
+// Identity The identity the abc.Context is built from, forwarded to the downstream services
+type Identity struct {
+	UnitID        string
+	DecisionID    string
+	NewUnitID     string
+	NewDecisionID string
+	Tags          map[string][]string
+}
+
+// Context Wrap abc.Context and record the experiment groups assigned during the request.
+// If the exposure is deferred, the automatic exposure of every evaluation is turned off and
+// logged by Flush once the request finishes successfully, or dropped by Discard otherwise.
+type Context struct {
+	abc.Context
+
+	identity        Identity
+	isDeferExposure bool
+
+	mu          sync.Mutex
+	assignments map[string]string // layerKey -> groupKey
+	exposures   []func(ctx context.Context) error
+}
+
+// New Build the abc.Context of identity and wrap it into the recording context,
+// attributions are applied after the identity
+func New(identity Identity, isDeferExposure bool, attributions ...abc.Attribution) *Context {
+	var opts = []abc.Attribution{abc.WithTags(identity.Tags)}
+	if identity.DecisionID != "" {
+		opts = append(opts, abc.WithDecisionID(identity.DecisionID))
+	}
+	if identity.NewUnitID != "" {
+		opts = append(opts, abc.WithNewUnitID(identity.NewUnitID))
+	}
+	if identity.NewDecisionID != "" {
+		opts = append(opts, abc.WithNewDecisionID(identity.NewDecisionID))
+	}
+	opts = append(opts, attributions...)
+	return &Context{
+		Context:         abc.NewUserContext(identity.UnitID, opts...),
+		identity:        identity,
+		isDeferExposure: isDeferExposure,
+		assignments:     map[string]string{},
+	}
+}
+
+// NewContext Store the recording context in ctx, it is also attached as the user context of abc.FromContext
+func NewContext(ctx context.Context, c *Context) context.Context {
+	return abc.NewContext(context.WithValue(ctx, contextKey{}, c), c)
+}
+
+// FromContext The recording context stored in ctx, nil if absent
+func FromContext(ctx context.Context) *Context {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(contextKey{}).(*Context)
+	return c
+}
+
+// Identity The identity the abc.Context is built from
+func (c *Context) Identity() Identity {
+	return c.identity
+}
+
+// Inherit Record the assignments made by the upstream services, the groups assigned locally take precedence
+func (c *Context) Inherit(assignments map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for layerKey, groupKey := range assignments {
+		if _, ok := c.assignments[layerKey]; !ok {
+			c.assignments[layerKey] = groupKey
+		}
+	}
+}
+
+// GetExperiment Record the assigned group, the automatic exposure is deferred if enabled
+func (c *Context) GetExperiment(ctx context.Context, projectID string, layerKey string,
+	opts ...abc.ExperimentOption) (*abc.ExperimentResult, error) {
+	isDeferred := c.isDeferred(opts)
+	if isDeferred {
+		opts = append(opts, abc.WithAutomatic(false))
+	}
+	result, err := c.Context.GetExperiment(ctx, projectID, layerKey, opts...)
+	if err != nil || result == nil || result.Group == nil {
+		return result, err
+	}
+	c.recordGroup(result.Group)
+	if isDeferred && !env.IsFallbackReason(result.Reason) {
+		c.deferExposure(func(ctx context.Context) error {
+			return abc.LogExperimentExposure(ctx, projectID, result)
+		})
+	}
+	return result, nil
+}
+
+// GetExperiments Record the assigned groups, the automatic exposure is deferred if enabled
+func (c *Context) GetExperiments(ctx context.Context, projectID string,
+	opts ...abc.ExperimentOption) (*abc.ExperimentList, error) {
+	isDeferred := c.isDeferred(opts)
+	if isDeferred {
+		opts = append(opts, abc.WithAutomatic(false))
+	}
+	result, err := c.Context.GetExperiments(ctx, projectID, opts...)
+	if err != nil || result == nil {
+		return result, err
+	}
+	isFallback := false
+	for _, group := range result.Data {
+		if group == nil {
+			continue
+		}
+		c.recordGroup(group)
+		isFallback = isFallback || env.IsFallbackReason(group.Reason)
+	}
+	if isDeferred && !isFallback {
+		c.deferExposure(func(ctx context.Context) error {
+			return abc.LogExperimentsExposure(ctx, projectID, result)
+		})
+	}
+	return result, nil
+}
+
+// GetFeatureFlag Record the group of the bound experiment, the automatic exposure is deferred if enabled
+func (c *Context) GetFeatureFlag(ctx context.Context, projectID string, key string,
+	opts ...abc.ConfigOption) (*abc.FeatureFlag, error) {
+	isDeferred := c.isDeferred(opts)
+	if isDeferred {
+		opts = append(opts, abc.WithAutomatic(false))
+	}
+	result, err := c.Context.GetFeatureFlag(ctx, projectID, key, opts...)
+	if err != nil || result == nil || result.ConfigResult == nil || result.Config == nil {
+		return result, err
+	}
+	c.recordGroup(result.Experiment)
+	if isDeferred && !env.IsFallbackReason(result.Reason) {
+		c.deferExposure(func(ctx context.Context) error {
+			return abc.LogFeatureFlagExposure(ctx, projectID, result)
+		})
+	}
+	return result, nil
+}
+
+// GetRemoteConfig Record the group of the bound experiment, the automatic exposure is deferred if enabled
+func (c *Context) GetRemoteConfig(ctx context.Context, projectID string, key string,
+	opts ...abc.ConfigOption) (*abc.ConfigResult, error) {
+	isDeferred := c.isDeferred(opts)
+	if isDeferred {
+		opts = append(opts, abc.WithAutomatic(false))
+	}
+	result, err := c.Context.GetRemoteConfig(ctx, projectID, key, opts...)
+	if err != nil || result == nil || result.Config == nil {
+		return result, err
+	}
+	c.recordGroup(result.Experiment)
+	if isDeferred && !env.IsFallbackReason(result.Reason) {
+		c.deferExposure(func(ctx context.Context) error {
+			return abc.LogRemoteConfigExposure(ctx, projectID, result)
+		})
+	}
+	return result, nil
+}
+
+// GetValueByVariantKey Record the assigned group. The result can not be exposed afterwards,
+// so the exposure of GetValueByVariantKey is never deferred
+func (c *Context) GetValueByVariantKey(ctx context.Context, projectID string, key string,
+	opts ...abc.ExperimentOption) (*abc.ValueResult, error) {
+	result, err := c.Context.GetValueByVariantKey(ctx, projectID, key, opts...)
+	if err != nil || result == nil || result.Detail == nil {
+		return result, err
+	}
+	if result.Detail.LayerKey != "" && result.Detail.GroupKey != "" {
+		c.mu.Lock()
+		c.assignments[result.Detail.LayerKey] = result.Detail.GroupKey
+		c.mu.Unlock()
+	}
+	return result, nil
+}
+
+// Assignments The assigned groups in the form of layerKey1=groupKey1;layerKey2=groupKey2, sorted by layerKey.
+// The keys are url-escaped, so a layerKey or groupKey containing ';' or '=' survives ParseAssignments
+func (c *Context) Assignments() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var layerKeys = make([]string, 0, len(c.assignments))
+	for layerKey := range c.assignments {
+		layerKeys = append(layerKeys, layerKey)
+	}
+	sort.Strings(layerKeys)
+	var builder strings.Builder
+	for i, layerKey := range layerKeys {
+		if i > 0 {
+			builder.WriteString(";")
+		}
+		builder.WriteString(url.QueryEscape(layerKey))
+		builder.WriteString("=")
+		builder.WriteString(url.QueryEscape(c.assignments[layerKey]))
+	}
+	return builder.String()
+}
+
+// ParseAssignments Parse the assignments in the form returned by Assignments, malformed items are skipped
+func ParseAssignments(assignments string) map[string]string {
+	var result = make(map[string]string)
+	for _, item := range strings.Split(assignments, ";") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		layerKey, err := url.QueryUnescape(kv[0])
+		if err != nil || layerKey == "" {
+			continue
+		}
+		groupKey, err := url.QueryUnescape(kv[1])
+		if err != nil || groupKey == "" {
+			continue
+		}
+		result[layerKey] = groupKey
+	}
+	return result
+}
+
+// Flush Log the deferred exposures, the first error is returned after all of them are logged
+func (c *Context) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	exposures := c.exposures
+	c.exposures = nil
+	c.mu.Unlock()
+	var firstErr error
+	for _, exposure := range exposures {
+		err := exposure(ctx)
+		if err != nil {
+			log.Errorf("deferred exposure fail:%v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Discard Drop the deferred exposures
+func (c *Context) Discard() {
+	c.mu.Lock()
+	c.exposures = nil
+	c.mu.Unlock()
+}
+
+// isDeferred Whether the automatic exposure of the evaluation with opts is deferred,
+// the evaluation that turns off the automatic exposure by itself is left as it is
+func (c *Context) isDeferred(opts []abc.ExperimentOption) bool {
+	if !c.isDeferExposure {
+		return false
+	}
+	options := experiment.Options{IsExposureLoggingAutomatic: true}
+	for _, opt := range opts {
+		if opt(&options) != nil {
+			return false
+		}
+	}
+	return options.IsExposureLoggingAutomatic
+}
+
+func (c *Context) recordGroup(group *abc.Group) {
+	if group == nil || group.LayerKey == "" || group.Key == "" {
+		return
+	}
+	c.mu.Lock()
+	c.assignments[group.LayerKey] = group.Key
+	c.mu.Unlock()
+}
+
+func (c *Context) deferExposure(exposure func(ctx context.Context) error) {
+	c.mu.Lock()
+	c.exposures = append(c.exposures, exposure)
+	c.mu.Unlock()
+}
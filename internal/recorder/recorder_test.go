@@ -0,0 +1,114 @@
+package recorder
+
+import (
+	"context"
+	"testing"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+var projectID = "123"
+
+func TestContext(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	defer abc.Release()
+	tests := []struct {
+		name            string
+		isDeferExposure bool
+		projectID       string
+		opts            []abc.ExperimentOption
+		wantExposures   int
+		wantAssignments string
+	}{
+		{
+			name:            "exposure deferred",
+			isDeferExposure: true,
+			projectID:       projectID,
+			wantExposures:   1,
+			wantAssignments: "overrideLayer=100003001",
+		},
+		{
+			name:            "exposure not deferred",
+			isDeferExposure: false,
+			projectID:       projectID,
+			wantExposures:   0,
+			wantAssignments: "overrideLayer=100003001",
+		},
+		{
+			name:            "automatic exposure turned off",
+			isDeferExposure: true,
+			projectID:       projectID,
+			opts:            []abc.ExperimentOption{abc.WithAutomatic(false)},
+			wantExposures:   0,
+			wantAssignments: "overrideLayer=100003001",
+		},
+		{
+			name:            "fallback",
+			isDeferExposure: true,
+			projectID:       "emptyProjectID",
+			opts:            []abc.ExperimentOption{abc.WithFallbackParams(map[string]string{"key1": "1"})},
+			wantExposures:   0,
+			wantAssignments: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Identity{UnitID: "unitID"}, tt.isDeferExposure)
+			result, err := c.GetExperiment(context.TODO(), tt.projectID, "overrideLayer", tt.opts...)
+			assert.Nil(t, err)
+			assert.NotNil(t, result)
+			assert.Equal(t, tt.wantExposures, len(c.exposures))
+			assert.Equal(t, tt.wantAssignments, c.Assignments())
+			assert.Nil(t, c.Flush(context.TODO()))
+			assert.Equal(t, 0, len(c.exposures))
+		})
+	}
+}
+
+func TestContext_Discard(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	defer abc.Release()
+	c := New(Identity{UnitID: "unitID"}, true)
+	_, err = c.GetRemoteConfig(context.TODO(), projectID, "remoteConfig1")
+	assert.Nil(t, err)
+	_, err = c.GetExperiments(context.TODO(), projectID, abc.WithLayerKey("overrideLayer"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(c.exposures))
+	c.Discard()
+	assert.Equal(t, 0, len(c.exposures))
+}
+
+func TestContext_Inherit(t *testing.T) {
+	c := New(Identity{UnitID: "unitID"}, false)
+	c.recordGroup(&abc.Group{LayerKey: "layer1", Key: "group1"})
+	c.Inherit(ParseAssignments("layer1=upstream1;layer2=upstream2;invalid;=group;layer3="))
+	assert.Equal(t, "layer1=group1;layer2=upstream2", c.Assignments())
+	assert.Equal(t, map[string]string{"layer1": "group1", "layer2": "upstream2"}, ParseAssignments(c.Assignments()))
+	assert.Equal(t, map[string]string{}, ParseAssignments(""))
+}
+
+func TestContext_escapedAssignments(t *testing.T) {
+	c := New(Identity{UnitID: "unitID"}, false)
+	c.Inherit(map[string]string{"layer;1": "group=1", "layer=2": "group;2", "layer 3": "group%3"})
+	assert.Equal(t, "layer+3=group%253;layer%3B1=group%3D1;layer%3D2=group%3B2", c.Assignments())
+	assert.Equal(t, map[string]string{"layer;1": "group=1", "layer=2": "group;2", "layer 3": "group%3"},
+		ParseAssignments(c.Assignments()))
+	// items that are not valid escapes are skipped
+	assert.Equal(t, map[string]string{"layer": "group"}, ParseAssignments("layer%zz=group;layer=group;bad=%zz"))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+	c := New(Identity{UnitID: "unitID", Tags: map[string][]string{"key": {"value"}}}, false)
+	got := FromContext(NewContext(context.Background(), c))
+	assert.Equal(t, c, got)
+	assert.Equal(t, "unitID", got.Identity().UnitID)
+}
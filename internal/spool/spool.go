@@ -0,0 +1,340 @@
+// Package spool A write-ahead spool directory of the records whose delivery failed.
+// The records are appended to segment files, each record is prefixed with its length and its CRC32,
+// and the segments are replayed oldest first. When the spool exceeds its size cap
+// the oldest segments are evicted, so that a long outage does not fill up the disk
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// The defaults of the spool
+const (
+	DefaultMaxBytes     = 1 << 30
+	DefaultSegmentBytes = 16 << 20
+)
+
+const (
+	segmentExt = ".spool"
+	headerSize = 8 // the length and the CRC32 of the record, big endian
+)
+
+// Spool The spool of a directory, it is safe for concurrent use
+type Spool struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+
+	mu       sync.Mutex
+	segments []*segment // oldest first, the last one is being appended
+	file     *os.File   // the file of the last segment
+	nextSeq  uint64
+	onEvict  func(records int) // called under the lock
+}
+
+type segment struct {
+	path string
+	size int64
+}
+
+// Option The option of the spool
+type Option func(s *Spool)
+
+// WithMaxBytes The size cap of the spool, the oldest segments are evicted beyond it. Default 1GB
+func WithMaxBytes(maxBytes int64) Option {
+	return func(s *Spool) {
+		s.maxBytes = maxBytes
+	}
+}
+
+// WithSegmentBytes The size a segment is closed at, only the closed segments are evicted. Default 16MB
+func WithSegmentBytes(segmentBytes int64) Option {
+	return func(s *Spool) {
+		s.segmentBytes = segmentBytes
+	}
+}
+
+// WithOnEvict Called with the number of the records lost when a segment is evicted
+func WithOnEvict(onEvict func(records int)) Option {
+	return func(s *Spool) {
+		s.onEvict = onEvict
+	}
+}
+
+// Open Open the spool of the directory, the segments left by the previous process are kept for replay
+func Open(dir string, opts ...Option) (*Spool, error) {
+	s := &Spool{dir: dir, maxBytes: DefaultMaxBytes, segmentBytes: DefaultSegmentBytes, nextSeq: 1}
+	for _, opt := range opts {
+		opt(s)
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "make dir")
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, errors.Wrap(err, "glob segments")
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil { // not a segment
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "stat segment")
+		}
+		s.segments = append(s.segments, &segment{path: path, size: info.Size()})
+		s.nextSeq = seq + 1
+	}
+	return s, nil
+}
+
+// Size The bytes of the records in the spool
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var size int64
+	for _, seg := range s.segments {
+		size += seg.size
+	}
+	return size
+}
+
+// Append Append the record durably to the last segment, the oldest segments are evicted beyond the size cap
+func (s *Spool) Append(record []byte) error {
+	encoded := encodeRecord(record)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.openSegment()
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(encoded)
+	if err != nil {
+		return errors.Wrap(err, "write segment")
+	}
+	err = s.file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "sync segment")
+	}
+	last := s.segments[len(s.segments)-1]
+	last.size += int64(len(encoded))
+	if last.size >= s.segmentBytes {
+		err = s.closeSegment()
+		if err != nil {
+			return err
+		}
+	}
+	return s.evict()
+}
+
+// Replay Hand the records to handle oldest first. It stops at the first error of handle,
+// the records not handled yet are kept for the next replay. The number of the records handled is returned
+func (s *Spool) Replay(handle func(record []byte) error) (int, error) {
+	handled := 0
+	for {
+		seg, err := s.oldestClosed()
+		if err != nil || seg == nil {
+			return handled, err
+		}
+		n, err := s.replaySegment(seg, handle)
+		handled += n
+		if err != nil {
+			return handled, err
+		}
+	}
+}
+
+// Close Close the segment being appended, the records are kept for the next Open
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeSegment()
+}
+
+// oldestClosed The oldest segment, the last segment is closed first if it is the only one
+func (s *Spool) oldestClosed() (*segment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.segments) == 0 {
+		return nil, nil
+	}
+	if len(s.segments) == 1 && s.file != nil {
+		err := s.closeSegment()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.segments[0], nil
+}
+
+func (s *Spool) replaySegment(seg *segment, handle func(record []byte) error) (int, error) {
+	records, err := readSegment(seg.path)
+	if err != nil {
+		return 0, err
+	}
+	for i, record := range records {
+		err = handle(record)
+		if err == nil {
+			continue
+		}
+		rewriteErr := s.rewriteSegment(seg, records[i:])
+		if rewriteErr != nil {
+			return i, rewriteErr
+		}
+		return i, err
+	}
+	return len(records), s.removeSegment(seg)
+}
+
+// rewriteSegment Keep only the records not handled yet in the segment
+func (s *Spool) rewriteSegment(seg *segment, records [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.contains(seg) { // evicted during the replay
+		return nil
+	}
+	var size int64
+	tmp, err := ioutil.TempFile(s.dir, "rewrite-*")
+	if err != nil {
+		return errors.Wrap(err, "create temp")
+	}
+	writer := bufio.NewWriter(tmp)
+	for _, record := range records {
+		encoded := encodeRecord(record)
+		_, _ = writer.Write(encoded) // the error is kept by the writer and returned by Flush
+		size += int64(len(encoded))
+	}
+	err = writer.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), seg.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrap(err, "rewrite segment")
+	}
+	seg.size = size
+	return nil
+}
+
+func (s *Spool) removeSegment(seg *segment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.contains(seg) {
+		return nil
+	}
+	err := os.Remove(seg.path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "remove segment")
+	}
+	s.segments = s.segments[1:]
+	return nil
+}
+
+func (s *Spool) contains(seg *segment) bool {
+	return len(s.segments) != 0 && s.segments[0] == seg
+}
+
+// openSegment Open a new last segment if the last one is closed
+func (s *Spool) openSegment() error {
+	if s.file != nil {
+		return nil
+	}
+	// padded, so that the names sort in order
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d", s.nextSeq)+segmentExt)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "open segment")
+	}
+	s.file = file
+	s.nextSeq++
+	s.segments = append(s.segments, &segment{path: path})
+	return nil
+}
+
+func (s *Spool) closeSegment() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return errors.Wrap(err, "close segment")
+}
+
+// evict Remove the oldest closed segments beyond the size cap
+func (s *Spool) evict() error {
+	var size int64
+	for _, seg := range s.segments {
+		size += seg.size
+	}
+	for size > s.maxBytes && len(s.segments) > 1 {
+		oldest := s.segments[0]
+		if s.onEvict != nil {
+			records, _ := readSegment(oldest.path)
+			s.onEvict(len(records))
+		}
+		err := os.Remove(oldest.path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "remove segment")
+		}
+		s.segments = s.segments[1:]
+		size -= oldest.size
+	}
+	return nil
+}
+
+// encodeRecord The record prefixed with its length and its CRC32
+func encodeRecord(record []byte) []byte {
+	encoded := make([]byte, headerSize+len(record))
+	binary.BigEndian.PutUint32(encoded[:4], uint32(len(record)))
+	binary.BigEndian.PutUint32(encoded[4:headerSize], crc32.ChecksumIEEE(record))
+	copy(encoded[headerSize:], record)
+	return encoded
+}
+
+// readSegment The records of the segment, a torn or corrupted tail left by a crash is ignored
+func readSegment(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open segment")
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var records [][]byte
+	for {
+		var header [headerSize]byte
+		_, err = io.ReadFull(reader, header[:])
+		if err != nil {
+			break
+		}
+		record := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		_, err = io.ReadFull(reader, record)
+		if err != nil || crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+			break
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
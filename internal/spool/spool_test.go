@@ -0,0 +1,90 @@
+package spool
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func replayAll(t *testing.T, s *Spool) []string {
+	var records []string
+	n, err := s.Replay(func(record []byte) error {
+		records = append(records, string(record))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(records), n)
+	return records
+}
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, WithSegmentBytes(30))
+	assert.Nil(t, err)
+	for i := 0; i < 5; i++ { // 8 bytes of header and 11 bytes of record, 2 records per segment
+		assert.Nil(t, s.Append([]byte("record-000"+strconv.Itoa(i))))
+	}
+	assert.Equal(t, int64(5*19), s.Size())
+	paths, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	assert.Equal(t, 3, len(paths))
+
+	// stop at the failure, the records not handled yet are kept
+	var handled []string
+	n, err := s.Replay(func(record []byte) error {
+		if string(record) == "record-0003" {
+			return errors.New("unavailable")
+		}
+		handled = append(handled, string(record))
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"record-0000", "record-0001", "record-0002"}, handled)
+	assert.Equal(t, int64(2*19), s.Size())
+
+	// the records survive the restart
+	assert.Nil(t, s.Append([]byte("record-0005")))
+	assert.Nil(t, s.Close())
+	s, err = Open(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Append([]byte("record-0006")))
+	assert.Equal(t, []string{"record-0003", "record-0004", "record-0005", "record-0006"}, replayAll(t, s))
+	assert.Equal(t, int64(0), s.Size())
+	assert.Empty(t, replayAll(t, s))
+}
+
+func TestSpool_evict(t *testing.T) {
+	evicted := 0
+	s, err := Open(t.TempDir(), WithSegmentBytes(38), WithMaxBytes(85),
+		WithOnEvict(func(records int) { evicted += records }))
+	assert.Nil(t, err)
+	defer s.Close()
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, s.Append([]byte("record-000"+strconv.Itoa(i))))
+	}
+	// the oldest segment of 2 records is evicted when the fifth record exceeds 85 bytes
+	assert.Equal(t, 2, evicted)
+	assert.Equal(t, []string{"record-0002", "record-0003", "record-0004", "record-0005"}, replayAll(t, s))
+}
+
+func TestSpool_tornTail(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Append([]byte("complete")))
+	assert.Nil(t, s.Close())
+	paths, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	file, err := os.OpenFile(paths[0], os.O_APPEND|os.O_WRONLY, 0600)
+	assert.Nil(t, err)
+	_, _ = file.Write(encodeRecord([]byte("torn"))[:10]) // a crash in the middle of a write
+	_ = file.Close()
+	_ = ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a segment"), 0600)
+	s, err = Open(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"complete"}, replayAll(t, s))
+}
@@ -0,0 +1,414 @@
+// Package stats Runtime statistics of the SDK, the counters and the latency histograms are recorded
+// in process and exposed through abc.Stats and abc.StatsHandler in the Prometheus text format.
+// Unlike the monitor events reported by the metrics plugin, nothing here is sampled.
+package stats
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Evaluation APIs
+const (
+	APIGetExperiments       = "GetExperiments"
+	APIGetRemoteConfig      = "GetRemoteConfig"
+	APIGetValueByVariantKey = "GetValueByVariantKey"
+)
+
+// Status label values
+const (
+	StatusSuccess  = "success"
+	StatusError    = "error"
+	StatusFallback = "fallback" // the evaluation failed and the caller-supplied fallback value is returned
+)
+
+// Plugin methods of the plugin errors
+const (
+	MethodSendData        = "SendData"
+	MethodLogExposure     = "LogExposure"
+	MethodLogMonitorEvent = "LogMonitorEvent"
+	MethodLogEvent        = "LogEvent"
+)
+
+// Label names
+const (
+	LabelAPI       = "api"
+	LabelStatus    = "status"
+	LabelProjectID = "project_id"
+	LabelVersion   = "version"
+	LabelChannel   = "channel"
+	LabelPlugin    = "plugin"
+	LabelMethod    = "method"
+	LabelType      = "type"
+	LabelEvent     = "event"
+)
+
+// Spool events of the spooled records
+const (
+	SpoolEventSpooled  = "spooled"  // the delivery failed and the record is appended to the spool
+	SpoolEventReplayed = "replayed" // the record is delivered by the replay
+	SpoolEventEvicted  = "evicted"  // the record is lost because the spool exceeds its size cap
+	SpoolEventDropped  = "dropped"  // the record is lost because it cannot be appended
+)
+
+// Exposure types of the suppressed exposures
+const (
+	ExposureTypeExperiment   = "experiment"
+	ExposureTypeRemoteConfig = "remote_config"
+	ExposureTypeFeatureFlag  = "feature_flag"
+)
+
+// DefaultBuckets The upper bounds in seconds of the latency histograms,
+// the evaluation takes microseconds while the refresh and the DMP query take milliseconds
+var DefaultBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	evaluations        = newCounterVec(LabelAPI, LabelStatus)
+	evaluationLatency  = newHistogramVec(LabelAPI)
+	refreshes          = newCounterVec(LabelProjectID, LabelStatus)
+	refreshLatency     = newHistogramVec(LabelProjectID)
+	versionRollbacks   = newCounterVec(LabelProjectID)
+	dmpRequests        = newCounterVec(LabelStatus)
+	dmpLatency         = newHistogramVec()
+	exposureDrops      = newCounterVec(LabelChannel)
+	exposureSuppressed = newCounterVec(LabelType)
+	exposureErrors     = newCounterVec(LabelChannel)
+	pluginErrors       = newCounterVec(LabelPlugin, LabelMethod)
+	pluginRetries      = newCounterVec(LabelPlugin, LabelMethod)
+	pluginDeadLetters  = newCounterVec(LabelPlugin, LabelMethod)
+	spoolRecords       = newCounterVec(LabelEvent)
+	spoolBytes         int64
+)
+
+// ObserveEvaluation Record an evaluation of api
+func ObserveEvaluation(api string, status string, latency time.Duration) {
+	evaluations.inc(api, status)
+	evaluationLatency.observe(latency, api)
+}
+
+// ObserveRefresh Record a local cache refresh of projectID, the initial load included
+func ObserveRefresh(projectID string, latency time.Duration, err error) {
+	refreshes.inc(projectID, errStatus(err))
+	refreshLatency.observe(latency, projectID)
+}
+
+// ObserveDMP Record a DMP tag query
+func ObserveDMP(latency time.Duration, err error) {
+	dmpRequests.inc(errStatus(err))
+	dmpLatency.observe(latency)
+}
+
+// IncVersionRollback Record a version of the local cache data failing the version check and rolled back
+func IncVersionRollback(projectID string) {
+	versionRollbacks.inc(projectID)
+}
+
+// IncExposureDrop Record an exposure dropped by the overflow policy of the full channel
+func IncExposureDrop(channel string) {
+	exposureDrops.inc(channel)
+}
+
+// IncExposureSuppressed Record an exposure suppressed as a repeat within the dedupe window
+func IncExposureSuppressed(exposureType string) {
+	exposureSuppressed.inc(exposureType)
+}
+
+// IncExposureError Record a record of the exposure channel failing to be reported
+func IncExposureError(channel string) {
+	exposureErrors.inc(channel)
+}
+
+// AddSpoolRecords Record the spooled records of the event
+func AddSpoolRecords(event string, n int) {
+	spoolRecords.add(uint64(n), event)
+}
+
+// SetSpoolBytes Record the bytes of the records in the spool
+func SetSpoolBytes(n int64) {
+	atomic.StoreInt64(&spoolBytes, n)
+}
+
+// IncPluginError Record a failed delivery of the metrics plugin
+func IncPluginError(plugin string, method string) {
+	pluginErrors.inc(plugin, method)
+}
+
+// IncPluginRetry Record a retry of a failed delivery of the metrics plugin
+func IncPluginRetry(plugin string, method string) {
+	pluginRetries.inc(plugin, method)
+}
+
+// IncPluginDeadLetter Record a delivery of the metrics plugin given up on and handed to the dead letter handler
+func IncPluginDeadLetter(plugin string, method string) {
+	pluginDeadLetters.inc(plugin, method)
+}
+
+// Reset Clear all the statistics, used by tests
+func Reset() {
+	for _, vec := range []*counterVec{evaluations, refreshes, versionRollbacks, dmpRequests, exposureDrops,
+		exposureSuppressed, exposureErrors, pluginErrors, pluginRetries, pluginDeadLetters, spoolRecords} {
+		vec.reset()
+	}
+	atomic.StoreInt64(&spoolBytes, 0)
+	for _, vec := range []*histogramVec{evaluationLatency, refreshLatency, dmpLatency} {
+		vec.reset()
+	}
+}
+
+func errStatus(err error) string {
+	if err != nil {
+		return StatusError
+	}
+	return StatusSuccess
+}
+
+// Counter The value of a counter series
+type Counter struct {
+	Labels map[string]string
+	Value  uint64
+}
+
+// Histogram The value of a histogram series
+type Histogram struct {
+	Labels map[string]string
+	// UpperBounds The upper bounds in seconds of the buckets, the +Inf bucket is Count
+	UpperBounds []float64
+	// BucketCounts Cumulative count of each bucket, the same length as UpperBounds
+	BucketCounts []uint64
+	Count        uint64
+	Sum          float64 // seconds
+}
+
+// Channel The state of an exposure channel
+type Channel struct {
+	Name     string
+	Depth    int
+	Capacity int
+	Drops    uint64
+}
+
+// Snapshot The statistics at a point in time, series are sorted by the label values
+type Snapshot struct {
+	// Evaluations Count of evaluations labeled by api and status
+	Evaluations []Counter
+	// EvaluationLatency Latency of evaluations labeled by api
+	EvaluationLatency []Histogram
+	// Refreshes Count of local cache refreshes labeled by project_id and status
+	Refreshes []Counter
+	// RefreshLatency Latency of local cache refreshes labeled by project_id
+	RefreshLatency []Histogram
+	// Versions The current version of the local cache data, the key is projectID
+	Versions map[string]string
+	// VersionRollbacks Count of the versions failing the version check and rolled back labeled by project_id
+	VersionRollbacks []Counter
+	// DMPRequests Count of DMP tag queries labeled by status
+	DMPRequests []Counter
+	// DMPLatency Latency of DMP tag queries
+	DMPLatency Histogram
+	// ExposureChannels The state of the exposure channels, sorted by name
+	ExposureChannels []Channel
+	// ExposureSuppressed Count of exposures suppressed as repeats labeled by type
+	ExposureSuppressed []Counter
+	// ExposureErrors Count of the records failing to be reported labeled by channel
+	ExposureErrors []Counter
+	// PluginErrors Count of failed deliveries of the metrics plugins labeled by plugin and method
+	PluginErrors []Counter
+	// PluginRetries Count of retries of the failed deliveries labeled by plugin and method
+	PluginRetries []Counter
+	// PluginDeadLetters Count of deliveries given up on labeled by plugin and method
+	PluginDeadLetters []Counter
+	// SpoolRecords Count of the records of the exposure spool labeled by event
+	SpoolRecords []Counter
+	// SpoolBytes The bytes of the records in the exposure spool
+	SpoolBytes int64
+}
+
+// ExposureDrops The count of the exposures dropped by each channel, the key is the channel name
+func ExposureDrops() map[string]uint64 {
+	var drops = make(map[string]uint64)
+	for _, counter := range exposureDrops.collect() {
+		drops[counter.Labels[LabelChannel]] = counter.Value
+	}
+	return drops
+}
+
+// Collect Take the snapshot, the versions and the channel states are supplied by the caller,
+// the drops of the channels are filled here
+func Collect(versions map[string]string, channels []Channel) *Snapshot {
+	drops := ExposureDrops()
+	var exposureChannels = make([]Channel, 0, len(channels))
+	for _, channel := range channels {
+		channel.Drops = drops[channel.Name]
+		exposureChannels = append(exposureChannels, channel)
+	}
+	sort.Slice(exposureChannels, func(i, j int) bool { return exposureChannels[i].Name < exposureChannels[j].Name })
+	snapshot := &Snapshot{
+		Evaluations:        evaluations.collect(),
+		EvaluationLatency:  evaluationLatency.collect(),
+		Refreshes:          refreshes.collect(),
+		RefreshLatency:     refreshLatency.collect(),
+		Versions:           versions,
+		VersionRollbacks:   versionRollbacks.collect(),
+		DMPRequests:        dmpRequests.collect(),
+		ExposureChannels:   exposureChannels,
+		ExposureSuppressed: exposureSuppressed.collect(),
+		ExposureErrors:     exposureErrors.collect(),
+		PluginErrors:       pluginErrors.collect(),
+		PluginRetries:      pluginRetries.collect(),
+		PluginDeadLetters:  pluginDeadLetters.collect(),
+		SpoolRecords:       spoolRecords.collect(),
+		SpoolBytes:         atomic.LoadInt64(&spoolBytes),
+	}
+	if dmp := dmpLatency.collect(); len(dmp) != 0 {
+		snapshot.DMPLatency = dmp[0]
+	} else {
+		snapshot.DMPLatency = emptyHistogram(map[string]string{})
+	}
+	return snapshot
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       uint64
+}
+
+// counterVec Counters of the same name, one series per combination of the label values
+type counterVec struct {
+	labelNames []string
+	series     sync.Map // joined label values -> *counterSeries
+}
+
+func newCounterVec(labelNames ...string) *counterVec {
+	return &counterVec{labelNames: labelNames}
+}
+
+func (v *counterVec) inc(labelValues ...string) {
+	v.add(1, labelValues...)
+}
+
+func (v *counterVec) add(n uint64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	series, ok := v.series.Load(key)
+	if !ok {
+		series, _ = v.series.LoadOrStore(key, &counterSeries{labelValues: labelValues})
+	}
+	atomic.AddUint64(&series.(*counterSeries).value, n)
+}
+
+func (v *counterVec) collect() []Counter {
+	var keys []string
+	var result = make(map[string]Counter)
+	v.series.Range(func(key, value interface{}) bool {
+		series := value.(*counterSeries)
+		keys = append(keys, key.(string))
+		result[key.(string)] = Counter{
+			Labels: labels(v.labelNames, series.labelValues),
+			Value:  atomic.LoadUint64(&series.value),
+		}
+		return true
+	})
+	sort.Strings(keys)
+	var counters = make([]Counter, 0, len(keys))
+	for _, key := range keys {
+		counters = append(counters, result[key])
+	}
+	return counters
+}
+
+func (v *counterVec) reset() {
+	v.series.Range(func(key, value interface{}) bool {
+		v.series.Delete(key)
+		return true
+	})
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 // non-cumulative count of each bucket
+	count       uint64
+	sum         int64 // nanoseconds
+}
+
+// histogramVec Latency histograms of the same name, one series per combination of the label values
+type histogramVec struct {
+	labelNames []string
+	series     sync.Map // joined label values -> *histogramSeries
+}
+
+func newHistogramVec(labelNames ...string) *histogramVec {
+	return &histogramVec{labelNames: labelNames}
+}
+
+func (v *histogramVec) observe(latency time.Duration, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	value, ok := v.series.Load(key)
+	if !ok {
+		value, _ = v.series.LoadOrStore(key, &histogramSeries{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(DefaultBuckets)),
+		})
+	}
+	series := value.(*histogramSeries)
+	// the first bucket whose upper bound is not less than the latency, beyond the last one it is only in +Inf
+	if i := sort.SearchFloat64s(DefaultBuckets, latency.Seconds()); i < len(DefaultBuckets) {
+		atomic.AddUint64(&series.counts[i], 1)
+	}
+	atomic.AddInt64(&series.sum, int64(latency))
+	atomic.AddUint64(&series.count, 1)
+}
+
+func (v *histogramVec) collect() []Histogram {
+	var keys []string
+	var result = make(map[string]Histogram)
+	v.series.Range(func(key, value interface{}) bool {
+		series := value.(*histogramSeries)
+		histogram := emptyHistogram(labels(v.labelNames, series.labelValues))
+		var cumulative uint64
+		for i := range series.counts {
+			cumulative += atomic.LoadUint64(&series.counts[i])
+			histogram.BucketCounts[i] = cumulative
+		}
+		histogram.Count = atomic.LoadUint64(&series.count)
+		if histogram.Count < cumulative { // observed concurrently
+			histogram.Count = cumulative
+		}
+		histogram.Sum = time.Duration(atomic.LoadInt64(&series.sum)).Seconds()
+		keys = append(keys, key.(string))
+		result[key.(string)] = histogram
+		return true
+	})
+	sort.Strings(keys)
+	var histograms = make([]Histogram, 0, len(keys))
+	for _, key := range keys {
+		histograms = append(histograms, result[key])
+	}
+	return histograms
+}
+
+func (v *histogramVec) reset() {
+	v.series.Range(func(key, value interface{}) bool {
+		v.series.Delete(key)
+		return true
+	})
+}
+
+func emptyHistogram(labels map[string]string) Histogram {
+	return Histogram{
+		Labels:       labels,
+		UpperBounds:  DefaultBuckets,
+		BucketCounts: make([]uint64, len(DefaultBuckets)),
+	}
+}
+
+func labels(names []string, values []string) map[string]string {
+	var result = make(map[string]string, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			result[name] = values[i]
+		}
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package stats
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveEvaluation(t *testing.T) {
+	Reset()
+	defer Reset()
+	ObserveEvaluation(APIGetExperiments, StatusSuccess, 50*time.Microsecond)
+	ObserveEvaluation(APIGetExperiments, StatusSuccess, 2*time.Millisecond)
+	ObserveEvaluation(APIGetExperiments, StatusFallback, 20*time.Second)
+	ObserveEvaluation(APIGetRemoteConfig, StatusError, time.Millisecond)
+	snapshot := Collect(nil, nil)
+	assert.Equal(t, []Counter{
+		{Labels: map[string]string{LabelAPI: APIGetExperiments, LabelStatus: StatusFallback}, Value: 1},
+		{Labels: map[string]string{LabelAPI: APIGetExperiments, LabelStatus: StatusSuccess}, Value: 2},
+		{Labels: map[string]string{LabelAPI: APIGetRemoteConfig, LabelStatus: StatusError}, Value: 1},
+	}, snapshot.Evaluations)
+	assert.Len(t, snapshot.EvaluationLatency, 2)
+	histogram := snapshot.EvaluationLatency[0]
+	assert.Equal(t, map[string]string{LabelAPI: APIGetExperiments}, histogram.Labels)
+	assert.Equal(t, uint64(3), histogram.Count)
+	assert.InDelta(t, 20.00205, histogram.Sum, 1e-9)
+	assert.Equal(t, uint64(1), histogram.BucketCounts[0])                     // le=0.0001
+	assert.Equal(t, uint64(1), histogram.BucketCounts[3])                     // le=0.001
+	assert.Equal(t, uint64(2), histogram.BucketCounts[4])                     // le=0.0025
+	assert.Equal(t, uint64(2), histogram.BucketCounts[len(DefaultBuckets)-1]) // le=10, 20s only in +Inf
+	assert.Equal(t, uint64(1), snapshot.EvaluationLatency[1].BucketCounts[3]) // exactly on the upper bound
+	assert.Equal(t, uint64(0), snapshot.EvaluationLatency[1].BucketCounts[2])
+}
+
+func TestCollect(t *testing.T) {
+	Reset()
+	defer Reset()
+	ObserveRefresh("123", 10*time.Millisecond, nil)
+	ObserveRefresh("123", 10*time.Millisecond, errors.New("timeout"))
+	ObserveDMP(time.Millisecond, nil)
+	IncExposureDrop("experiment_exposure")
+	IncExposureDrop("experiment_exposure")
+	IncPluginError("kafka", MethodLogExposure)
+	IncPluginRetry("kafka", MethodLogExposure)
+	IncPluginDeadLetter("kafka", MethodSendData)
+	IncVersionRollback("123")
+	IncExposureSuppressed(ExposureTypeExperiment)
+	IncExposureError("experiment_event")
+	AddSpoolRecords(SpoolEventSpooled, 3)
+	SetSpoolBytes(100)
+	snapshot := Collect(map[string]string{"123": "v1"}, []Channel{
+		{Name: "remote_config_exposure", Depth: 1, Capacity: 8},
+		{Name: "experiment_exposure", Depth: 8, Capacity: 8},
+	})
+	assert.Equal(t, []Counter{
+		{Labels: map[string]string{LabelProjectID: "123", LabelStatus: StatusError}, Value: 1},
+		{Labels: map[string]string{LabelProjectID: "123", LabelStatus: StatusSuccess}, Value: 1},
+	}, snapshot.Refreshes)
+	assert.Equal(t, uint64(2), snapshot.RefreshLatency[0].Count)
+	assert.Equal(t, []Counter{{Labels: map[string]string{LabelProjectID: "123"}, Value: 1}}, snapshot.VersionRollbacks)
+	assert.Equal(t, []Counter{{Labels: map[string]string{LabelStatus: StatusSuccess}, Value: 1}}, snapshot.DMPRequests)
+	assert.Equal(t, uint64(1), snapshot.DMPLatency.Count)
+	assert.Equal(t, []Channel{
+		{Name: "experiment_exposure", Depth: 8, Capacity: 8, Drops: 2},
+		{Name: "remote_config_exposure", Depth: 1, Capacity: 8},
+	}, snapshot.ExposureChannels)
+	assert.Equal(t, []Counter{
+		{Labels: map[string]string{LabelPlugin: "kafka", LabelMethod: MethodLogExposure}, Value: 1},
+	}, snapshot.PluginErrors)
+	assert.Equal(t, []Counter{
+		{Labels: map[string]string{LabelPlugin: "kafka", LabelMethod: MethodLogExposure}, Value: 1},
+	}, snapshot.PluginRetries)
+	assert.Equal(t, []Counter{
+		{Labels: map[string]string{LabelPlugin: "kafka", LabelMethod: MethodSendData}, Value: 1},
+	}, snapshot.PluginDeadLetters)
+	assert.Equal(t, []Counter{
+		{Labels: map[string]string{LabelType: ExposureTypeExperiment}, Value: 1},
+	}, snapshot.ExposureSuppressed)
+	assert.Equal(t, []Counter{{Labels: map[string]string{LabelChannel: "experiment_event"}, Value: 1}},
+		snapshot.ExposureErrors)
+	assert.Equal(t, []Counter{{Labels: map[string]string{LabelEvent: SpoolEventSpooled}, Value: 3}},
+		snapshot.SpoolRecords)
+	assert.Equal(t, int64(100), snapshot.SpoolBytes)
+}
+
+func TestSnapshot_WriteText(t *testing.T) {
+	Reset()
+	defer Reset()
+	ObserveEvaluation(APIGetRemoteConfig, StatusSuccess, 3*time.Millisecond)
+	IncPluginError(`a"b`, MethodSendData)
+	IncExposureSuppressed(ExposureTypeRemoteConfig)
+	IncExposureError("track_event")
+	var buf bytes.Buffer
+	err := Collect(map[string]string{"123": "v1"}, []Channel{{Name: "experiment_event", Depth: 2, Capacity: 4}}).
+		WriteText(&buf)
+	assert.Nil(t, err)
+	text := buf.String()
+	for _, line := range []string{
+		"# TYPE abc_evaluations_total counter",
+		`abc_evaluations_total{api="GetRemoteConfig",status="success"} 1`,
+		"# TYPE abc_evaluation_duration_seconds histogram",
+		`abc_evaluation_duration_seconds_bucket{api="GetRemoteConfig",le="0.0025"} 0`,
+		`abc_evaluation_duration_seconds_bucket{api="GetRemoteConfig",le="0.005"} 1`,
+		`abc_evaluation_duration_seconds_bucket{api="GetRemoteConfig",le="+Inf"} 1`,
+		`abc_evaluation_duration_seconds_sum{api="GetRemoteConfig"} 0.003`,
+		`abc_evaluation_duration_seconds_count{api="GetRemoteConfig"} 1`,
+		`abc_cache_version_info{project_id="123",version="v1"} 1`,
+		`abc_dmp_request_duration_seconds_bucket{le="+Inf"} 0`,
+		`abc_dmp_request_duration_seconds_count 0`,
+		`abc_exposure_channel_depth{channel="experiment_event"} 2`,
+		`abc_exposure_channel_capacity{channel="experiment_event"} 4`,
+		`abc_exposure_channel_drops_total{channel="experiment_event"} 0`,
+		`abc_exposure_suppressed_total{type="remote_config"} 1`,
+		`abc_exposure_errors_total{channel="track_event"} 1`,
+		`abc_spool_bytes 0`,
+		`abc_plugin_errors_total{method="SendData",plugin="a\"b"} 1`,
+	} {
+		assert.Contains(t, strings.Split(text, "\n"), line)
+	}
+}
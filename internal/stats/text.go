@@ -0,0 +1,172 @@
+// Package stats Runtime statistics of the SDK, the counters and the latency histograms are recorded
+// in process and exposed through abc.Stats and abc.StatsHandler in the Prometheus text format.
+// Unlike the monitor events reported by the metrics plugin, nothing here is sampled.
+package stats
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType The content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric names of the text exposition
+const (
+	MetricEvaluations          = "abc_evaluations_total"
+	MetricEvaluationDuration   = "abc_evaluation_duration_seconds"
+	MetricRefreshes            = "abc_refresh_total"
+	MetricRefreshDuration      = "abc_refresh_duration_seconds"
+	MetricVersion              = "abc_cache_version_info"
+	MetricVersionRollbacks     = "abc_cache_version_rollbacks_total"
+	MetricDMPRequests          = "abc_dmp_requests_total"
+	MetricDMPDuration          = "abc_dmp_request_duration_seconds"
+	MetricExposureChannelDepth = "abc_exposure_channel_depth"
+	MetricExposureChannelCap   = "abc_exposure_channel_capacity"
+	MetricExposureChannelDrops = "abc_exposure_channel_drops_total"
+	MetricExposureSuppressed   = "abc_exposure_suppressed_total"
+	MetricExposureErrors       = "abc_exposure_errors_total"
+	MetricPluginErrors         = "abc_plugin_errors_total"
+	MetricPluginRetries        = "abc_plugin_retries_total"
+	MetricPluginDeadLetters    = "abc_plugin_dead_letters_total"
+	MetricSpoolRecords         = "abc_spool_records_total"
+	MetricSpoolBytes           = "abc_spool_bytes"
+)
+
+const (
+	metricTypeCounter   = "counter"
+	metricTypeGauge     = "gauge"
+	metricTypeHistogram = "histogram"
+	labelLE             = "le"
+	infiniteUpperBound  = "+Inf"
+)
+
+// WriteText Write the snapshot in the Prometheus text exposition format
+func (s *Snapshot) WriteText(w io.Writer) error {
+	var b textBuilder
+	b.header(MetricEvaluations, metricTypeCounter, "Evaluations by API and status.")
+	b.counters(MetricEvaluations, s.Evaluations)
+	b.header(MetricEvaluationDuration, metricTypeHistogram, "Evaluation latency by API in seconds.")
+	b.histograms(MetricEvaluationDuration, s.EvaluationLatency)
+	b.header(MetricRefreshes, metricTypeCounter, "Local cache refreshes by project and status.")
+	b.counters(MetricRefreshes, s.Refreshes)
+	b.header(MetricRefreshDuration, metricTypeHistogram, "Local cache refresh latency by project in seconds.")
+	b.histograms(MetricRefreshDuration, s.RefreshLatency)
+	b.header(MetricVersion, metricTypeGauge, "Current version of the local cache data by project, always 1.")
+	var projectIDs = make([]string, 0, len(s.Versions))
+	for projectID := range s.Versions {
+		projectIDs = append(projectIDs, projectID)
+	}
+	sort.Strings(projectIDs)
+	for _, projectID := range projectIDs {
+		b.sample(MetricVersion, []string{LabelProjectID, projectID, LabelVersion, s.Versions[projectID]}, "1")
+	}
+	b.header(MetricVersionRollbacks, metricTypeCounter, "Versions of the local cache data rolled back by project.")
+	b.counters(MetricVersionRollbacks, s.VersionRollbacks)
+	b.header(MetricDMPRequests, metricTypeCounter, "DMP tag queries by status.")
+	b.counters(MetricDMPRequests, s.DMPRequests)
+	b.header(MetricDMPDuration, metricTypeHistogram, "DMP tag query latency in seconds.")
+	b.histograms(MetricDMPDuration, []Histogram{s.DMPLatency})
+	b.header(MetricExposureChannelDepth, metricTypeGauge, "Exposures waiting in the channel.")
+	for _, channel := range s.ExposureChannels {
+		b.sample(MetricExposureChannelDepth, []string{LabelChannel, channel.Name}, strconv.Itoa(channel.Depth))
+	}
+	b.header(MetricExposureChannelCap, metricTypeGauge, "Capacity of the exposure channel.")
+	for _, channel := range s.ExposureChannels {
+		b.sample(MetricExposureChannelCap, []string{LabelChannel, channel.Name}, strconv.Itoa(channel.Capacity))
+	}
+	b.header(MetricExposureChannelDrops, metricTypeCounter, "Exposures dropped because the channel is full.")
+	for _, channel := range s.ExposureChannels {
+		b.sample(MetricExposureChannelDrops, []string{LabelChannel, channel.Name},
+			strconv.FormatUint(channel.Drops, 10))
+	}
+	b.header(MetricExposureSuppressed, metricTypeCounter,
+		"Exposures suppressed as repeats within the dedupe window by type.")
+	b.counters(MetricExposureSuppressed, s.ExposureSuppressed)
+	b.header(MetricExposureErrors, metricTypeCounter, "Exposures failing to be reported by channel.")
+	b.counters(MetricExposureErrors, s.ExposureErrors)
+	b.header(MetricPluginErrors, metricTypeCounter, "Failed deliveries of the metrics plugins by plugin and method.")
+	b.counters(MetricPluginErrors, s.PluginErrors)
+	b.header(MetricPluginRetries, metricTypeCounter, "Retries of the failed deliveries by plugin and method.")
+	b.counters(MetricPluginRetries, s.PluginRetries)
+	b.header(MetricPluginDeadLetters, metricTypeCounter, "Deliveries given up on by plugin and method.")
+	b.counters(MetricPluginDeadLetters, s.PluginDeadLetters)
+	b.header(MetricSpoolRecords, metricTypeCounter, "Records of the exposure spool by event.")
+	b.counters(MetricSpoolRecords, s.SpoolRecords)
+	b.header(MetricSpoolBytes, metricTypeGauge, "Bytes of the records in the exposure spool.")
+	b.sample(MetricSpoolBytes, nil, strconv.FormatInt(s.SpoolBytes, 10))
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+type textBuilder struct {
+	bytes.Buffer
+}
+
+func (b *textBuilder) header(name string, metricType string, help string) {
+	b.WriteString("# HELP " + name + " " + help + "\n")
+	b.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+func (b *textBuilder) counters(name string, counters []Counter) {
+	for _, counter := range counters {
+		b.sample(name, sortedLabels(counter.Labels), strconv.FormatUint(counter.Value, 10))
+	}
+}
+
+func (b *textBuilder) histograms(name string, histograms []Histogram) {
+	for _, histogram := range histograms {
+		labels := sortedLabels(histogram.Labels)
+		for i, upperBound := range histogram.UpperBounds {
+			b.sample(name+"_bucket", append(labels, labelLE, formatFloat(upperBound)),
+				strconv.FormatUint(histogram.BucketCounts[i], 10))
+		}
+		b.sample(name+"_bucket", append(labels, labelLE, infiniteUpperBound),
+			strconv.FormatUint(histogram.Count, 10))
+		b.sample(name+"_sum", labels, formatFloat(histogram.Sum))
+		b.sample(name+"_count", labels, strconv.FormatUint(histogram.Count, 10))
+	}
+}
+
+// sample Write a sample line, labels is a list of name, value pairs
+func (b *textBuilder) sample(name string, labels []string, value string) {
+	b.WriteString(name)
+	if len(labels) != 0 {
+		b.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+		b.WriteString("}")
+	}
+	b.WriteString(" " + value + "\n")
+}
+
+// sortedLabels The name, value pairs sorted by the label name, the returned slice is safe to append
+func sortedLabels(labels map[string]string) []string {
+	var names = make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var result = make([]string, 0, len(names)*2)
+	for _, name := range names {
+		result = append(result, name, labels[name])
+	}
+	return result[:len(result):len(result)]
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
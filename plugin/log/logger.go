@@ -6,6 +6,7 @@ import (
 	"log"
 	"path"
 	"runtime"
+	"strings"
 )
 
 func init() {
@@ -13,9 +14,11 @@ func init() {
 	defaultLogger = &InnerLogger{}
 }
 
-// RegisterLogger Register the logger and print the log to the set logger
+// RegisterLogger Register the logger and print the log to the set logger,
+// the structured logs are printed to it through NewStructuredLogger
 func RegisterLogger(logger Logger) {
 	defaultLogger = logger
+	structuredLogger = NewStructuredLogger(logger)
 }
 
 // SetLoggerLevel Set the logger level
@@ -124,7 +127,7 @@ type InnerLogger struct{}
 
 // Warn printing
 func (i *InnerLogger) Warn(args ...interface{}) {
-	log.Printf(getCallerInfo()+"\n", args...)
+	log.Print(getCallerInfo() + fmt.Sprint(args...))
 }
 
 // Warnf printing
@@ -134,7 +137,7 @@ func (i *InnerLogger) Warnf(format string, args ...interface{}) {
 
 // Info printing
 func (i *InnerLogger) Info(args ...interface{}) {
-	log.Printf(getCallerInfo()+"\n", args...)
+	log.Print(getCallerInfo() + fmt.Sprint(args...))
 }
 
 // Infof printing
@@ -144,7 +147,7 @@ func (i *InnerLogger) Infof(format string, args ...interface{}) {
 
 // Error printing
 func (i *InnerLogger) Error(args ...interface{}) {
-	log.Printf(getCallerInfo()+"\n", args...)
+	log.Print(getCallerInfo() + fmt.Sprint(args...))
 }
 
 // Errorf printing
@@ -154,7 +157,7 @@ func (i *InnerLogger) Errorf(format string, args ...interface{}) {
 
 // Debug printing
 func (i *InnerLogger) Debug(args ...interface{}) {
-	log.Printf(getCallerInfo()+"\n", args...)
+	log.Print(getCallerInfo() + fmt.Sprint(args...))
 }
 
 // Debugf printing
@@ -162,11 +165,29 @@ func (i *InnerLogger) Debugf(format string, args ...interface{}) {
 	log.Printf(getCallerInfo()+format+"\n", args...)
 }
 
+// packagePath The import path of this package, the frames of it are skipped in the caller information
+const packagePath = "github.com/abetterchoice/go-sdk/plugin/log"
+
+// getCallerInfo The file and the line of the first caller outside this package,
+// so that it is right whichever of the printf and the structured functions and adapters are on the way
 func getCallerInfo() string {
-	_, file, line, ok := runtime.Caller(3)
-	if !ok {
-		return ""
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if !isPackageFrame(frame.Function) {
+			return fmt.Sprintf("%s:%d\t", path.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// isPackageFrame Whether the function is of this package, the tests of this package are regarded as callers
+func isPackageFrame(function string) bool {
+	if !strings.HasPrefix(function, packagePath+".") {
+		return false
 	}
-	fileName := path.Base(file)
-	return fmt.Sprintf("%s:%d\t", fileName, line)
+	return !strings.HasPrefix(function, packagePath+".Test")
 }
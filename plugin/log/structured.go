@@ -0,0 +1,233 @@
+// Package log logger
+package log
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// The keys of the fields attached by the SDK
+const (
+	FieldProjectID    = "projectID"
+	FieldVersion      = "version"
+	FieldLayerKey     = "layerKey"
+	FieldExperimentID = "experimentID"
+	FieldGroupID      = "groupID"
+	FieldConfigKey    = "configKey"
+	FieldTagKey       = "tagKey"
+	FieldLatency      = "latency"
+	FieldError        = "error"
+)
+
+// Field The key/value pair attached to the structured log
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F The field of the key and the value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err The error field
+func Err(err error) Field {
+	return Field{Key: FieldError, Value: err}
+}
+
+// StructuredLogger The logger receiving the message and the key/value fields separately,
+// ctx is the context of the call, it is never nil, and the fields attached to it by WithFields
+// are already placed in front of the fields
+type StructuredLogger interface {
+	Log(ctx context.Context, level Level, msg string, fields ...Field)
+}
+
+var structuredLogger StructuredLogger = &InnerLogger{}
+
+// RegisterStructuredLogger Register the structured logger, the printf style logs are also printed to it
+func RegisterStructuredLogger(logger StructuredLogger) {
+	structuredLogger = logger
+	defaultLogger = NewPrintfLogger(logger)
+}
+
+// NewStructuredLogger Adapt the printf style logger to StructuredLogger,
+// the fields are appended to the message in the form of key=value
+func NewStructuredLogger(logger Logger) StructuredLogger {
+	if structured, ok := logger.(StructuredLogger); ok {
+		return structured
+	}
+	return &printfAdapter{logger: logger}
+}
+
+// NewPrintfLogger Adapt the structured logger to the printf style Logger,
+// the logs are formatted into the message and have no fields
+func NewPrintfLogger(logger StructuredLogger) Logger {
+	if printf, ok := logger.(Logger); ok {
+		return printf
+	}
+	return &structuredAdapter{logger: logger}
+}
+
+type contextFieldsKey struct{}
+
+// WithFields Attach the fields to ctx, they are logged before the fields of each call with the ctx
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	parent := ContextFields(ctx)
+	merged := make([]Field, 0, len(parent)+len(fields))
+	merged = append(append(merged, parent...), fields...)
+	return context.WithValue(ctx, contextFieldsKey{}, merged)
+}
+
+// ContextFields The fields attached to ctx by WithFields
+func ContextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(contextFieldsKey{}).([]Field)
+	return fields
+}
+
+// DebugContext Structured debug printing
+func DebugContext(ctx context.Context, msg string, fields ...Field) {
+	logContext(ctx, DebugLevel, msg, fields)
+}
+
+// InfoContext Structured info printing
+func InfoContext(ctx context.Context, msg string, fields ...Field) {
+	logContext(ctx, InfoLevel, msg, fields)
+}
+
+// WarnContext Structured warn printing
+func WarnContext(ctx context.Context, msg string, fields ...Field) {
+	logContext(ctx, WarnLevel, msg, fields)
+}
+
+// ErrorContext Structured error printing
+func ErrorContext(ctx context.Context, msg string, fields ...Field) {
+	logContext(ctx, ErrorLevel, msg, fields)
+}
+
+func logContext(ctx context.Context, level Level, msg string, fields []Field) {
+	if loggerLevel > level {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if contextFields := ContextFields(ctx); len(contextFields) != 0 {
+		fields = append(contextFields[:len(contextFields):len(contextFields)], fields...)
+	}
+	structuredLogger.Log(ctx, level, msg, fields...)
+}
+
+// String The name of the level
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return "none"
+	}
+}
+
+// Log Structured printing, the fields are appended to the message in the form of key=value
+func (i *InnerLogger) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	log.Print(getCallerInfo() + level.String() + "\t" + FormatFields(msg, fields))
+}
+
+// FormatFields Append the fields to the message in the form of key=value,
+// the values containing spaces or quotes are quoted
+func FormatFields(msg string, fields []Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	var builder strings.Builder
+	builder.WriteString(msg)
+	for _, field := range fields {
+		builder.WriteByte(' ')
+		builder.WriteString(field.Key)
+		builder.WriteByte('=')
+		value := fmt.Sprint(field.Value)
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		builder.WriteString(value)
+	}
+	return builder.String()
+}
+
+// printfAdapter The StructuredLogger printing to the printf style Logger
+type printfAdapter struct {
+	logger Logger
+}
+
+// Log StructuredLogger
+func (p *printfAdapter) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	line := FormatFields(msg, fields)
+	switch level {
+	case DebugLevel:
+		p.logger.Debugf("%s", line)
+	case InfoLevel:
+		p.logger.Infof("%s", line)
+	case WarnLevel:
+		p.logger.Warnf("%s", line)
+	default:
+		p.logger.Errorf("%s", line)
+	}
+}
+
+// structuredAdapter The printf style Logger printing to the StructuredLogger
+type structuredAdapter struct {
+	logger StructuredLogger
+}
+
+// Debug printing
+func (s *structuredAdapter) Debug(args ...interface{}) {
+	s.logger.Log(context.Background(), DebugLevel, fmt.Sprint(args...))
+}
+
+// Debugf printing
+func (s *structuredAdapter) Debugf(format string, args ...interface{}) {
+	s.logger.Log(context.Background(), DebugLevel, fmt.Sprintf(format, args...))
+}
+
+// Info printing
+func (s *structuredAdapter) Info(args ...interface{}) {
+	s.logger.Log(context.Background(), InfoLevel, fmt.Sprint(args...))
+}
+
+// Infof printing
+func (s *structuredAdapter) Infof(format string, args ...interface{}) {
+	s.logger.Log(context.Background(), InfoLevel, fmt.Sprintf(format, args...))
+}
+
+// Warn printing
+func (s *structuredAdapter) Warn(args ...interface{}) {
+	s.logger.Log(context.Background(), WarnLevel, fmt.Sprint(args...))
+}
+
+// Warnf printing
+func (s *structuredAdapter) Warnf(format string, args ...interface{}) {
+	s.logger.Log(context.Background(), WarnLevel, fmt.Sprintf(format, args...))
+}
+
+// Error printing
+func (s *structuredAdapter) Error(args ...interface{}) {
+	s.logger.Log(context.Background(), ErrorLevel, fmt.Sprint(args...))
+}
+
+// Errorf printing
+func (s *structuredAdapter) Errorf(format string, args ...interface{}) {
+	s.logger.Log(context.Background(), ErrorLevel, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,135 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	level  Level
+	msg    string
+	fields []Field
+}
+
+type recordLogger struct {
+	records []record
+}
+
+func (r *recordLogger) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	r.records = append(r.records, record{level: level, msg: msg, fields: fields})
+}
+
+type printfLogger struct {
+	lines []string
+}
+
+func (p *printfLogger) Info(args ...interface{}) {
+	p.lines = append(p.lines, "info "+fmt.Sprint(args...))
+}
+func (p *printfLogger) Warn(args ...interface{}) {
+	p.lines = append(p.lines, "warn "+fmt.Sprint(args...))
+}
+func (p *printfLogger) Error(args ...interface{}) {
+	p.lines = append(p.lines, "error "+fmt.Sprint(args...))
+}
+func (p *printfLogger) Debug(args ...interface{}) {
+	p.lines = append(p.lines, "debug "+fmt.Sprint(args...))
+}
+func (p *printfLogger) Infof(format string, args ...interface{}) {
+	p.lines = append(p.lines, "info "+fmt.Sprintf(format, args...))
+}
+func (p *printfLogger) Warnf(format string, args ...interface{}) {
+	p.lines = append(p.lines, "warn "+fmt.Sprintf(format, args...))
+}
+func (p *printfLogger) Errorf(format string, args ...interface{}) {
+	p.lines = append(p.lines, "error "+fmt.Sprintf(format, args...))
+}
+func (p *printfLogger) Debugf(format string, args ...interface{}) {
+	p.lines = append(p.lines, "debug "+fmt.Sprintf(format, args...))
+}
+
+func restoreLogger() {
+	RegisterLogger(&InnerLogger{})
+	SetLoggerLevel(NotLogLevel)
+}
+
+func TestFormatFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    string
+		fields []Field
+		want   string
+	}{
+		{name: "no fields", msg: "hello", want: "hello"},
+		{name: "fields", msg: "hello", fields: []Field{F(FieldProjectID, "123"), F(FieldGroupID, 100)},
+			want: "hello projectID=123 groupID=100"},
+		{name: "quoted", msg: "hello", fields: []Field{F("a", "x y"), F("b", ""), Err(fmt.Errorf(`k="v"`))},
+			want: `hello a="x y" b="" error="k=\"v\""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatFields(tt.msg, tt.fields))
+		})
+	}
+}
+
+func TestStructuredLogger(t *testing.T) {
+	defer restoreLogger()
+	logger := &recordLogger{}
+	RegisterStructuredLogger(logger)
+	SetLoggerLevel(InfoLevel)
+
+	ctx := WithFields(context.Background(), F(FieldProjectID, "123"))
+	ctx = WithFields(ctx, F(FieldVersion, "v1"))
+	DebugContext(ctx, "skipped")
+	InfoContext(ctx, "refresh", F(FieldLatency, "1ms"))
+	ErrorContext(nil, "fail", Err(fmt.Errorf("x")))
+	Warnf("layer %s not found", "L")
+	Error("a", "b")
+	assert.Equal(t, []record{
+		{level: InfoLevel, msg: "refresh", fields: []Field{F(FieldProjectID, "123"), F(FieldVersion, "v1"),
+			F(FieldLatency, "1ms")}},
+		{level: ErrorLevel, msg: "fail", fields: []Field{Err(fmt.Errorf("x"))}},
+		{level: WarnLevel, msg: "layer L not found"},
+		{level: ErrorLevel, msg: "ab"},
+	}, logger.records)
+	assert.Equal(t, []Field{F(FieldProjectID, "123"), F(FieldVersion, "v1")}, ContextFields(ctx))
+	assert.Nil(t, ContextFields(context.Background()))
+}
+
+func TestPrintfAdapter(t *testing.T) {
+	defer restoreLogger()
+	logger := &printfLogger{}
+	RegisterLogger(logger)
+	SetLoggerLevel(DebugLevel)
+	DebugContext(context.Background(), "alive", F(FieldProjectID, "123"))
+	WarnContext(WithFields(context.Background(), F(FieldLayerKey, "L")), "bitmap not found", F(FieldGroupID, 1))
+	Infof("version=%s", "v1")
+	assert.Equal(t, []string{"debug alive projectID=123", "warn bitmap not found layerKey=L groupID=1",
+		"info version=v1"}, logger.lines)
+}
+
+func TestInnerLogger(t *testing.T) {
+	defer restoreLogger()
+	var buffer bytes.Buffer
+	log.SetOutput(&buffer)
+	defer log.SetOutput(os.Stderr)
+	log.SetFlags(0)
+	defer log.SetFlags(log.LstdFlags)
+	SetLoggerLevel(DebugLevel)
+	Info("hello ", 1)
+	InfoContext(context.Background(), "hello", F("n", 2))
+	Errorf("%s=%d", "n", 3)
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	for i, want := range []string{"\thello 1", "\tinfo\thello n=2", "\tn=3"} {
+		assert.Regexp(t, `^structured_test\.go:\d+`+want+`$`, lines[i])
+	}
+}
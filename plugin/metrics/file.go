@@ -0,0 +1,254 @@
+// Package metrics TODO
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abetterchoice/protoc_cache_server"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/pkg/errors"
+)
+
+// The defaults of FileClient
+const (
+	DefaultFilePluginName = "jsonl_file"
+	DefaultFileMaxSize    = 100 << 20
+	DefaultFileMaxAge     = 24 * time.Hour
+	DefaultFileMaxBackups = 7
+)
+
+// backupTimeFormat The time in the names of the rotated files, it sorts in time order
+const backupTimeFormat = "20060102T150405.000"
+
+var _ Client = (*FileClient)(nil)
+
+// FileClient The metrics client appending each exposure, event, monitor event and data row as a JSON line
+// of Record to the file. The file is rotated when it reaches the size or the age limit, the rotated file is renamed
+// with the time of the rotation, for example exposures.jsonl is renamed to exposures-20240102T030405.000.jsonl
+//
+// example:
+//
+//	fileClient := metrics.NewFileClient("/data/log/abc/exposures.jsonl", metrics.WithFileMaxBackups(3))
+//	defer fileClient.Close()
+//	err := abc.Init(ctx, projectIDList, abc.WithRegisterMetricsPlugin(fileClient, nil))
+type FileClient struct {
+	name       string
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// FileOption The option of FileClient
+type FileOption func(c *FileClient)
+
+// WithFilePluginName The plugin name of the client, the exposures are delivered to the client
+// whose name is the metricsPluginName of the metrics config. Default jsonl_file
+func WithFilePluginName(name string) FileOption {
+	return func(c *FileClient) {
+		c.name = name
+	}
+}
+
+// WithFileMaxSize The size in bytes the file is rotated at, 0 means no size limit. Default 100MB
+func WithFileMaxSize(maxSize int64) FileOption {
+	return func(c *FileClient) {
+		c.maxSize = maxSize
+	}
+}
+
+// WithFileMaxAge The age the file is rotated at since it is opened, 0 means no age limit. Default 24h
+func WithFileMaxAge(maxAge time.Duration) FileOption {
+	return func(c *FileClient) {
+		c.maxAge = maxAge
+	}
+}
+
+// WithFileMaxBackups The number of the rotated files to keep, the oldest are removed, 0 means keeping all. Default 7
+func WithFileMaxBackups(maxBackups int) FileOption {
+	return func(c *FileClient) {
+		c.maxBackups = maxBackups
+	}
+}
+
+// NewFileClient The client writing to the file of the path, the directory is created if absent
+func NewFileClient(path string, opts ...FileOption) *FileClient {
+	c := &FileClient{
+		name:       DefaultFilePluginName,
+		path:       path,
+		maxSize:    DefaultFileMaxSize,
+		maxAge:     DefaultFileMaxAge,
+		maxBackups: DefaultFileMaxBackups,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Name plugin name
+func (c *FileClient) Name() string {
+	return c.name
+}
+
+// Init Open the file, so that an unwritable path fails the initialization, the config is not used
+func (c *FileClient) Init(ctx context.Context, config *protoc_cache_server.MetricsInitConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file != nil {
+		return nil
+	}
+	return c.open()
+}
+
+// LogExposure Write each exposure as a line
+func (c *FileClient) LogExposure(ctx context.Context, metadata *Metadata,
+	exposureGroup *protoc_event_server.ExposureGroup) error {
+	return c.write(exposureRecords(metadata, exposureGroup, c.now()))
+}
+
+// LogEvent Write each event as a line
+func (c *FileClient) LogEvent(ctx context.Context, metadata *Metadata,
+	eventGroup *protoc_event_server.EventGroup) error {
+	return c.write(eventRecords(metadata, eventGroup, c.now()))
+}
+
+// LogMonitorEvent Write each monitor event as a line
+func (c *FileClient) LogMonitorEvent(ctx context.Context, metadata *Metadata,
+	monitorEventGroup *protoc_event_server.MonitorEventGroup) error {
+	return c.write(monitorEventRecords(metadata, monitorEventGroup, c.now()))
+}
+
+// SendData Write each row as a line
+func (c *FileClient) SendData(ctx context.Context, metadata *Metadata, data [][]string) error {
+	return c.write(dataRecords(metadata, data, c.now()))
+}
+
+// Close Close the file, it is opened again by the next write
+func (c *FileClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return errors.Wrap(err, "close file")
+}
+
+// write Write the records in one write call, so that the lines of a group are never split across the files
+func (c *FileClient) write(records []*Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, record := range records {
+		err := encoder.Encode(record)
+		if err != nil {
+			return errors.Wrap(err, "json encode")
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.rotateIfNeeded(int64(buffer.Len()))
+	if err != nil {
+		return err
+	}
+	n, err := c.file.Write(buffer.Bytes())
+	c.size += int64(n)
+	return errors.Wrap(err, "write file")
+}
+
+func (c *FileClient) rotateIfNeeded(writeSize int64) error {
+	if c.file == nil {
+		return c.open()
+	}
+	bySize := c.maxSize > 0 && c.size > 0 && c.size+writeSize > c.maxSize
+	byAge := c.maxAge > 0 && c.now().Sub(c.openedAt) >= c.maxAge
+	if !bySize && !byAge {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	if err != nil {
+		return errors.Wrap(err, "close file")
+	}
+	err = os.Rename(c.path, c.backupPath(c.now()))
+	if err != nil {
+		return errors.Wrap(err, "rename file")
+	}
+	err = c.removeOldBackups()
+	if err != nil {
+		return err
+	}
+	return c.open()
+}
+
+func (c *FileClient) open() error {
+	err := os.MkdirAll(filepath.Dir(c.path), 0755)
+	if err != nil {
+		return errors.Wrap(err, "make dir")
+	}
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "open file")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "stat file")
+	}
+	c.file, c.size, c.openedAt = file, info.Size(), c.now()
+	return nil
+}
+
+// backupPath The path the file is renamed to when it is rotated at the time
+func (c *FileClient) backupPath(t time.Time) string {
+	ext := filepath.Ext(c.path)
+	return strings.TrimSuffix(c.path, ext) + "-" + t.Format(backupTimeFormat) + ext
+}
+
+// backupPaths The rotated files from the oldest to the newest
+func (c *FileClient) backupPaths() ([]string, error) {
+	ext := filepath.Ext(c.path)
+	paths, err := filepath.Glob(strings.TrimSuffix(c.path, ext) + "-*" + ext)
+	if err != nil {
+		return nil, errors.Wrap(err, "glob backups")
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (c *FileClient) removeOldBackups() error {
+	if c.maxBackups <= 0 {
+		return nil
+	}
+	paths, err := c.backupPaths()
+	if err != nil {
+		return err
+	}
+	for len(paths) > c.maxBackups {
+		err = os.Remove(paths[0])
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "remove backup")
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
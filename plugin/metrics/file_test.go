@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/protoc_event_server"
+)
+
+func readRecords(t *testing.T, path string) []*Record {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer file.Close()
+	var records []*Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := &Record{}
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			t.Fatalf("unmarshal %s: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestFileClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "abc", "exposures.jsonl")
+	c := NewFileClient(path)
+	defer c.Close()
+	if err := c.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	metadata := &Metadata{MetricsPluginName: c.Name(), TableName: "t", TableID: "1", Token: "secret"}
+	err := c.LogExposure(context.Background(), metadata, &protoc_event_server.ExposureGroup{
+		Exposures: []*protoc_event_server.Exposure{{UnitId: "u1", GroupId: 1}, {UnitId: "u2", GroupId: 2}}})
+	if err != nil {
+		t.Fatalf("LogExposure() error = %v", err)
+	}
+	err = c.LogEvent(context.Background(), metadata, &protoc_event_server.EventGroup{
+		Events: []*protoc_event_server.Event{{EventName: "click"}}})
+	if err != nil {
+		t.Fatalf("LogEvent() error = %v", err)
+	}
+	err = c.LogMonitorEvent(context.Background(), metadata, &protoc_event_server.MonitorEventGroup{
+		Events: []*protoc_event_server.MonitorEvent{{EventName: "refresh"}}})
+	if err != nil {
+		t.Fatalf("LogMonitorEvent() error = %v", err)
+	}
+	if err = c.SendData(context.Background(), metadata, [][]string{{"a", "b"}}); err != nil {
+		t.Fatalf("SendData() error = %v", err)
+	}
+	records := readRecords(t, path)
+	var types []string
+	for _, record := range records {
+		types = append(types, record.Type)
+		if record.TableName != "t" || record.TableID != "1" {
+			t.Errorf("record table = %s %s, want t 1", record.TableName, record.TableID)
+		}
+	}
+	want := []string{RecordTypeExposure, RecordTypeExposure, RecordTypeEvent, RecordTypeMonitorEvent, RecordTypeData}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("record types = %v, want %v", types, want)
+	}
+	if records[1].Exposure.GetUnitId() != "u2" || !reflect.DeepEqual(records[4].Data, []string{"a", "b"}) {
+		t.Errorf("unexpected records %+v %+v", records[1], records[4])
+	}
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("token must not be written")
+	}
+}
+
+func TestFileClient_rotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "exposures.jsonl")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := NewFileClient(path, WithFileMaxSize(250), WithFileMaxAge(time.Hour), WithFileMaxBackups(2))
+	c.now = func() time.Time { return now }
+	defer c.Close()
+	metadata := &Metadata{TableName: "t"}
+	row := [][]string{{"0123456789012345678901234567890123456789"}}
+	for i := 0; i < 3; i++ { // each line is about 106 bytes, the third write rotates by size
+		if err := c.SendData(context.Background(), metadata, row); err != nil {
+			t.Fatalf("SendData() error = %v", err)
+		}
+		now = now.Add(time.Second)
+	}
+	backups, _ := c.backupPaths()
+	if len(backups) != 1 || len(readRecords(t, backups[0])) != 2 || len(readRecords(t, path)) != 1 {
+		t.Fatalf("rotate by size: backups = %v", backups)
+	}
+	for i := 0; i < 2; i++ { // rotate by age twice, the oldest backup is removed
+		now = now.Add(time.Hour)
+		if err := c.SendData(context.Background(), metadata, row); err != nil {
+			t.Fatalf("SendData() error = %v", err)
+		}
+	}
+	backups, _ = c.backupPaths()
+	want := []string{
+		filepath.Join(dir, "exposures-20240102T040408.000.jsonl"),
+		filepath.Join(dir, "exposures-20240102T050408.000.jsonl"),
+	}
+	if !reflect.DeepEqual(backups, want) {
+		t.Errorf("backups = %v, want %v", backups, want)
+	}
+}
@@ -8,4 +8,8 @@ type Metadata struct {
 	TableID           string `json:"tableId"`           // Specific table ID
 	Token             string `json:"token"`             // Token
 	SamplingInterval  uint32 `json:"samplingInterval"`  // Sampling interval
+	// SamplingMode The sampling mode, empty means SamplingModeRandom
+	SamplingMode SamplingMode `json:"samplingMode,omitempty"`
+	// SamplingSalt The salt of SamplingModeHash, so that the tables sample different units
+	SamplingSalt string `json:"samplingSalt,omitempty"`
 }
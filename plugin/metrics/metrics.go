@@ -8,7 +8,9 @@ import (
 	"runtime"
 	"sync"
 
+	"github.com/abetterchoice/go-sdk/internal/stats"
 	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
 	"github.com/abetterchoice/protoc_cache_server"
 	"github.com/abetterchoice/protoc_event_server"
 	"github.com/pkg/errors"
@@ -77,9 +79,10 @@ var sendDataHook func(metadata *Metadata, data [][]string) error
 // you can register this hook. The hook will be called synchronously when reporting.
 // When the hook function fails to execute, the report will be terminated. If you do not want to terminate,
 // please return err = nil.
-// In fact, users can use this hook to customize monitoring reporting.
+// In fact, users can use this hook to customize monitoring reporting. Use is preferred,
+// a middleware can also rewrite or drop the rows.
 func RegisterSendDataHook(handler func(metadata *Metadata, data [][]string) error) {
-	if handler != nil {
+	if handler == nil {
 		return
 	}
 	sendDataHook = handler
@@ -92,9 +95,10 @@ var logExposureHook func(metadata *Metadata, group *protoc_event_server.Exposure
 // The hook will be called synchronously when reporting.
 // When the hook function fails to execute, the report will be terminated.
 // If you do not want to terminate, please return err = nil.
-// In fact, users can use this hook to customize monitoring reporting
+// In fact, users can use this hook to customize monitoring reporting. Use is preferred,
+// a middleware can also rewrite or drop the exposures
 func RegisterLogExposureHook(handler func(metadata *Metadata, group *protoc_event_server.ExposureGroup) error) {
-	if handler != nil {
+	if handler == nil {
 		return
 	}
 	logExposureHook = handler
@@ -104,97 +108,106 @@ func RegisterLogExposureHook(handler func(metadata *Metadata, group *protoc_even
 // it will be reported Here, the plug-in interface is directly called for reporting. Generally,
 // monitoring reporting components have asynchronous reporting functions,
 // so unified asynchronous reporting is not performed here
-// Report the specified monitoring reporting plug-in metadata.MetricsPluginName according to the specific event
+// Report the specified monitoring reporting plug-in metadata.MetricsPluginName according to the specific event.
+// The rows run through the middlewares, see Use, and a failed delivery is handled by the retry policy,
+// see SetRetryPolicy
 func SendData(ctx context.Context, metadata *Metadata, data [][]string) (err error) {
 	defer func() {
 		recoverErr := recover()
 		if recoverErr != nil {
-			body := make([]byte, 1<<10)
-			runtime.Stack(body, false)
-			log.Errorf("recoverErr:%v\n%s", recoverErr, body)
-			err = fmt.Errorf("recoverErr:%v\n%s", recoverErr, body)
-			return
+			err = panicError(recoverErr)
+			recordPluginError(metadata, stats.MethodSendData)
 		}
 	}()
+	data = SampleData(metadata, data)
 	if len(data) == 0 {
 		return nil
 	}
-	if !SamplingResult(metadata.SamplingInterval) {
-		return nil
-	}
 	if sendDataHook != nil {
 		err := sendDataHook(metadata, data)
 		if err != nil {
+			recordPluginError(metadata, stats.MethodSendData)
 			return errors.Wrap(err, "sendDataHook")
 		}
 	}
-	c, ok := GetClient(metadata.MetricsPluginName)
-	if !ok {
-		return nil
-	}
-	return c.SendData(ctx, metadata, data)
+	return dispatch(ctx, metadata, &Payload{Method: MethodSendData, Data: data})
 }
 
 // LogExposure sends data and reports in multiple ways. If the clientNames passed in have been registered,
 // it will be reported. Here, the plug-in interface is directly called for reporting. Generally,
 // monitoring reporting components have asynchronous reporting functions,
 // so unified asynchronous reporting is not performed here
-// Report the specified monitoring reporting plug-in metadata.MetricsPluginName according to the specific event
+// Report the specified monitoring reporting plug-in metadata.MetricsPluginName according to the specific event.
+// The exposures run through the middlewares, see Use, and a failed delivery is handled by the retry policy,
+// see SetRetryPolicy
 func LogExposure(ctx context.Context, metadata *Metadata, group *protoc_event_server.ExposureGroup) (err error) {
 	defer func() {
 		recoverErr := recover()
 		if recoverErr != nil {
-			body := make([]byte, 1<<10)
-			runtime.Stack(body, false)
-			log.Errorf("recoverErr:%v\n%s", recoverErr, body)
-			err = fmt.Errorf("recoverErr:%v\n%s", recoverErr, body)
-			return
+			err = panicError(recoverErr)
+			recordPluginError(metadata, stats.MethodLogExposure)
 		}
 	}()
-	if group == nil || len(group.Exposures) == 0 {
-		return nil
-	}
-	if !SamplingResult(metadata.SamplingInterval) {
+	group = SampleExposures(metadata, group)
+	if group == nil {
 		return nil
 	}
 	if logExposureHook != nil {
 		err := logExposureHook(metadata, group)
 		if err != nil {
+			recordPluginError(metadata, stats.MethodLogExposure)
 			return errors.Wrap(err, "logExposureHook")
 		}
 	}
-	c, ok := GetClient(metadata.MetricsPluginName)
-	if !ok {
-		return nil
-	}
-	return c.LogExposure(ctx, metadata, group)
+	return dispatch(ctx, metadata, &Payload{Method: MethodLogExposure, Exposures: group})
 }
 
 // LogMonitorEvent Report the specified monitoring reporting plug-in metadata.MetricsPluginName
-// according to the specific event
-func LogMonitorEvent(ctx context.Context, metadata *Metadata, group *protoc_event_server.MonitorEventGroup) (
-	err error) {
-	defer func() {
-		recoverErr := recover()
-		if recoverErr != nil {
-			body := make([]byte, 1<<10)
-			runtime.Stack(body, false)
-			log.Errorf("recoverErr:%v\n%s", recoverErr, body)
-			err = fmt.Errorf("recoverErr:%v\n%s", recoverErr, body)
-			return
-		}
-	}()
+// according to the specific event. The monitor events carry no unit ID, so they are sampled in the random mode,
+// the caller samples them by SampleUnit beforehand for the hash mode. The events run through the middlewares,
+// see Use, and a failed delivery is handled by the retry policy, see SetRetryPolicy
+func LogMonitorEvent(ctx context.Context, metadata *Metadata, group *protoc_event_server.MonitorEventGroup) error {
 	if group == nil || len(group.Events) == 0 {
 		return nil
 	}
 	if !SamplingResult(metadata.SamplingInterval) {
 		return nil
 	}
-	c, ok := GetClient(metadata.MetricsPluginName)
-	if !ok {
+	return dispatch(ctx, metadata, &Payload{Method: MethodLogMonitorEvent, MonitorEvents: group})
+}
+
+// LogEvent Report the conversions and the custom events to the specified monitoring reporting plug-in
+// metadata.MetricsPluginName. The events run through the middlewares, see Use, and a failed delivery
+// is handled by the retry policy, see SetRetryPolicy
+func LogEvent(ctx context.Context, metadata *Metadata, group *protoc_event_server.EventGroup) error {
+	group = SampleEvents(metadata, group)
+	if group == nil {
 		return nil
 	}
-	return c.LogMonitorEvent(ctx, metadata, group)
+	return dispatch(ctx, metadata, &Payload{Method: MethodLogEvent, Events: group})
+}
+
+// panicError The error of the recovered panic, it is logged with the stack
+func panicError(recoverErr interface{}) error {
+	body := make([]byte, 1<<10)
+	runtime.Stack(body, false)
+	log.Errorf("recoverErr:%v\n%s", recoverErr, body)
+	return fmt.Errorf("recoverErr:%v\n%s", recoverErr, body)
+}
+
+// recordPluginError Record the failed delivery in the runtime statistics
+func recordPluginError(metadata *Metadata, method string) {
+	var pluginName string
+	if metadata != nil {
+		pluginName = metadata.MetricsPluginName
+	}
+	stats.IncPluginError(pluginName, method)
+}
+
+// startSpan Start the span of the plugin delivery
+func startSpan(ctx context.Context, name string, metadata *Metadata) (context.Context, trace.Span) {
+	return trace.Start(ctx, name, trace.String(trace.AttributePluginName, metadata.MetricsPluginName),
+		trace.String(trace.AttributeTableName, metadata.TableName))
 }
 
 // SamplingResult Sampling results
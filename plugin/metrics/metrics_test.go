@@ -77,6 +77,9 @@ func TestRegisterSendDataHook(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			RegisterSendDataHook(tt.args.handler)
+			if tt.args.handler != nil && sendDataHook == nil {
+				t.Errorf("RegisterSendDataHook() the hook is not registered")
+			}
 		})
 	}
 }
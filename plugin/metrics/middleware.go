@@ -0,0 +1,123 @@
+// Package metrics TODO
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"github.com/abetterchoice/protoc_event_server"
+)
+
+// Payload The records handed to the plugin, the field of Method is set
+type Payload struct {
+	// Method MethodLogExposure, MethodSendData, MethodLogMonitorEvent or MethodLogEvent
+	Method string
+	// Exposures The payload of LogExposure
+	Exposures *protoc_event_server.ExposureGroup
+	// MonitorEvents The payload of LogMonitorEvent
+	MonitorEvents *protoc_event_server.MonitorEventGroup
+	// Events The payload of LogEvent
+	Events *protoc_event_server.EventGroup
+	// Data The payload of SendData
+	Data [][]string
+}
+
+// Len The number of the records of the payload
+func (p *Payload) Len() int {
+	switch p.Method {
+	case MethodLogExposure:
+		if p.Exposures != nil {
+			return len(p.Exposures.Exposures)
+		}
+	case MethodLogMonitorEvent:
+		if p.MonitorEvents != nil {
+			return len(p.MonitorEvents.Events)
+		}
+	case MethodLogEvent:
+		if p.Events != nil {
+			return len(p.Events.Events)
+		}
+	case MethodSendData:
+		return len(p.Data)
+	}
+	return 0
+}
+
+// deliverTo Call the method of the plugin with the payload
+func (p *Payload) deliverTo(ctx context.Context, c Client, metadata *Metadata) error {
+	switch p.Method {
+	case MethodLogExposure:
+		return c.LogExposure(ctx, metadata, p.Exposures)
+	case MethodLogMonitorEvent:
+		return c.LogMonitorEvent(ctx, metadata, p.MonitorEvents)
+	case MethodLogEvent:
+		return c.LogEvent(ctx, metadata, p.Events)
+	case MethodSendData:
+		return c.SendData(ctx, metadata, p.Data)
+	}
+	return Permanent(errUnknownMethod)
+}
+
+// Handler Hand the payload to the next middleware, the last one hands it to the plugin of metadata.MetricsPluginName
+type Handler func(ctx context.Context, metadata *Metadata, payload *Payload) error
+
+// Middleware Wrap the next handler. A middleware can enrich or rewrite the metadata and the payload
+// before calling next, for example to add the request ID or the app version to the records,
+// or drop the payload by returning nil without calling next. The payload of the caller is shared,
+// so a middleware rewriting it should copy the records first. An error is returned to the caller of the
+// delivery, Permanent(err) keeps it out of the retries and the exposure spool
+type Middleware func(next Handler) Handler
+
+var (
+	middlewareMutex sync.RWMutex
+	middlewares     []Middleware
+	chain           Handler = deliverPayload
+)
+
+// Use Append the middlewares to the chain of LogExposure, SendData, LogMonitorEvent and LogEvent.
+// The middlewares run in the order they are added, after the sampling and before the plugin.
+// A retried delivery does not run the middlewares again
+func Use(middleware ...Middleware) {
+	middlewareMutex.Lock()
+	defer middlewareMutex.Unlock()
+	for _, m := range middleware {
+		if m != nil {
+			middlewares = append(middlewares, m)
+		}
+	}
+	chain = deliverPayload
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		chain = middlewares[i](chain)
+	}
+}
+
+// dispatch Run the payload through the middlewares to the plugin,
+// the panic of a middleware or the plugin is returned as the error
+func dispatch(ctx context.Context, metadata *Metadata, payload *Payload) (err error) {
+	defer func() {
+		recoverErr := recover()
+		if recoverErr != nil {
+			err = panicError(recoverErr)
+			recordPluginError(metadata, payload.Method)
+		}
+	}()
+	middlewareMutex.RLock()
+	handler := chain
+	middlewareMutex.RUnlock()
+	return handler(ctx, metadata, payload)
+}
+
+// deliverPayload The end of the chain, hand the payload to the plugin with the retry policy
+func deliverPayload(ctx context.Context, metadata *Metadata, payload *Payload) error {
+	if payload == nil || payload.Len() == 0 { // emptied by a middleware
+		return nil
+	}
+	c, ok := GetClient(metadata.MetricsPluginName)
+	if !ok {
+		return nil
+	}
+	letter := &DeadLetter{Metadata: metadata, Payload: *payload}
+	return deliver(ctx, letter, func(ctx context.Context) error {
+		return letter.Payload.deliverTo(ctx, c, letter.Metadata)
+	})
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/pkg/errors"
+)
+
+func setupMiddleware(t *testing.T, middleware ...Middleware) *flakyClient {
+	client := &flakyClient{}
+	RegisterClient(client)
+	Use(middleware...)
+	t.Cleanup(func() {
+		middlewareMutex.Lock()
+		middlewares = nil
+		chain = deliverPayload
+		middlewareMutex.Unlock()
+		clientFactory = make(map[string]Client)
+	})
+	return client
+}
+
+func TestUse(t *testing.T) {
+	var order []string
+	var got *Payload
+	tag := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, metadata *Metadata, payload *Payload) error {
+				order = append(order, name)
+				return next(ctx, metadata, payload)
+			}
+		}
+	}
+	enrich := func(next Handler) Handler {
+		return func(ctx context.Context, metadata *Metadata, payload *Payload) error {
+			exposures := make([]*protoc_event_server.Exposure, 0, len(payload.Exposures.Exposures))
+			for _, exposure := range payload.Exposures.Exposures {
+				exposures = append(exposures, &protoc_event_server.Exposure{
+					UnitId:    exposure.UnitId,
+					ExtraData: map[string]string{"app_version": "1.2.0"},
+				})
+			}
+			payload.Exposures = &protoc_event_server.ExposureGroup{Exposures: exposures}
+			got = payload
+			return next(ctx, metadata, payload)
+		}
+	}
+	client := setupMiddleware(t, tag("first"), tag("second"), enrich)
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	group := &protoc_event_server.ExposureGroup{Exposures: []*protoc_event_server.Exposure{{UnitId: "u1"}}}
+	if err := LogExposure(context.Background(), metadata, group); err != nil {
+		t.Fatalf("LogExposure() error = %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("the middlewares run in the order %v, want [first second]", order)
+	}
+	if got == nil || got.Method != MethodLogExposure || got.Exposures.Exposures[0].ExtraData["app_version"] != "1.2.0" {
+		t.Errorf("unexpected payload %+v", got)
+	}
+	if group.Exposures[0].ExtraData != nil {
+		t.Errorf("the exposures of the caller should not be modified")
+	}
+	if client.attemptCount() != 1 {
+		t.Errorf("attempts = %d, want 1", client.attemptCount())
+	}
+}
+
+func TestUse_drop(t *testing.T) {
+	drop := func(next Handler) Handler {
+		return func(ctx context.Context, metadata *Metadata, payload *Payload) error {
+			if payload.Method == MethodSendData {
+				return nil
+			}
+			return next(ctx, metadata, payload)
+		}
+	}
+	client := setupMiddleware(t, drop)
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	if err := SendData(context.Background(), metadata, [][]string{{"1"}}); err != nil {
+		t.Fatalf("SendData() error = %v", err)
+	}
+	if client.attemptCount() != 0 {
+		t.Errorf("the dropped rows should not reach the plugin, attempts = %d", client.attemptCount())
+	}
+	group := &protoc_event_server.MonitorEventGroup{Events: []*protoc_event_server.MonitorEvent{{}}}
+	if err := LogMonitorEvent(context.Background(), metadata, group); err != nil {
+		t.Fatalf("LogMonitorEvent() error = %v", err)
+	}
+	if client.attemptCount() != 1 {
+		t.Errorf("attempts = %d, want 1", client.attemptCount())
+	}
+}
+
+func TestUse_error(t *testing.T) {
+	reject := func(next Handler) Handler {
+		return func(ctx context.Context, metadata *Metadata, payload *Payload) error {
+			if payload.Method == MethodLogEvent {
+				return errors.New("rejected")
+			}
+			panic("middleware panic")
+		}
+	}
+	client := setupMiddleware(t, reject)
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	group := &protoc_event_server.EventGroup{Events: []*protoc_event_server.Event{{EventName: "purchase"}}}
+	if err := LogEvent(context.Background(), metadata, group); err == nil {
+		t.Errorf("LogEvent() the error of the middleware should be returned")
+	}
+	if err := SendData(context.Background(), metadata, [][]string{{"1"}}); err == nil {
+		t.Errorf("SendData() the panic of the middleware should be returned as the error")
+	}
+	if client.attemptCount() != 0 {
+		t.Errorf("attempts = %d, want 0", client.attemptCount())
+	}
+}
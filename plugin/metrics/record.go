@@ -0,0 +1,82 @@
+// Package metrics TODO
+package metrics
+
+import (
+	"time"
+
+	"github.com/abetterchoice/protoc_event_server"
+)
+
+// The types of the Record
+const (
+	RecordTypeExposure     = "exposure"
+	RecordTypeEvent        = "event"
+	RecordTypeMonitorEvent = "monitorEvent"
+	RecordTypeData         = "data"
+)
+
+// Record One exposure, event, monitor event or data row written by the built-in clients FileClient and
+// WebhookClient, exactly one of Exposure, Event, MonitorEvent and Data is set according to Type.
+// The token of the metadata is never written
+type Record struct {
+	Type         string                            `json:"type"`
+	Time         int64                             `json:"time"` // Unix milliseconds when the record is received
+	TableName    string                            `json:"tableName,omitempty"`
+	TableID      string                            `json:"tableId,omitempty"`
+	Exposure     *protoc_event_server.Exposure     `json:"exposure,omitempty"`
+	Event        *protoc_event_server.Event        `json:"event,omitempty"`
+	MonitorEvent *protoc_event_server.MonitorEvent `json:"monitorEvent,omitempty"`
+	Data         []string                          `json:"data,omitempty"`
+}
+
+func newRecord(recordType string, metadata *Metadata, now time.Time) *Record {
+	record := &Record{Type: recordType, Time: now.UnixNano() / int64(time.Millisecond)}
+	if metadata != nil {
+		record.TableName, record.TableID = metadata.TableName, metadata.TableID
+	}
+	return record
+}
+
+// exposureRecords One record for each exposure of the group
+func exposureRecords(metadata *Metadata, group *protoc_event_server.ExposureGroup, now time.Time) []*Record {
+	var records = make([]*Record, 0, len(group.GetExposures()))
+	for _, exposure := range group.GetExposures() {
+		record := newRecord(RecordTypeExposure, metadata, now)
+		record.Exposure = exposure
+		records = append(records, record)
+	}
+	return records
+}
+
+// eventRecords One record for each event of the group
+func eventRecords(metadata *Metadata, group *protoc_event_server.EventGroup, now time.Time) []*Record {
+	var records = make([]*Record, 0, len(group.GetEvents()))
+	for _, event := range group.GetEvents() {
+		record := newRecord(RecordTypeEvent, metadata, now)
+		record.Event = event
+		records = append(records, record)
+	}
+	return records
+}
+
+// monitorEventRecords One record for each monitor event of the group
+func monitorEventRecords(metadata *Metadata, group *protoc_event_server.MonitorEventGroup, now time.Time) []*Record {
+	var records = make([]*Record, 0, len(group.GetEvents()))
+	for _, event := range group.GetEvents() {
+		record := newRecord(RecordTypeMonitorEvent, metadata, now)
+		record.MonitorEvent = event
+		records = append(records, record)
+	}
+	return records
+}
+
+// dataRecords One record for each row of the data
+func dataRecords(metadata *Metadata, data [][]string, now time.Time) []*Record {
+	var records = make([]*Record, 0, len(data))
+	for _, row := range data {
+		record := newRecord(RecordTypeData, metadata, now)
+		record.Data = row
+		records = append(records, record)
+	}
+	return records
+}
@@ -0,0 +1,316 @@
+// Package metrics TODO
+package metrics
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
+	"github.com/pkg/errors"
+)
+
+// The methods of the deliveries
+const (
+	MethodSendData        = stats.MethodSendData
+	MethodLogExposure     = stats.MethodLogExposure
+	MethodLogMonitorEvent = stats.MethodLogMonitorEvent
+	MethodLogEvent        = stats.MethodLogEvent
+)
+
+// The defaults of the retry policy
+const (
+	DefaultRetryMinBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff = 10 * time.Second
+	DefaultRetryMaxPending = 1000
+)
+
+// RetryPolicy The policy of the failed deliveries of LogExposure, SendData, LogMonitorEvent and LogEvent.
+// The first attempt is made by the caller, the retries are made in the background with exponential backoff,
+// so the payload must not be modified after it is handed to the plugin
+type RetryPolicy struct {
+	// MaxAttempts The attempts of a delivery including the first one, 1 means no retry
+	MaxAttempts int
+	// MinBackoff The wait before the first retry, it is doubled after each failed retry. Default 100ms
+	MinBackoff time.Duration
+	// MaxBackoff The cap of the wait between the retries. Default 10s
+	MaxBackoff time.Duration
+	// MaxPending The deliveries being retried at most, beyond it the error of the first attempt is
+	// returned to the caller instead. Default 1000
+	MaxPending int
+	// Retryable The classifier of the errors, the permanent ones are not retried. Default IsRetryable
+	Retryable func(err error) bool
+}
+
+// DeadLetter The delivery given up on, either its error is permanent or its attempts are used up
+// and the spool handler does not keep it
+type DeadLetter struct {
+	Metadata *Metadata
+	// Payload The method and the records, as rewritten by the middlewares
+	Payload
+	// Attempts The attempts made
+	Attempts int
+	// Err The error of the last attempt
+	Err error
+}
+
+// DeadLetterHandler The handler of the deliveries given up on, for example to archive them or to alert.
+// It is called synchronously by the caller of the delivery or by the retry worker
+type DeadLetterHandler func(letter *DeadLetter)
+
+// SpoolHandler The durable sink of the deliveries failed with a retryable error and not retried any more.
+// The letter holds the payload as sampled and rewritten by the middlewares, it is delivered again by Redeliver.
+// An error means the delivery is not kept. The exposure spool of the SDK registers it, see abc.WithExposureSpool
+type SpoolHandler func(letter *DeadLetter) error
+
+var errUnknownMethod = errors.New("unknown method")
+
+var (
+	deliveryMutex     sync.RWMutex
+	retryPolicy       *RetryPolicy // nil means no retry
+	deadLetterHandler DeadLetterHandler
+	spoolHandler      SpoolHandler
+	pendingRetries    int64
+)
+
+// SetRetryPolicy Retry the failed deliveries in the background with the policy, nil disables the retries.
+// With the spool handler registered, see RegisterSpoolHandler and abc.WithExposureSpool, a delivery whose attempts
+// are used up with a retryable error is spooled instead of being handed to the dead letter handler,
+// and so is a retryable failure beyond MaxPending. The permanent errors always go to the dead letter handler
+func SetRetryPolicy(policy *RetryPolicy) error {
+	if policy == nil {
+		deliveryMutex.Lock()
+		retryPolicy = nil
+		deliveryMutex.Unlock()
+		return nil
+	}
+	if policy.MaxAttempts < 1 {
+		return errors.Errorf("max attempts should be positive")
+	}
+	p := *policy
+	if p.MinBackoff == 0 {
+		p.MinBackoff = DefaultRetryMinBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if p.MinBackoff < 0 || p.MaxBackoff < p.MinBackoff {
+		return errors.Errorf("retry backoff should be positive and the max not less than the min")
+	}
+	if p.MaxPending == 0 {
+		p.MaxPending = DefaultRetryMaxPending
+	}
+	if p.MaxPending < 0 {
+		return errors.Errorf("max pending should be positive")
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsRetryable
+	}
+	deliveryMutex.Lock()
+	retryPolicy = &p
+	deliveryMutex.Unlock()
+	return nil
+}
+
+// RegisterDeadLetterHandler Register the handler of the deliveries given up on, nil removes it
+func RegisterDeadLetterHandler(handler DeadLetterHandler) {
+	deliveryMutex.Lock()
+	defer deliveryMutex.Unlock()
+	deadLetterHandler = handler
+}
+
+// RegisterSpoolHandler Register the sink of the retryable failures, nil removes it
+func RegisterSpoolHandler(handler SpoolHandler) {
+	deliveryMutex.Lock()
+	defer deliveryMutex.Unlock()
+	spoolHandler = handler
+}
+
+// permanentError The error marked as not retryable
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap Support errors.Is and errors.As
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent Mark the error of the plugin as not retryable, for example an invalid payload
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsRetryable The default classifier, all the errors are retryable except the ones marked by Permanent
+// and the cancellation of the context
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// IsPermanent Whether the error is permanent by the classifier of the retry policy if any, otherwise by IsRetryable.
+// The deliveries failed with a permanent error are handed to the dead letter handler
+func IsPermanent(err error) bool {
+	if err == nil {
+		return false
+	}
+	deliveryMutex.RLock()
+	policy := retryPolicy
+	deliveryMutex.RUnlock()
+	if policy == nil {
+		return !IsRetryable(err)
+	}
+	return !policy.Retryable(err)
+}
+
+// deliver Make the first attempt of the delivery. When it fails, a retryable error is retried in the background
+// and nil is returned, or handed to the spool handler and returned to the caller if the policy does not retry it.
+// A permanent error is handed to the dead letter handler and returned
+func deliver(ctx context.Context, letter *DeadLetter, call func(ctx context.Context) error) error {
+	err := attempt(ctx, letter, call)
+	letter.Attempts = 1
+	if err == nil {
+		return nil
+	}
+	letter.Err = err
+	if IsPermanent(err) {
+		handleDeadLetter(letter)
+		return err
+	}
+	deliveryMutex.RLock()
+	policy := retryPolicy
+	deliveryMutex.RUnlock()
+	if policy == nil || policy.MaxAttempts < 2 {
+		spoolLetter(letter)
+		return err
+	}
+	if atomic.AddInt64(&pendingRetries, 1) > int64(policy.MaxPending) {
+		atomic.AddInt64(&pendingRetries, -1)
+		spoolLetter(letter)
+		return err
+	}
+	go policy.retry(letter, call)
+	return nil
+}
+
+// retry Retry the delivery with backoff until it succeeds, the error is permanent or the attempts are used up.
+// The delivery whose attempts are used up goes to the spool handler, or to the dead letter handler without one
+func (p *RetryPolicy) retry(letter *DeadLetter, call func(ctx context.Context) error) {
+	defer atomic.AddInt64(&pendingRetries, -1)
+	backoff := p.MinBackoff
+	for letter.Attempts < p.MaxAttempts {
+		time.Sleep(backoff)
+		stats.IncPluginRetry(letter.Metadata.MetricsPluginName, letter.Method)
+		err := attempt(context.Background(), letter, call)
+		letter.Attempts++
+		if err == nil {
+			return
+		}
+		letter.Err = err
+		if !p.Retryable(err) {
+			handleDeadLetter(letter)
+			return
+		}
+		backoff *= 2
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+	// the attempts are used up with a retryable error, the spool handler keeps the delivery if any
+	if !spoolLetter(letter) {
+		handleDeadLetter(letter)
+	}
+}
+
+// attempt One attempt of the delivery, the panic of the plugin is returned as the error
+func attempt(ctx context.Context, letter *DeadLetter, call func(ctx context.Context) error) (err error) {
+	defer func() {
+		recoverErr := recover()
+		if recoverErr != nil {
+			err = panicError(recoverErr)
+		}
+		if err != nil {
+			recordPluginError(letter.Metadata, letter.Method)
+		}
+	}()
+	ctx, span := startSpan(ctx, spanNames[letter.Method], letter.Metadata)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	return call(ctx)
+}
+
+// Redeliver Deliver the letter kept by the spool handler to the plugin again. The payload is sampled and rewritten
+// by the middlewares already, so it goes to the plugin directly without the sampling, the hooks, the middlewares
+// and the retries
+func Redeliver(ctx context.Context, letter *DeadLetter) error {
+	c, ok := GetClient(letter.Metadata.MetricsPluginName)
+	if !ok {
+		return nil
+	}
+	return attempt(ctx, letter, func(ctx context.Context) error {
+		return letter.Payload.deliverTo(ctx, c, letter.Metadata)
+	})
+}
+
+var spanNames = map[string]string{
+	MethodSendData:        trace.SpanSendData,
+	MethodLogExposure:     trace.SpanLogExposure,
+	MethodLogMonitorEvent: trace.SpanLogMonitorEvent,
+	MethodLogEvent:        trace.SpanLogEvent,
+}
+
+// handleDeadLetter Hand the delivery given up on to the handler, it is logged if there is no handler
+func handleDeadLetter(letter *DeadLetter) {
+	stats.IncPluginDeadLetter(letter.Metadata.MetricsPluginName, letter.Method)
+	deliveryMutex.RLock()
+	handler := deadLetterHandler
+	deliveryMutex.RUnlock()
+	if handler == nil {
+		log.WarnContext(context.Background(), "delivery is dropped", log.F("plugin", letter.Metadata.MetricsPluginName),
+			log.F("method", letter.Method), log.F("attempts", letter.Attempts), log.Err(letter.Err))
+		return
+	}
+	defer func() {
+		recoverErr := recover()
+		if recoverErr != nil {
+			_ = panicError(recoverErr)
+		}
+	}()
+	handler(letter)
+}
+
+// spoolLetter Hand the delivery to the spool handler, false if there is no handler or it does not keep the delivery
+func spoolLetter(letter *DeadLetter) (spooled bool) {
+	deliveryMutex.RLock()
+	handler := spoolHandler
+	deliveryMutex.RUnlock()
+	if handler == nil {
+		return false
+	}
+	defer func() {
+		recoverErr := recover()
+		if recoverErr != nil {
+			_ = panicError(recoverErr)
+			spooled = false
+		}
+	}()
+	return handler(letter) == nil
+}
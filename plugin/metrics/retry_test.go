@@ -0,0 +1,281 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/protoc_cache_server"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/pkg/errors"
+)
+
+// flakyClient The plugin failing with the errors in order, then succeeding
+type flakyClient struct {
+	mu       sync.Mutex
+	errs     []error
+	attempts int
+}
+
+func (c *flakyClient) Name() string {
+	return "flaky"
+}
+
+func (c *flakyClient) Init(ctx context.Context, config *protoc_cache_server.MetricsInitConfig) error {
+	return nil
+}
+
+func (c *flakyClient) LogExposure(ctx context.Context, metadata *Metadata,
+	exposureGroup *protoc_event_server.ExposureGroup) error {
+	return c.next()
+}
+
+func (c *flakyClient) LogEvent(ctx context.Context, metadata *Metadata,
+	eventGroup *protoc_event_server.EventGroup) error {
+	return nil
+}
+
+func (c *flakyClient) LogMonitorEvent(ctx context.Context, metadata *Metadata,
+	monitorEventGroup *protoc_event_server.MonitorEventGroup) error {
+	return c.next()
+}
+
+func (c *flakyClient) SendData(ctx context.Context, metadata *Metadata, data [][]string) error {
+	return c.next()
+}
+
+func (c *flakyClient) next() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func (c *flakyClient) attemptCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts
+}
+
+func setupRetry(t *testing.T, client *flakyClient, policy *RetryPolicy) chan *DeadLetter {
+	RegisterClient(client)
+	if err := SetRetryPolicy(policy); err != nil {
+		t.Fatalf("SetRetryPolicy() error = %v", err)
+	}
+	letters := make(chan *DeadLetter, 1)
+	RegisterDeadLetterHandler(func(letter *DeadLetter) { letters <- letter })
+	t.Cleanup(func() {
+		_ = SetRetryPolicy(nil)
+		RegisterDeadLetterHandler(nil)
+		clientFactory = make(map[string]Client)
+	})
+	return letters
+}
+
+func TestSetRetryPolicy(t *testing.T) {
+	defer func() { _ = SetRetryPolicy(nil) }()
+	invalid := []*RetryPolicy{
+		{MaxAttempts: 0},
+		{MaxAttempts: 3, MinBackoff: time.Second, MaxBackoff: time.Millisecond},
+		{MaxAttempts: 3, MaxPending: -1},
+	}
+	for _, policy := range invalid {
+		if err := SetRetryPolicy(policy); err == nil {
+			t.Errorf("SetRetryPolicy(%+v) should fail", policy)
+		}
+	}
+	if err := SetRetryPolicy(&RetryPolicy{MaxAttempts: 3}); err != nil {
+		t.Fatalf("SetRetryPolicy() error = %v", err)
+	}
+	if retryPolicy.MinBackoff != DefaultRetryMinBackoff || retryPolicy.MaxPending != DefaultRetryMaxPending ||
+		retryPolicy.Retryable == nil {
+		t.Errorf("the defaults are not applied, got %+v", retryPolicy)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(errors.New("timeout")) {
+		t.Errorf("a plain error should be retryable")
+	}
+	if IsRetryable(errors.Wrap(Permanent(errors.New("invalid")), "send")) {
+		t.Errorf("a wrapped permanent error should not be retryable")
+	}
+	if IsRetryable(context.Canceled) || IsRetryable(nil) {
+		t.Errorf("the cancellation and nil should not be retryable")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	client := &flakyClient{errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+	letters := setupRetry(t, client, &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond})
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	// the retries are made in the background
+	if err := SendData(context.Background(), metadata, [][]string{{"1"}}); err != nil {
+		t.Fatalf("SendData() error = %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for client.attemptCount() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if client.attemptCount() != 3 {
+		t.Fatalf("attempts = %d, want 3", client.attemptCount())
+	}
+	select {
+	case letter := <-letters:
+		t.Errorf("the delivery succeeds at the third attempt, got the dead letter %+v", letter)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestRetry_deadLetter(t *testing.T) {
+	client := &flakyClient{errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+	letters := setupRetry(t, client, &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	group := &protoc_event_server.ExposureGroup{Exposures: []*protoc_event_server.Exposure{{UnitId: "u1"}}}
+	if err := LogExposure(context.Background(), metadata, group); err != nil {
+		t.Fatalf("LogExposure() error = %v", err)
+	}
+	select {
+	case letter := <-letters:
+		if letter.Method != MethodLogExposure || letter.Exposures != group || letter.Attempts != 2 ||
+			letter.Err == nil {
+			t.Errorf("unexpected dead letter %+v", letter)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the attempts are used up, the dead letter is expected")
+	}
+}
+
+func TestRetry_permanent(t *testing.T) {
+	client := &flakyClient{errs: []error{Permanent(errors.New("invalid payload"))}}
+	letters := setupRetry(t, client, &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond})
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	group := &protoc_event_server.MonitorEventGroup{Events: []*protoc_event_server.MonitorEvent{{}}}
+	// the permanent error is returned and handed to the dead letter handler at once
+	if err := LogMonitorEvent(context.Background(), metadata, group); !IsPermanent(err) {
+		t.Fatalf("LogMonitorEvent() error = %v, want a permanent error", err)
+	}
+	select {
+	case letter := <-letters:
+		if letter.MonitorEvents != group || letter.Attempts != 1 {
+			t.Errorf("unexpected dead letter %+v", letter)
+		}
+	default:
+		t.Fatalf("the dead letter is expected")
+	}
+	if client.attemptCount() != 1 {
+		t.Errorf("attempts = %d, want 1", client.attemptCount())
+	}
+}
+
+func TestRetry_noPolicy(t *testing.T) {
+	client := &flakyClient{errs: []error{errors.New("unavailable")}}
+	letters := setupRetry(t, client, nil)
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	// one attempt, the error is returned to the caller
+	if err := SendData(context.Background(), metadata, [][]string{{"1"}}); err == nil {
+		t.Fatalf("SendData() should fail")
+	}
+	if len(letters) != 0 || client.attemptCount() != 1 {
+		t.Errorf("dead letters = %d, attempts = %d", len(letters), client.attemptCount())
+	}
+}
+
+func TestRegisterSpoolHandler(t *testing.T) {
+	dropU2 := func(next Handler) Handler {
+		return func(ctx context.Context, metadata *Metadata, payload *Payload) error {
+			var kept []*protoc_event_server.Exposure
+			for _, exposure := range payload.Exposures.Exposures {
+				if exposure.UnitId != "u2" {
+					kept = append(kept, exposure)
+				}
+			}
+			payload.Exposures = &protoc_event_server.ExposureGroup{Exposures: kept}
+			return next(ctx, metadata, payload)
+		}
+	}
+	client := setupMiddleware(t, dropU2)
+	client.errs = []error{errors.New("unavailable")}
+	var spooled []*DeadLetter
+	RegisterSpoolHandler(func(letter *DeadLetter) error {
+		spooled = append(spooled, letter)
+		return nil
+	})
+	t.Cleanup(func() { RegisterSpoolHandler(nil) })
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	group := &protoc_event_server.ExposureGroup{Exposures: []*protoc_event_server.Exposure{{UnitId: "u1"}, {UnitId: "u2"}}}
+	// without a retry policy the failure is spooled and returned
+	if err := LogExposure(context.Background(), metadata, group); err == nil {
+		t.Fatalf("LogExposure() should fail")
+	}
+	if len(spooled) != 1 {
+		t.Fatalf("spooled = %d, want 1", len(spooled))
+	}
+	// the spooled payload is the one rewritten by the middlewares
+	letter := spooled[0]
+	if letter.Method != MethodLogExposure || letter.Len() != 1 || letter.Exposures.Exposures[0].UnitId != "u1" {
+		t.Errorf("unexpected spooled letter %+v", letter)
+	}
+	if err := Redeliver(context.Background(), letter); err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	if client.attemptCount() != 2 {
+		t.Errorf("attempts = %d, want 2", client.attemptCount())
+	}
+
+	// a permanent error is not spooled
+	client.errs = []error{Permanent(errors.New("invalid payload"))}
+	if err := LogExposure(context.Background(), metadata, group); !IsPermanent(err) {
+		t.Fatalf("LogExposure() error = %v, want a permanent error", err)
+	}
+	if len(spooled) != 1 {
+		t.Errorf("spooled = %d, want 1", len(spooled))
+	}
+}
+
+func TestRetry_spool(t *testing.T) {
+	client := &flakyClient{errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+	letters := setupRetry(t, client, &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+	spooled := make(chan *DeadLetter, 1)
+	RegisterSpoolHandler(func(letter *DeadLetter) error {
+		spooled <- letter
+		return nil
+	})
+	t.Cleanup(func() { RegisterSpoolHandler(nil) })
+	metadata := &Metadata{MetricsPluginName: client.Name(), SamplingInterval: 1}
+	if err := SendData(context.Background(), metadata, [][]string{{"1"}}); err != nil {
+		t.Fatalf("SendData() error = %v", err)
+	}
+	// the attempts are used up, the spool keeps the delivery instead of the dead letter handler
+	select {
+	case letter := <-spooled:
+		if letter.Method != MethodSendData || letter.Attempts != 2 || letter.Err == nil {
+			t.Errorf("unexpected spooled letter %+v", letter)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the attempts are used up, the delivery is expected to be spooled")
+	}
+	if len(letters) != 0 {
+		t.Errorf("dead letters = %d, want 0", len(letters))
+	}
+
+	// the delivery the spool does not keep goes to the dead letter handler
+	client.mu.Lock()
+	client.errs = []error{errors.New("unavailable"), errors.New("unavailable")}
+	client.mu.Unlock()
+	RegisterSpoolHandler(func(letter *DeadLetter) error { return errors.New("not spooled") })
+	if err := SendData(context.Background(), metadata, [][]string{{"2"}}); err != nil {
+		t.Fatalf("SendData() error = %v", err)
+	}
+	select {
+	case <-letters:
+	case <-time.After(time.Second):
+		t.Fatalf("the dead letter is expected")
+	}
+}
@@ -0,0 +1,126 @@
+// Package metrics TODO
+package metrics
+
+import (
+	"hash/fnv"
+
+	"github.com/abetterchoice/protoc_event_server"
+)
+
+// SamplingMode How the records are sampled by the sampling interval
+type SamplingMode string
+
+// The sampling modes
+const (
+	// SamplingModeRandom Each call is kept with the probability 1/interval, the default
+	SamplingModeRandom SamplingMode = "random"
+	// SamplingModeHash Each unit ID is kept with the probability 1/interval by its hash with the salt,
+	// so the records of a sampled-in unit are always kept
+	SamplingModeHash SamplingMode = "hash"
+)
+
+// EventUnitIDKey The key of the unit ID in the metadata of an Event, used by the hash sampling of LogEvent
+const EventUnitIDKey = "unit_id"
+
+// HashSamplingResult The sampling result of the unit ID, the same for the same unit ID, salt and interval
+func HashSamplingResult(unitID string, salt string, interval uint32) bool {
+	if interval <= 1 {
+		return interval == 1
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(salt))
+	_, _ = h.Write([]byte{0}) // separator, so that the salt and the unit ID do not run into each other
+	_, _ = h.Write([]byte(unitID))
+	return h.Sum64()%uint64(interval) == 0
+}
+
+// SampleUnit The sampling result of the record of the unit by the mode of metadata,
+// the unit ID is ignored in the random mode
+func SampleUnit(metadata *Metadata, unitID string) bool {
+	if metadata.SamplingMode == SamplingModeHash {
+		return HashSamplingResult(unitID, metadata.SamplingSalt, metadata.SamplingInterval)
+	}
+	return SamplingResult(metadata.SamplingInterval)
+}
+
+// SampleExposures The exposures kept by the sampling of metadata, nil if none is kept.
+// The whole group is kept or dropped in the random mode, each exposure by its unit ID in the hash mode
+func SampleExposures(metadata *Metadata, group *protoc_event_server.ExposureGroup) *protoc_event_server.ExposureGroup {
+	if group == nil || len(group.Exposures) == 0 {
+		return nil
+	}
+	if metadata.SamplingMode != SamplingModeHash {
+		if !SamplingResult(metadata.SamplingInterval) {
+			return nil
+		}
+		return group
+	}
+	var kept = make([]*protoc_event_server.Exposure, 0, len(group.Exposures))
+	for _, exposure := range group.Exposures {
+		if SampleUnit(metadata, exposure.UnitId) {
+			kept = append(kept, exposure)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	if len(kept) == len(group.Exposures) {
+		return group
+	}
+	return &protoc_event_server.ExposureGroup{Exposures: kept}
+}
+
+// SampleData The rows kept by the sampling of metadata, nil if none is kept. The whole rows are kept or dropped
+// in the random mode, each row by its unit ID, the first column, in the hash mode
+func SampleData(metadata *Metadata, data [][]string) [][]string {
+	if len(data) == 0 {
+		return nil
+	}
+	if metadata.SamplingMode != SamplingModeHash {
+		if !SamplingResult(metadata.SamplingInterval) {
+			return nil
+		}
+		return data
+	}
+	var kept = make([][]string, 0, len(data))
+	for _, row := range data {
+		var unitID string
+		if len(row) != 0 {
+			unitID = row[0]
+		}
+		if SampleUnit(metadata, unitID) {
+			kept = append(kept, row)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// SampleEvents The events kept by the sampling of metadata, nil if none is kept. The whole group is kept
+// or dropped in the random mode, each event by its unit ID, the EventUnitIDKey of its metadata, in the hash mode
+func SampleEvents(metadata *Metadata, group *protoc_event_server.EventGroup) *protoc_event_server.EventGroup {
+	if group == nil || len(group.Events) == 0 {
+		return nil
+	}
+	if metadata.SamplingMode != SamplingModeHash {
+		if !SamplingResult(metadata.SamplingInterval) {
+			return nil
+		}
+		return group
+	}
+	var kept = make([]*protoc_event_server.Event, 0, len(group.Events))
+	for _, event := range group.Events {
+		if SampleUnit(metadata, event.Metadata[EventUnitIDKey]) {
+			kept = append(kept, event)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	if len(kept) == len(group.Events) {
+		return group
+	}
+	return &protoc_event_server.EventGroup{Events: kept}
+}
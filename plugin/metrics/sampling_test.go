@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/abetterchoice/protoc_event_server"
+)
+
+func TestHashSamplingResult(t *testing.T) {
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		unitID := "u" + strconv.Itoa(i)
+		result := HashSamplingResult(unitID, "table", 10)
+		if result != HashSamplingResult(unitID, "table", 10) {
+			t.Fatalf("the result of %s is not deterministic", unitID)
+		}
+		if result {
+			kept++
+		}
+	}
+	if kept < 900 || kept > 1100 {
+		t.Errorf("kept = %d, want about 1000", kept)
+	}
+	if !HashSamplingResult("u1", "table", 1) || HashSamplingResult("u1", "table", 0) {
+		t.Errorf("interval 1 keeps all and interval 0 keeps none")
+	}
+	// the tables with different salts sample different units
+	same := 0
+	for i := 0; i < 1000; i++ {
+		unitID := "u" + strconv.Itoa(i)
+		if HashSamplingResult(unitID, "a", 2) == HashSamplingResult(unitID, "b", 2) {
+			same++
+		}
+	}
+	if same > 600 {
+		t.Errorf("same = %d, the salts should be independent", same)
+	}
+}
+
+func TestSampleExposures(t *testing.T) {
+	metadata := &Metadata{SamplingInterval: 2, SamplingMode: SamplingModeHash, SamplingSalt: "table"}
+	group := &protoc_event_server.ExposureGroup{}
+	for i := 0; i < 100; i++ {
+		group.Exposures = append(group.Exposures, &protoc_event_server.Exposure{UnitId: "u" + strconv.Itoa(i%10)})
+	}
+	sampled := SampleExposures(metadata, group)
+	if sampled == nil || len(sampled.Exposures) == 0 || len(sampled.Exposures) == len(group.Exposures) {
+		t.Fatalf("a part of the units should be kept, got %v", sampled)
+	}
+	for _, exposure := range sampled.Exposures {
+		if !HashSamplingResult(exposure.UnitId, "table", 2) {
+			t.Errorf("%s is sampled out", exposure.UnitId)
+		}
+	}
+	// the same units are kept call by call
+	again := SampleExposures(metadata, group)
+	if len(again.Exposures) != len(sampled.Exposures) {
+		t.Errorf("kept %d then %d", len(sampled.Exposures), len(again.Exposures))
+	}
+	if len(group.Exposures) != 100 {
+		t.Errorf("the group of the caller is modified")
+	}
+	// the whole group in the random mode
+	random := &Metadata{SamplingInterval: 1}
+	if SampleExposures(random, group) != group {
+		t.Errorf("the group should be kept as is")
+	}
+	if SampleExposures(&Metadata{SamplingInterval: 0, SamplingMode: SamplingModeHash}, group) != nil {
+		t.Errorf("interval 0 keeps none")
+	}
+}
+
+func TestSampleData(t *testing.T) {
+	metadata := &Metadata{SamplingInterval: 3, SamplingMode: SamplingModeHash, SamplingSalt: "table"}
+	var data [][]string
+	for i := 0; i < 30; i++ {
+		data = append(data, []string{"u" + strconv.Itoa(i), "projectID"})
+	}
+	for _, row := range SampleData(metadata, data) {
+		if !HashSamplingResult(row[0], "table", 3) {
+			t.Errorf("%s is sampled out", row[0])
+		}
+	}
+	events := &protoc_event_server.EventGroup{Events: []*protoc_event_server.Event{
+		{Metadata: map[string]string{EventUnitIDKey: "u1"}},
+	}}
+	sampled := SampleEvents(metadata, events)
+	if (sampled != nil) != HashSamplingResult("u1", "table", 3) {
+		t.Errorf("the event of u1 should follow its hash")
+	}
+}
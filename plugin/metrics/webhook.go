@@ -0,0 +1,454 @@
+// Package metrics TODO
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/protoc_cache_server"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// WebhookFormat The format of the request body of WebhookClient
+type WebhookFormat string
+
+// The formats of WebhookClient
+const (
+	// WebhookFormatJSON The body is {"records":[...]} of Record in JSON
+	WebhookFormatJSON WebhookFormat = "json"
+	// WebhookFormatProtobuf The records are grouped by the type and the table, the body of each group is the
+	// ExposureGroup, EventGroup or MonitorEventGroup in protobuf, and the data rows are sent in JSON since
+	// they have no protobuf message. The type and the table are in the headers X-Abc-Record-Type,
+	// X-Abc-Table-Name and X-Abc-Table-Id
+	WebhookFormatProtobuf WebhookFormat = "protobuf"
+)
+
+// The headers of the protobuf requests of WebhookClient
+const (
+	HeaderRecordType = "X-Abc-Record-Type"
+	HeaderTableName  = "X-Abc-Table-Name"
+	HeaderTableID    = "X-Abc-Table-Id"
+)
+
+// The defaults of WebhookClient
+const (
+	DefaultWebhookPluginName    = "webhook"
+	DefaultWebhookBatchSize     = 500
+	DefaultWebhookFlushInterval = 5 * time.Second
+	DefaultWebhookQueueSize     = 10000
+	DefaultWebhookMaxRetries    = 3
+	DefaultWebhookRetryBackoff  = 500 * time.Millisecond
+	DefaultWebhookTimeout       = 10 * time.Second
+)
+
+// maxWebhookRetryBackoff The limit of the exponential backoff between the retries
+const maxWebhookRetryBackoff = 30 * time.Second
+
+// errWebhookClosed The client is closed, the records are not accepted any more
+var errWebhookClosed = errors.New("webhook client is closed")
+
+var _ Client = (*WebhookClient)(nil)
+
+// WebhookClient The metrics client posting the exposures, events, monitor events and data rows in batches
+// to the URL. The records are queued without blocking the caller and posted by a background goroutine
+// when the batch is full or at the flush interval. A batch is retried with exponential backoff
+// on the network errors, 429 and 5xx, and dropped with an error log after the retries run out.
+// Call Close to post the queued records before the process exits
+//
+// example:
+//
+//	webhookClient := metrics.NewWebhookClient("https://collector.example.com/abc",
+//		metrics.WithWebhookHeader("Authorization", "Bearer "+token))
+//	defer webhookClient.Close()
+//	err := abc.Init(ctx, projectIDList, abc.WithRegisterMetricsPlugin(webhookClient, nil))
+type WebhookClient struct {
+	name          string
+	url           string
+	format        WebhookFormat
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+	maxRetries    int
+	retryBackoff  time.Duration
+	header        http.Header
+	httpClient    *http.Client
+
+	queue     chan *Record
+	flushChan chan chan struct{}
+	closeChan chan struct{}
+	doneChan  chan struct{}
+	closeOnce sync.Once
+	mu        sync.RWMutex // guard closed against the queueing
+	closed    bool
+}
+
+// WebhookOption The option of WebhookClient
+type WebhookOption func(c *WebhookClient)
+
+// WithWebhookPluginName The plugin name of the client, the exposures are delivered to the client
+// whose name is the metricsPluginName of the metrics config. Default webhook
+func WithWebhookPluginName(name string) WebhookOption {
+	return func(c *WebhookClient) {
+		c.name = name
+	}
+}
+
+// WithWebhookFormat The format of the request body. Default WebhookFormatJSON
+func WithWebhookFormat(format WebhookFormat) WebhookOption {
+	return func(c *WebhookClient) {
+		c.format = format
+	}
+}
+
+// WithWebhookBatchSize The number of the records posted in one batch at most. Default 500
+func WithWebhookBatchSize(batchSize int) WebhookOption {
+	return func(c *WebhookClient) {
+		if batchSize > 0 {
+			c.batchSize = batchSize
+		}
+	}
+}
+
+// WithWebhookFlushInterval The interval the incomplete batch is posted at. Default 5s
+func WithWebhookFlushInterval(flushInterval time.Duration) WebhookOption {
+	return func(c *WebhookClient) {
+		if flushInterval > 0 {
+			c.flushInterval = flushInterval
+		}
+	}
+}
+
+// WithWebhookQueueSize The number of the records waiting to be posted at most,
+// the records are rejected with an error when the queue is full. Default 10000
+func WithWebhookQueueSize(queueSize int) WebhookOption {
+	return func(c *WebhookClient) {
+		if queueSize > 0 {
+			c.queueSize = queueSize
+		}
+	}
+}
+
+// WithWebhookRetry The number of the retries of a batch and the backoff before the first retry,
+// the backoff doubles on each retry up to 30s. Default 3 retries and 500ms
+func WithWebhookRetry(maxRetries int, backoff time.Duration) WebhookOption {
+	return func(c *WebhookClient) {
+		c.maxRetries, c.retryBackoff = maxRetries, backoff
+	}
+}
+
+// WithWebhookHeader Add the header to each request, such as the authorization
+func WithWebhookHeader(key, value string) WebhookOption {
+	return func(c *WebhookClient) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithWebhookHTTPClient The http client of the requests. Default the client with 10s timeout
+func WithWebhookHTTPClient(httpClient *http.Client) WebhookOption {
+	return func(c *WebhookClient) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// NewWebhookClient The client posting to the URL, the background goroutine is started at once
+func NewWebhookClient(url string, opts ...WebhookOption) *WebhookClient {
+	c := &WebhookClient{
+		name:          DefaultWebhookPluginName,
+		url:           url,
+		format:        WebhookFormatJSON,
+		batchSize:     DefaultWebhookBatchSize,
+		flushInterval: DefaultWebhookFlushInterval,
+		queueSize:     DefaultWebhookQueueSize,
+		maxRetries:    DefaultWebhookMaxRetries,
+		retryBackoff:  DefaultWebhookRetryBackoff,
+		header:        http.Header{},
+		httpClient:    &http.Client{Timeout: DefaultWebhookTimeout},
+		flushChan:     make(chan chan struct{}),
+		closeChan:     make(chan struct{}),
+		doneChan:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.queue = make(chan *Record, c.queueSize)
+	go c.run()
+	return c
+}
+
+// Name plugin name
+func (c *WebhookClient) Name() string {
+	return c.name
+}
+
+// Init The client is ready once created, the config is not used
+func (c *WebhookClient) Init(ctx context.Context, config *protoc_cache_server.MetricsInitConfig) error {
+	if c.url == "" {
+		return errors.New("webhook url is required")
+	}
+	return nil
+}
+
+// LogExposure Queue each exposure
+func (c *WebhookClient) LogExposure(ctx context.Context, metadata *Metadata,
+	exposureGroup *protoc_event_server.ExposureGroup) error {
+	return c.enqueue(exposureRecords(metadata, exposureGroup, time.Now()))
+}
+
+// LogEvent Queue each event
+func (c *WebhookClient) LogEvent(ctx context.Context, metadata *Metadata,
+	eventGroup *protoc_event_server.EventGroup) error {
+	return c.enqueue(eventRecords(metadata, eventGroup, time.Now()))
+}
+
+// LogMonitorEvent Queue each monitor event
+func (c *WebhookClient) LogMonitorEvent(ctx context.Context, metadata *Metadata,
+	monitorEventGroup *protoc_event_server.MonitorEventGroup) error {
+	return c.enqueue(monitorEventRecords(metadata, monitorEventGroup, time.Now()))
+}
+
+// SendData Queue each row
+func (c *WebhookClient) SendData(ctx context.Context, metadata *Metadata, data [][]string) error {
+	return c.enqueue(dataRecords(metadata, data, time.Now()))
+}
+
+// Flush Post the queued records and wait until they are posted or ctx is done
+func (c *WebhookClient) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case c.flushChan <- done:
+	case <-c.doneChan:
+		return errWebhookClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close Stop accepting the records, post the queued records and stop the background goroutine
+func (c *WebhookClient) Close() error {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		close(c.closeChan)
+	})
+	<-c.doneChan
+	return nil
+}
+
+// enqueue Queue the records without blocking, the records not queued are reported in the error
+func (c *WebhookClient) enqueue(records []*Record) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return errWebhookClosed
+	}
+	for i, record := range records {
+		select {
+		case c.queue <- record:
+		default:
+			return errors.Errorf("webhook queue is full, %d records are dropped", len(records)-i)
+		}
+	}
+	return nil
+}
+
+func (c *WebhookClient) run() {
+	defer close(c.doneChan)
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+	var batch = make([]*Record, 0, c.batchSize)
+	post := func() {
+		if len(batch) == 0 {
+			return
+		}
+		c.post(batch)
+		batch = make([]*Record, 0, c.batchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case record := <-c.queue:
+				batch = append(batch, record)
+				if len(batch) >= c.batchSize {
+					post()
+				}
+			default:
+				post()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case record := <-c.queue:
+			batch = append(batch, record)
+			if len(batch) >= c.batchSize {
+				post()
+			}
+		case <-ticker.C:
+			post()
+		case done := <-c.flushChan:
+			drain()
+			close(done)
+		case <-c.closeChan:
+			drain()
+			return
+		}
+	}
+}
+
+// post Post the batch in the format, the failure is logged since nobody waits for it
+func (c *WebhookClient) post(batch []*Record) {
+	var err error
+	if c.format == WebhookFormatProtobuf {
+		err = c.postProtobuf(batch)
+	} else {
+		err = c.postJSON(batch, nil)
+	}
+	if err != nil {
+		log.ErrorContext(context.Background(), "webhook post fail", log.F("plugin", c.name),
+			log.F("records", len(batch)), log.Err(err))
+	}
+}
+
+func (c *WebhookClient) postJSON(records []*Record, header http.Header) error {
+	body, err := json.Marshal(struct {
+		Records []*Record `json:"records"`
+	}{Records: records})
+	if err != nil {
+		return errors.Wrap(err, "json marshal")
+	}
+	return c.postWithRetry(body, "application/json", header)
+}
+
+// webhookGroupKey The key the records are grouped by in the protobuf format
+type webhookGroupKey struct {
+	recordType string
+	tableName  string
+	tableID    string
+}
+
+func (c *WebhookClient) postProtobuf(batch []*Record) error {
+	var keys []webhookGroupKey
+	var groups = make(map[webhookGroupKey][]*Record)
+	for _, record := range batch {
+		key := webhookGroupKey{recordType: record.Type, tableName: record.TableName, tableID: record.TableID}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], record)
+	}
+	var errs []string
+	for _, key := range keys {
+		header := http.Header{}
+		header.Set(HeaderRecordType, key.recordType)
+		header.Set(HeaderTableName, key.tableName)
+		header.Set(HeaderTableID, key.tableID)
+		err := c.postGroup(key.recordType, groups[key], header)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %v", key.recordType, key.tableName, err))
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Errorf("%v", errs)
+	}
+	return nil
+}
+
+func (c *WebhookClient) postGroup(recordType string, records []*Record, header http.Header) error {
+	var message proto.Message
+	switch recordType {
+	case RecordTypeExposure:
+		group := &protoc_event_server.ExposureGroup{}
+		for _, record := range records {
+			group.Exposures = append(group.Exposures, record.Exposure)
+		}
+		message = group
+	case RecordTypeEvent:
+		group := &protoc_event_server.EventGroup{}
+		for _, record := range records {
+			group.Events = append(group.Events, record.Event)
+		}
+		message = group
+	case RecordTypeMonitorEvent:
+		group := &protoc_event_server.MonitorEventGroup{}
+		for _, record := range records {
+			group.Events = append(group.Events, record.MonitorEvent)
+		}
+		message = group
+	default:
+		return c.postJSON(records, header)
+	}
+	body, err := proto.Marshal(message)
+	if err != nil {
+		return errors.Wrap(err, "proto marshal")
+	}
+	return c.postWithRetry(body, "application/x-protobuf", header)
+}
+
+// postWithRetry Post the body, retry with exponential backoff on the network errors, 429 and 5xx.
+// The retries stop early when the client is closed, so that Close is not held up by an unreachable URL
+func (c *WebhookClient) postWithRetry(body []byte, contentType string, header http.Header) error {
+	backoff := c.retryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		retryable, err = c.postOnce(body, contentType, header)
+		if err == nil || !retryable || attempt >= c.maxRetries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-c.closeChan:
+			return errors.Wrap(err, "closed while retrying")
+		}
+		backoff *= 2
+		if backoff > maxWebhookRetryBackoff {
+			backoff = maxWebhookRetryBackoff
+		}
+	}
+}
+
+// postOnce Post the body once, it returns whether the failure is worth retrying
+func (c *WebhookClient) postOnce(body []byte, contentType string, header http.Header) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, "new request")
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, errors.Wrap(err, "http do")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, errors.Errorf("invalid status code %d", resp.StatusCode)
+}
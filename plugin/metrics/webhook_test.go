@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/golang/protobuf/proto"
+)
+
+// webhookServer The test server recording the requests, it fails the first failures requests with 503
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookServer(failures int) *webhookServer {
+	s := &webhookServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+	}))
+	return s
+}
+
+func (s *webhookServer) received() ([]*http.Request, [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests, s.bodies
+}
+
+func testExposureGroup(unitIDs ...string) *protoc_event_server.ExposureGroup {
+	group := &protoc_event_server.ExposureGroup{}
+	for _, unitID := range unitIDs {
+		group.Exposures = append(group.Exposures, &protoc_event_server.Exposure{UnitId: unitID})
+	}
+	return group
+}
+
+func TestWebhookClient_json(t *testing.T) {
+	server := newWebhookServer(1)
+	defer server.Close()
+	c := NewWebhookClient(server.URL, WithWebhookBatchSize(2), WithWebhookFlushInterval(time.Hour),
+		WithWebhookRetry(2, time.Millisecond), WithWebhookHeader("Authorization", "Bearer t"))
+	if err := c.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	metadata := &Metadata{MetricsPluginName: c.Name(), TableName: "t"}
+	if err := c.LogExposure(context.Background(), metadata, testExposureGroup("u1", "u2", "u3")); err != nil {
+		t.Fatalf("LogExposure() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	requests, bodies := server.received()
+	if len(requests) != 2 { // the batch of u1 and u2 is retried once, u3 is posted by Flush
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if requests[0].Header.Get("Authorization") != "Bearer t" ||
+		requests[0].Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected header %v", requests[0].Header)
+	}
+	var payload struct {
+		Records []*Record `json:"records"`
+	}
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+	if len(payload.Records) != 2 || payload.Records[1].Exposure.GetUnitId() != "u2" ||
+		payload.Records[1].TableName != "t" {
+		t.Errorf("unexpected payload %s", bodies[0])
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := c.SendData(context.Background(), metadata, [][]string{{"a"}}); err != errWebhookClosed {
+		t.Errorf("SendData() after Close error = %v, want %v", err, errWebhookClosed)
+	}
+}
+
+func TestWebhookClient_protobuf(t *testing.T) {
+	server := newWebhookServer(0)
+	defer server.Close()
+	c := NewWebhookClient(server.URL, WithWebhookFormat(WebhookFormatProtobuf), WithWebhookFlushInterval(time.Hour))
+	metadata := &Metadata{MetricsPluginName: c.Name(), TableName: "t", TableID: "1"}
+	_ = c.LogExposure(context.Background(), metadata, testExposureGroup("u1", "u2"))
+	_ = c.SendData(context.Background(), metadata, [][]string{{"a"}})
+	if err := c.Close(); err != nil { // Close posts the queued records
+		t.Fatalf("Close() error = %v", err)
+	}
+	requests, bodies := server.received()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if requests[0].Header.Get(HeaderRecordType) != RecordTypeExposure ||
+		requests[0].Header.Get(HeaderTableID) != "1" ||
+		requests[0].Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("unexpected header %v", requests[0].Header)
+	}
+	group := &protoc_event_server.ExposureGroup{}
+	if err := proto.Unmarshal(bodies[0], group); err != nil {
+		t.Fatalf("proto unmarshal error = %v", err)
+	}
+	if len(group.Exposures) != 2 || group.Exposures[0].UnitId != "u1" {
+		t.Errorf("unexpected exposures %v", group.Exposures)
+	}
+	if requests[1].Header.Get(HeaderRecordType) != RecordTypeData ||
+		requests[1].Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected header %v", requests[1].Header)
+	}
+}
+
+func TestWebhookClient_queueFull(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer server.Close()
+	c := NewWebhookClient(server.URL, WithWebhookBatchSize(1), WithWebhookQueueSize(1), WithWebhookRetry(0, 0))
+	defer c.Close()
+	defer close(block) // unblock the post before Close waits for it
+	metadata := &Metadata{MetricsPluginName: c.Name()}
+	var err error
+	for i := 0; i < 10 && err == nil; i++ { // the goroutine is blocked by the first post, the queue fills up
+		err = c.LogExposure(context.Background(), metadata, testExposureGroup("u"))
+	}
+	if err == nil {
+		t.Errorf("LogExposure() error = nil, want queue full")
+	}
+}
@@ -0,0 +1,144 @@
+// Package trace Tracing plugin, the SDK starts a span through the registered tracer at the evaluation,
+// the DMP tag query, the local cache refresh and the plugin delivery, so that the work of the SDK
+// can be seen in the distributed traces. Nothing is traced by default.
+package trace
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Span names started by the SDK
+const (
+	SpanGetExperiments  = "abc.GetExperiments"  // experiment evaluation
+	SpanGetRemoteConfig = "abc.GetRemoteConfig" // remote configuration and feature flag evaluation
+	SpanGetTagValue     = "abc.dmp.GetTagValue" // DMP tag query of the targeting
+	SpanRefresh         = "abc.cache.Refresh"   // local cache refresh of a project
+	SpanLogExposure     = "abc.metrics.LogExposure"
+	SpanLogMonitorEvent = "abc.metrics.LogMonitorEvent"
+	SpanLogEvent        = "abc.metrics.LogEvent"
+	SpanSendData        = "abc.metrics.SendData"
+)
+
+// Span attribute keys set by the SDK
+const (
+	AttributeProjectID   = "abc.project_id"
+	AttributeLayerKeys   = "abc.layer_keys"   // []string, sorted
+	AttributeGroupIDs    = "abc.group_ids"    // []int64 of the hit groups, sorted
+	AttributeConfigKey   = "abc.config_key"   // the remote configuration key
+	AttributeReason      = "abc.reason"       // the evaluation reason of the remote configuration
+	AttributeVersion     = "abc.version"      // version of the local cache data
+	AttributeIsModified  = "abc.is_modified"  // bool, whether the local cache data is updated by the refresh
+	AttributeTagKey      = "abc.tag_key"      // the DMP tag key
+	AttributeDMPPlatform = "abc.dmp_platform" // int64 DMP platform code
+	AttributePluginName  = "abc.plugin_name"  // the metrics plugin name
+	AttributeTableName   = "abc.table_name"   // the metrics table name
+	AttributeSDKVersion  = "abc.sdk_version"
+)
+
+// Attribute Span attribute, the value is one of string, []string, int64, []int64 or bool
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String string attribute
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// StringSlice []string attribute
+func StringSlice(key string, value []string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int64 int64 attribute
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int64Slice []int64 attribute
+func Int64Slice(key string, value []int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool bool attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span The span started by Tracer
+type Span interface {
+	// SetAttributes Set the attributes of the span
+	SetAttributes(attributes ...Attribute)
+	// RecordError Record the error and mark the span failed, nil error is ignored
+	RecordError(err error)
+	// End End the span, the span is not used any more after End
+	End()
+}
+
+// Tracer Tracing abstract class, it is usually an adapter of the tracing system such as OpenTelemetry
+type Tracer interface {
+	// Start Start the span as a child of the span in ctx, the returned context carries the new span
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+// tracerHolder Wrap the registered tracer, atomic.Value needs the same concrete type for every Store
+type tracerHolder struct {
+	tracer Tracer
+}
+
+// defaultTracer The registered tracer, read on the evaluation path while RegisterTracer may run concurrently
+var defaultTracer atomic.Value
+
+func init() {
+	defaultTracer.Store(tracerHolder{tracer: noopTracer{}})
+}
+
+// RegisterTracer Register the tracer, it should be registered before Init. Nil restores the no-op default
+func RegisterTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	defaultTracer.Store(tracerHolder{tracer: tracer})
+}
+
+func registeredTracer() Tracer {
+	return defaultTracer.Load().(tracerHolder).tracer
+}
+
+// Start Start the span through the registered tracer, ctx is returned as is by the no-op default
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	tracer := registeredTracer()
+	if _, ok := tracer.(noopTracer); ok {
+		return ctx, noopSpan{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, name, attributes...)
+}
+
+// IsEnabled Whether a tracer is registered, used to skip building the attributes when nothing is traced
+func IsEnabled() bool {
+	_, ok := registeredTracer().(noopTracer)
+	return !ok
+}
+
+type noopTracer struct{}
+
+// Start The no-op span
+func (noopTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+// SetAttributes no-op
+func (noopSpan) SetAttributes(attributes ...Attribute) {}
+
+// RecordError no-op
+func (noopSpan) RecordError(err error) {}
+
+// End no-op
+func (noopSpan) End() {}
@@ -0,0 +1,106 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type spanKey struct{}
+
+type mockSpan struct {
+	name       string
+	attributes []Attribute
+	err        error
+	ended      bool
+}
+
+func (s *mockSpan) SetAttributes(attributes ...Attribute) {
+	s.attributes = append(s.attributes, attributes...)
+}
+
+func (s *mockSpan) RecordError(err error) {
+	if err != nil {
+		s.err = err
+	}
+}
+
+func (s *mockSpan) End() {
+	s.ended = true
+}
+
+type mockTracer struct {
+	spans []*mockSpan
+}
+
+func (t *mockTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	span := &mockSpan{name: name, attributes: attributes}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestStart(t *testing.T) {
+	defer RegisterTracer(nil)
+	ctx := context.Background()
+	gotCtx, span := Start(ctx, SpanGetExperiments)
+	assert.Equal(t, ctx, gotCtx)
+	assert.False(t, IsEnabled())
+	span.SetAttributes(String(AttributeProjectID, "123"))
+	span.RecordError(errors.New("mock err"))
+	span.End()
+
+	tracer := &mockTracer{}
+	RegisterTracer(tracer)
+	assert.True(t, IsEnabled())
+	gotCtx, span = Start(nil, SpanRefresh, String(AttributeProjectID, "123"))
+	assert.NotNil(t, gotCtx)
+	assert.Equal(t, span, gotCtx.Value(spanKey{}))
+	span.SetAttributes(Bool(AttributeIsModified, true), Int64(AttributeDMPPlatform, 1),
+		StringSlice(AttributeLayerKeys, []string{"layer"}), Int64Slice(AttributeGroupIDs, []int64{1}))
+	span.RecordError(nil)
+	span.End()
+	assert.Equal(t, []*mockSpan{{
+		name: SpanRefresh,
+		attributes: []Attribute{
+			{Key: AttributeProjectID, Value: "123"},
+			{Key: AttributeIsModified, Value: true},
+			{Key: AttributeDMPPlatform, Value: int64(1)},
+			{Key: AttributeLayerKeys, Value: []string{"layer"}},
+			{Key: AttributeGroupIDs, Value: []int64{1}},
+		},
+		ended: true,
+	}}, tracer.spans)
+
+	RegisterTracer(nil)
+	assert.False(t, IsEnabled())
+}
+
+type stubTracer struct{}
+
+func (stubTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func TestRegisterTracer_concurrent(t *testing.T) {
+	defer RegisterTracer(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterTracer(stubTracer{})
+			RegisterTracer(nil)
+		}()
+		go func() {
+			defer wg.Done()
+			_, span := Start(context.Background(), SpanGetExperiments)
+			span.End()
+			IsEnabled()
+		}()
+	}
+	wg.Wait()
+	assert.False(t, IsEnabled())
+}
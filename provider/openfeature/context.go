@@ -0,0 +1,79 @@
+// Package openfeature implements the OpenFeature provider contract on top of abc.Context,
+// so that services standardized on the OpenFeature evaluation API can evaluate ABC remote configurations
+// and feature flags. Flag keys are remote configuration keys of the project bound to the provider.
+package openfeature
+
+import (
+	"fmt"
+
+	abc "github.com/abetterchoice/go-sdk"
+	of "github.com/open-feature/go-sdk/openfeature"
+)
+
+// Evaluation context keys with special meaning, the targeting key is used as unitID,
+// other attributes are passed as tags for targeting
+const (
+	// DecisionIDKey The key of the decisionID used for hashing, the unitID is used if empty
+	DecisionIDKey = "decisionId"
+	// NewUnitIDKey The key of the newUnitID used when the account system is switching
+	NewUnitIDKey = "newUnitId"
+	// NewDecisionIDKey The key of the newDecisionID used for hashing, the newUnitID is used if empty
+	NewDecisionIDKey = "newDecisionId"
+)
+
+// newUserContext Map the OpenFeature evaluation context into abc.Context,
+// nil is returned if the targeting key is missing
+func newUserContext(evalCtx of.FlattenedContext) abc.Context {
+	unitID, _ := evalCtx[of.TargetingKey].(string)
+	if unitID == "" {
+		return nil
+	}
+	var attributions []abc.Attribution
+	var tags = make(map[string][]string, len(evalCtx))
+	for key, value := range evalCtx {
+		switch key {
+		case of.TargetingKey:
+		case DecisionIDKey:
+			attributions = append(attributions, abc.WithDecisionID(fmt.Sprint(value)))
+		case NewUnitIDKey:
+			attributions = append(attributions, abc.WithNewUnitID(fmt.Sprint(value)))
+		case NewDecisionIDKey:
+			attributions = append(attributions, abc.WithNewDecisionID(fmt.Sprint(value)))
+		default:
+			tagValues, ok := convertTagValues(value)
+			if ok {
+				tags[key] = tagValues
+			}
+		}
+	}
+	if len(tags) > 0 {
+		attributions = append(attributions, abc.WithTags(tags))
+	}
+	return abc.NewUserContext(unitID, attributions...)
+}
+
+// convertTagValues Convert the attribute into tag values, nested objects are not supported
+func convertTagValues(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case []interface{}:
+		var result = make([]string, 0, len(v))
+		for _, item := range v {
+			itemValues, ok := convertTagValues(item)
+			if !ok || len(itemValues) != 1 {
+				return nil, false
+			}
+			result = append(result, itemValues[0])
+		}
+		return result, true
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return []string{fmt.Sprint(v)}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,194 @@
+// Package openfeature implements the OpenFeature provider contract on top of abc.Context,
+// so that services standardized on the OpenFeature evaluation API can evaluate ABC remote configurations
+// and feature flags. Flag keys are remote configuration keys of the project bound to the provider.
+package openfeature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/env"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/pkg/errors"
+)
+
+const (
+	// The reasons of ABC without a standard OpenFeature counterpart are passed through as is
+	overrideReason = of.Reason(env.ReasonOverride)
+	holdoutReason  = of.Reason(env.ReasonHoldout)
+
+	// defaultVariant The variant of the remote configuration default value
+	defaultVariant = "default"
+
+	// Flag metadata keys
+	metadataReason        = "reason"
+	metadataExperimentKey = "experimentKey"
+	metadataLayerKey      = "layerKey"
+	metadataGroupID       = "groupId"
+)
+
+// BooleanEvaluation Evaluate the feature flag and convert its value into bool
+func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool,
+	evalCtx of.FlattenedContext) of.BoolResolutionDetail {
+	config, detail := p.evaluate(ctx, flag, evalCtx, true)
+	if config == nil {
+		return of.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	value, err := config.GetBool()
+	if err != nil {
+		return of.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, "bool", err)}
+	}
+	return of.BoolResolutionDetail{Value: value, ProviderResolutionDetail: detail}
+}
+
+// StringEvaluation Evaluate the remote configuration and return its value as string
+func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultValue string,
+	evalCtx of.FlattenedContext) of.StringResolutionDetail {
+	config, detail := p.evaluate(ctx, flag, evalCtx, false)
+	if config == nil {
+		return of.StringResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	return of.StringResolutionDetail{Value: config.String(), ProviderResolutionDetail: detail}
+}
+
+// FloatEvaluation Evaluate the remote configuration and convert its value into float64
+func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64,
+	evalCtx of.FlattenedContext) of.FloatResolutionDetail {
+	config, detail := p.evaluate(ctx, flag, evalCtx, false)
+	if config == nil {
+		return of.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	value, err := config.GetFloat64()
+	if err != nil {
+		return of.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, "float", err)}
+	}
+	return of.FloatResolutionDetail{Value: value, ProviderResolutionDetail: detail}
+}
+
+// IntEvaluation Evaluate the remote configuration and convert its value into int64
+func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue int64,
+	evalCtx of.FlattenedContext) of.IntResolutionDetail {
+	config, detail := p.evaluate(ctx, flag, evalCtx, false)
+	if config == nil {
+		return of.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	value, err := config.GetInt64()
+	if err != nil {
+		return of.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, "int", err)}
+	}
+	return of.IntResolutionDetail{Value: value, ProviderResolutionDetail: detail}
+}
+
+// ObjectEvaluation Evaluate the remote configuration and decode its json value
+func (p *Provider) ObjectEvaluation(ctx context.Context, flag string, defaultValue interface{},
+	evalCtx of.FlattenedContext) of.InterfaceResolutionDetail {
+	config, detail := p.evaluate(ctx, flag, evalCtx, false)
+	if config == nil {
+		return of.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	var value interface{}
+	err := json.Unmarshal(config.Bytes(), &value)
+	if err != nil {
+		return of.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(flag, "object", err)}
+	}
+	return of.InterfaceResolutionDetail{Value: value, ProviderResolutionDetail: detail}
+}
+
+// evaluate Get the remote configuration, or the feature flag if isFeatureFlag.
+// If the evaluation fails, the returned value is nil and the detail carries the resolution error.
+func (p *Provider) evaluate(ctx context.Context, flag string, evalCtx of.FlattenedContext,
+	isFeatureFlag bool) (*abc.Value, of.ProviderResolutionDetail) {
+	if !abc.IsProjectReady(p.projectID) {
+		return nil, errorDetail(of.NewProviderNotReadyResolutionError(
+			fmt.Sprintf("projectID [%s] is not loaded", p.projectID)))
+	}
+	userCtx := newUserContext(evalCtx)
+	if userCtx == nil {
+		return nil, errorDetail(of.NewTargetingKeyMissingResolutionError("targeting key is required as unitID"))
+	}
+	var err error
+	var result *abc.ConfigResult
+	if isFeatureFlag {
+		var featureFlag *abc.FeatureFlag
+		featureFlag, err = userCtx.GetFeatureFlag(ctx, p.projectID, flag, p.configOptions...)
+		if featureFlag != nil {
+			result = featureFlag.ConfigResult
+		}
+	} else {
+		result, err = userCtx.GetRemoteConfig(ctx, p.projectID, flag, p.configOptions...)
+	}
+	if err != nil {
+		return nil, errorDetail(resolutionError(flag, err))
+	}
+	if result == nil || result.Config == nil || result.Value == nil {
+		return nil, errorDetail(of.NewFlagNotFoundResolutionError(fmt.Sprintf("flag [%s] not found", flag)))
+	}
+	return result.Value, resolutionDetail(result.Config)
+}
+
+// resolutionError Map the evaluation error into the OpenFeature resolution error
+func resolutionError(flag string, err error) of.ResolutionError {
+	switch {
+	case errors.Is(err, env.ErrProjectNotFound):
+		return of.NewProviderNotReadyResolutionError(err.Error())
+	case errors.Is(err, env.ErrRemoteConfigNotFound):
+		return of.NewFlagNotFoundResolutionError(fmt.Sprintf("flag [%s] not found", flag))
+	default:
+		return of.NewGeneralResolutionError(err.Error())
+	}
+}
+
+func errorDetail(resolutionError of.ResolutionError) of.ProviderResolutionDetail {
+	return of.ProviderResolutionDetail{
+		ResolutionError: resolutionError,
+		Reason:          of.ErrorReason,
+	}
+}
+
+func typeMismatch(flag string, typeName string, err error) of.ProviderResolutionDetail {
+	return errorDetail(of.NewTypeMismatchResolutionError(
+		fmt.Sprintf("value of flag [%s] is not %s: %v", flag, typeName, err)))
+}
+
+// resolutionDetail Map the reason and the bound experiment group of the configuration into
+// the OpenFeature reason, variant and flag metadata
+func resolutionDetail(config *abc.Config) of.ProviderResolutionDetail {
+	detail := of.ProviderResolutionDetail{
+		Reason:       convertReason(config.Reason),
+		FlagMetadata: of.FlagMetadata{},
+	}
+	if config.Reason != "" {
+		detail.FlagMetadata[metadataReason] = string(config.Reason)
+	}
+	if config.Experiment != nil {
+		detail.Variant = config.Experiment.Key
+		detail.FlagMetadata[metadataExperimentKey] = config.Experiment.ExperimentKey
+		detail.FlagMetadata[metadataLayerKey] = config.Experiment.LayerKey
+		detail.FlagMetadata[metadataGroupID] = config.Experiment.ID
+	} else if config.IsDefault {
+		detail.Variant = defaultVariant
+	}
+	return detail
+}
+
+// convertReason Map the ABC evaluation reason into the OpenFeature reason
+func convertReason(reason env.Reason) of.Reason {
+	switch reason {
+	case env.ReasonTargetingMatch:
+		return of.TargetingMatchReason
+	case env.ReasonSplit:
+		return of.SplitReason
+	case env.ReasonLayerDefault, env.ReasonSystemDefault, env.ReasonFallback:
+		return of.DefaultReason
+	case env.ReasonOverride:
+		return overrideReason
+	case env.ReasonHoldout:
+		return holdoutReason
+	case env.ReasonError, env.ReasonNotReady:
+		return of.ErrorReason
+	default:
+		return of.UnknownReason
+	}
+}
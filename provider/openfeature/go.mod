@@ -0,0 +1,55 @@
+module github.com/abetterchoice/go-sdk/provider/openfeature
+
+go 1.19
+
+require (
+	github.com/abetterchoice/go-sdk v0.0.0
+	github.com/open-feature/go-sdk v1.11.0
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	cloud.google.com/go v0.105.0 // indirect
+	cloud.google.com/go/compute v1.13.0 // indirect
+	cloud.google.com/go/compute/metadata v0.2.1 // indirect
+	cloud.google.com/go/iam v0.8.0 // indirect
+	cloud.google.com/go/pubsub v1.27.1 // indirect
+	github.com/RoaringBitmap/roaring v1.2.1 // indirect
+	github.com/abetterchoice/hashutil v0.0.0-20240612073854-14a51781e8ad // indirect
+	github.com/abetterchoice/metrics-pubsub v0.0.0-20240619132001-145052104b60 // indirect
+	github.com/abetterchoice/protoc_cache_server v0.0.0-20250422112234-d546680b8d97 // indirect
+	github.com/abetterchoice/protoc_dmp_proxy_server v0.0.0-20241211131012-b69df5102634 // indirect
+	github.com/abetterchoice/protoc_event_server v0.0.0-20240614085823-cafaa745b226 // indirect
+	github.com/abetterchoice/tagutil v0.0.0-20250422120833-f61c86e633a3 // indirect
+	github.com/bits-and-blooms/bitset v1.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
+	github.com/golang/mock v1.6.0 // indirect
+	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/googleapis/enterprise-certificate-proxy v0.2.0 // indirect
+	github.com/googleapis/gax-go/v2 v2.7.0 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.15.0 // indirect
+	github.com/mschoch/smat v0.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opencensus.io v0.24.0 // indirect
+	golang.org/x/exp v0.0.0-20240205201215-2c58cdc269a3 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/oauth2 v0.3.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/api v0.103.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/genproto v0.0.0-20221227171554-f9683d7f8bef // indirect
+	google.golang.org/grpc v1.51.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/abetterchoice/go-sdk => ../..
+	github.com/golang/protobuf => github.com/golang/protobuf v1.4.3
+)
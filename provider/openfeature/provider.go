@@ -0,0 +1,179 @@
+// Package openfeature implements the OpenFeature provider contract on top of abc.Context,
+// so that services standardized on the OpenFeature evaluation API can evaluate ABC remote configurations
+// and feature flags. Flag keys are remote configuration keys of the project bound to the provider.
+package openfeature
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProviderName The provider name reported in the OpenFeature metadata and events
+	ProviderName = "abetterchoice"
+
+	defaultLoadTimeout        = 10 * time.Second
+	defaultReadyCheckInterval = time.Second
+)
+
+// Provider OpenFeature provider bound to a single ABC project.
+// The ABC SDK must be initialized by abc.Init before the provider is registered,
+// the provider does not own the SDK life cycle and Shutdown does not release it.
+type Provider struct {
+	projectID          string
+	loadTimeout        time.Duration
+	readyCheckInterval time.Duration
+	configOptions      []abc.ConfigOption
+
+	mu     sync.RWMutex
+	state  of.State
+	events chan of.Event
+	stop   chan struct{}
+}
+
+// Option Provider options
+type Option func(p *Provider)
+
+// WithLoadTimeout sets the timeout for loading the project during Init, 10s by default
+func WithLoadTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		if timeout > 0 {
+			p.loadTimeout = timeout
+		}
+	}
+}
+
+// WithReadyCheckInterval sets the interval for checking whether the project has been loaded
+// after Init failed, the provider-ready event is emitted once it is loaded. 1s by default
+func WithReadyCheckInterval(interval time.Duration) Option {
+	return func(p *Provider) {
+		if interval > 0 {
+			p.readyCheckInterval = interval
+		}
+	}
+}
+
+// WithConfigOptions sets the options passed to every evaluation, such as abc.WithIsDisableDMPConfigOpt
+func WithConfigOptions(opts ...abc.ConfigOption) Option {
+	return func(p *Provider) {
+		p.configOptions = append(p.configOptions, opts...)
+	}
+}
+
+// NewProvider Create the OpenFeature provider of projectID
+func NewProvider(projectID string, opts ...Option) *Provider {
+	p := &Provider{
+		projectID:          projectID,
+		loadTimeout:        defaultLoadTimeout,
+		readyCheckInterval: defaultReadyCheckInterval,
+		state:              of.NotReadyState,
+		events:             make(chan of.Event, 1),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Metadata The provider metadata
+func (p *Provider) Metadata() of.Metadata {
+	return of.Metadata{Name: ProviderName}
+}
+
+// Hooks The provider does not register any hooks
+func (p *Provider) Hooks() []of.Hook {
+	return nil
+}
+
+// Init Load the project if it is not loaded yet, the provider is ready once the project data is in the local cache.
+// If loading fails, the error is returned and the project is watched in the background,
+// the provider-ready event is emitted through EventChannel once the project is loaded.
+func (p *Provider) Init(evaluationContext of.EvaluationContext) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+	}
+	if abc.IsProjectReady(p.projectID) {
+		p.state = of.ReadyState
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.loadTimeout)
+	defer cancel()
+	err := abc.RegisterProjectIDs(ctx, []string{p.projectID})
+	if err == nil && abc.IsProjectReady(p.projectID) {
+		p.state = of.ReadyState
+		return nil
+	}
+	if err == nil {
+		err = errors.Errorf("projectID [%s] is not loaded", p.projectID)
+	}
+	p.state = of.ErrorState
+	go p.watchReady(p.stop)
+	return errors.Wrapf(err, "load projectID [%s]", p.projectID)
+}
+
+// watchReady Wait for the project to be loaded and emit the provider-ready event
+func (p *Provider) watchReady(stop chan struct{}) {
+	ticker := time.NewTicker(p.readyCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !abc.IsProjectReady(p.projectID) {
+				continue
+			}
+			p.mu.Lock()
+			p.state = of.ReadyState
+			p.mu.Unlock()
+			p.emit(of.Event{
+				ProviderName: ProviderName,
+				EventType:    of.ProviderReady,
+				ProviderEventDetails: of.ProviderEventDetails{
+					Message: "projectID [" + p.projectID + "] is loaded",
+				},
+			})
+			return
+		}
+	}
+}
+
+// emit Send the event without blocking, the event is dropped if nobody consumes the channel
+func (p *Provider) emit(event of.Event) {
+	select {
+	case p.events <- event:
+	default:
+		log.Warnf("[projectID=%s]openfeature event %s is dropped", p.projectID, event.EventType)
+	}
+}
+
+// Shutdown Stop watching the project, the ABC SDK is not released
+func (p *Provider) Shutdown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+	p.state = of.NotReadyState
+}
+
+// Status The provider state
+func (p *Provider) Status() of.State {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.state
+}
+
+// EventChannel The channel of provider events
+func (p *Provider) EventChannel() <-chan of.Event {
+	return p.events
+}
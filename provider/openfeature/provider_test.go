@@ -0,0 +1,171 @@
+package openfeature
+
+import (
+	"context"
+	"testing"
+
+	abc "github.com/abetterchoice/go-sdk"
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/testdata"
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+)
+
+var projectID = "123"
+
+func initSDK(t *testing.T) {
+	err := abc.Init(context.Background(), []string{projectID},
+		abc.WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		abc.WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+}
+
+func TestProvider_Init(t *testing.T) {
+	initSDK(t)
+	p := NewProvider(projectID)
+	assert.Equal(t, of.NotReadyState, p.Status())
+	assert.Nil(t, p.Init(of.EvaluationContext{}))
+	assert.Equal(t, of.ReadyState, p.Status())
+	assert.Equal(t, ProviderName, p.Metadata().Name)
+	p.Shutdown()
+	assert.Equal(t, of.NotReadyState, p.Status())
+}
+
+func TestProvider_StringEvaluation(t *testing.T) {
+	initSDK(t)
+	tests := []struct {
+		name        string
+		projectID   string
+		flag        string
+		evalCtx     of.FlattenedContext
+		want        string
+		wantReason  of.Reason
+		wantErrCode of.ErrorCode
+	}{
+		{
+			name:       "normal",
+			projectID:  projectID,
+			flag:       "remoteConfig1",
+			evalCtx:    of.FlattenedContext{of.TargetingKey: "unitID"},
+			want:       "remoteConfig1-condition1",
+			wantReason: of.SplitReason,
+		},
+		{
+			name:       "targeting match",
+			projectID:  projectID,
+			flag:       "withTag",
+			evalCtx:    of.FlattenedContext{of.TargetingKey: "unitID", "tagKey1": "ios"},
+			want:       "withTag-condition1",
+			wantReason: of.TargetingMatchReason,
+		},
+		{
+			name:       "default value",
+			projectID:  projectID,
+			flag:       "withTag",
+			evalCtx:    of.FlattenedContext{of.TargetingKey: "unitID", "tagKey1": []interface{}{"ios", "iphone"}},
+			want:       "withTagDefaultValue",
+			wantReason: of.DefaultReason,
+		},
+		{
+			name:        "flag not found",
+			projectID:   projectID,
+			flag:        "emptyKey",
+			evalCtx:     of.FlattenedContext{of.TargetingKey: "unitID"},
+			want:        "default",
+			wantReason:  of.ErrorReason,
+			wantErrCode: of.FlagNotFoundCode,
+		},
+		{
+			name:        "targeting key missing",
+			projectID:   projectID,
+			flag:        "remoteConfig1",
+			evalCtx:     of.FlattenedContext{},
+			want:        "default",
+			wantReason:  of.ErrorReason,
+			wantErrCode: of.TargetingKeyMissingCode,
+		},
+		{
+			name:        "provider not ready",
+			projectID:   "emptyProjectID",
+			flag:        "remoteConfig1",
+			evalCtx:     of.FlattenedContext{of.TargetingKey: "unitID"},
+			want:        "default",
+			wantReason:  of.ErrorReason,
+			wantErrCode: of.ProviderNotReadyCode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProvider(tt.projectID, WithConfigOptions(abc.WithIsDisableDMPConfigOpt(true)))
+			got := p.StringEvaluation(context.TODO(), tt.flag, "default", tt.evalCtx)
+			assert.Equal(t, tt.want, got.Value)
+			assert.Equal(t, tt.wantReason, got.Reason)
+			assert.Equal(t, tt.wantErrCode, got.ResolutionDetail().ErrorCode)
+		})
+	}
+}
+
+func TestProvider_TypeMismatch(t *testing.T) {
+	initSDK(t)
+	p := NewProvider(projectID)
+	evalCtx := of.FlattenedContext{of.TargetingKey: "unitID"}
+	boolResult := p.BooleanEvaluation(context.TODO(), "remoteConfig1", true, evalCtx)
+	assert.True(t, boolResult.Value)
+	assert.Equal(t, of.TypeMismatchCode, boolResult.ResolutionDetail().ErrorCode)
+	intResult := p.IntEvaluation(context.TODO(), "remoteConfig1", 10, evalCtx)
+	assert.Equal(t, int64(10), intResult.Value)
+	assert.Equal(t, of.TypeMismatchCode, intResult.ResolutionDetail().ErrorCode)
+	floatResult := p.FloatEvaluation(context.TODO(), "remoteConfig1", 1.5, evalCtx)
+	assert.Equal(t, 1.5, floatResult.Value)
+	assert.Equal(t, of.TypeMismatchCode, floatResult.ResolutionDetail().ErrorCode)
+	objectResult := p.ObjectEvaluation(context.TODO(), "remoteConfig1", nil, evalCtx)
+	assert.Nil(t, objectResult.Value)
+	assert.Equal(t, of.TypeMismatchCode, objectResult.ResolutionDetail().ErrorCode)
+}
+
+func Test_convertReason(t *testing.T) {
+	tests := []struct {
+		reason env.Reason
+		want   of.Reason
+	}{
+		{reason: env.ReasonOverride, want: of.Reason("OVERRIDE")},
+		{reason: env.ReasonHoldout, want: of.Reason("HOLDOUT")},
+		{reason: env.ReasonTargetingMatch, want: of.TargetingMatchReason},
+		{reason: env.ReasonSplit, want: of.SplitReason},
+		{reason: env.ReasonLayerDefault, want: of.DefaultReason},
+		{reason: env.ReasonSystemDefault, want: of.DefaultReason},
+		{reason: env.ReasonFallback, want: of.DefaultReason},
+		{reason: env.ReasonError, want: of.ErrorReason},
+		{reason: env.ReasonNotReady, want: of.ErrorReason},
+		{reason: "", want: of.UnknownReason},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.reason), func(t *testing.T) {
+			assert.Equal(t, tt.want, convertReason(tt.reason))
+		})
+	}
+}
+
+func Test_convertTagValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   []string
+		wantOK bool
+	}{
+		{name: "nil", value: nil},
+		{name: "string", value: "ios", want: []string{"ios"}, wantOK: true},
+		{name: "string list", value: []string{"ios", "iphone"}, want: []string{"ios", "iphone"}, wantOK: true},
+		{name: "list", value: []interface{}{"ios", 1}, want: []string{"ios", "1"}, wantOK: true},
+		{name: "number", value: 1.5, want: []string{"1.5"}, wantOK: true},
+		{name: "bool", value: true, want: []string{"true"}, wantOK: true},
+		{name: "object", value: map[string]interface{}{"a": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := convertTagValues(tt.value)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
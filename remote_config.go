@@ -14,7 +14,8 @@ import (
 	"github.com/abetterchoice/go-sdk/internal/cache"
 	"github.com/abetterchoice/go-sdk/internal/config"
 	"github.com/abetterchoice/go-sdk/internal/experiment"
-	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
 	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
 	"github.com/abetterchoice/protoc_event_server"
 	"github.com/pkg/errors"
@@ -58,32 +59,45 @@ func GetAllRemoteConfigs(projectID string) (map[string]*Value, error) {
 func (c *userContext) GetRemoteConfig(ctx context.Context, projectID string, key string,
 	opts ...ConfigOption) (result *ConfigResult, err error) {
 	options := defaultExperimentOptions // Copy, defaultExperimentOptions remains unchanged as template
+	// the evaluation error replaced by the fallback result, it is still reported through the monitor event
+	var fallbackErr error
+	ctx, span := trace.Start(ctx, trace.SpanGetRemoteConfig, trace.String(trace.AttributeProjectID, projectID),
+		trace.String(trace.AttributeConfigKey, key))
+	defer func() {
+		if trace.IsEnabled() {
+			span.SetAttributes(configAttributes(options.Application, result)...)
+		}
+		span.RecordError(err)
+		span.RecordError(fallbackErr)
+		span.End()
+	}()
 	defer func(startTime time.Time) {
 		latency := time.Since(startTime)
-		if options.IsExposureLoggingAutomatic && !internal.C.IsDisableReport {
-			exposureErr := asyncExposureRemoteConfig(projectID, result, protoc_event_server.ExposureType_EXPOSURE_TYPE_AUTOMATIC)
-			if exposureErr != nil {
-				log.Errorf("[projectID=%v]asyncExposureRemoteConfig fail:%v", projectID, exposureErr)
-			}
+		// the fallback result is not a real evaluation, no exposure is recorded
+		if options.IsExposureLoggingAutomatic && !internal.C.IsDisableReport && fallbackErr == nil {
+			asyncExposureRemoteConfig(projectID, result, protoc_event_server.ExposureType_EXPOSURE_TYPE_AUTOMATIC)
 		}
-		exposureErr := asyncExposureRemoteConfigEvent(projectID, result, latency, env.JSONString(&options), err)
-		if exposureErr != nil {
-			log.Errorf("[projectID=%v]exposureRemoteConfigEvent fail:%v", projectID, exposureErr)
+		eventErr := err
+		if fallbackErr != nil {
+			eventErr = fallbackErr
 		}
+		asyncExposureRemoteConfigEvent(projectID, result, latency, optionsJSON(&options), eventErr)
+		stats.ObserveEvaluation(stats.APIGetRemoteConfig, evaluationStatus(err, fallbackErr != nil), latency)
 	}(time.Now())
-	if c.err != nil {
-		return nil, c.err
-	}
-	c.fillOption(&options)
+	c.fillOption(projectID, &options)
 	for _, opt := range opts {
 		err := opt(&options)
 		if err != nil {
 			return nil, errors.Wrap(err, "opt")
 		}
 	}
-	configValue, err := config.Executor.GetRemoteConfig(ctx, projectID, key, &options)
+	configValue, err := c.getRemoteConfig(ctx, projectID, key, &options)
 	if err != nil {
-		return nil, err
+		if !options.HasFallbackValue {
+			return nil, err
+		}
+		fallbackErr = err
+		return c.fallbackConfigResult(key, &options, fallbackReason(err)), nil
 	}
 	return &ConfigResult{
 		userCtx: c,
@@ -92,13 +106,24 @@ func (c *userContext) GetRemoteConfig(ctx context.Context, projectID string, key
 			Value:          &Value{data: configValue.Data},
 			IsOverrideList: configValue.IsOverrideList,
 			IsDefault:      configValue.IsDefault,
-			Experiment:     convertGroup2Experiment(configValue.Experiment),
+			Reason:         configValue.Reason,
+			Experiment:     withVersion(convertGroup2Experiment(configValue.Experiment), options.Application),
+			Version:        applicationVersion(options.Application),
 			remoteConfig:   configValue.RemoteConfig,
 			unitIDType:     configValue.UnitIDType,
 		},
 	}, nil
 }
 
+// getRemoteConfig The remote configuration of the user, the error of the construction of the user context included
+func (c *userContext) getRemoteConfig(ctx context.Context, projectID string, key string,
+	options *experiment.Options) (*config.Value, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return config.Executor.GetRemoteConfig(ctx, projectID, key, options)
+}
+
 // ConfigOption Gets the relevant Option of the hit configuration, including the specified scene ID
 // WithSceneIDConfigOption Or if the configuration is not hit, return zero value or specify a default value
 // WithDefaultValueConfigOption
@@ -123,6 +148,22 @@ func WithIsDisableDMPConfigOpt(isDisableDMP bool) ConfigOption {
 	}
 }
 
+// WithFallback sets the value returned when the project is not loaded, the key does not exist
+// or the evaluation fails. Instead of an error, a result carrying the fallback value is returned,
+// its Reason explains why the fallback was used, and no exposure is recorded for it.
+// The value can be []byte, string, bool, integer or float, other types are serialized into json.
+func WithFallback(value interface{}) ConfigOption {
+	return func(options *experiment.Options) error {
+		data, err := fallbackBytes(value)
+		if err != nil {
+			return errors.Wrap(err, "fallbackBytes")
+		}
+		options.HasFallbackValue = true
+		options.FallbackValue = data
+		return nil
+	}
+}
+
 // ConfigResult TODO
 type ConfigResult struct {
 	userCtx *userContext `json:"-"`
@@ -142,9 +183,16 @@ type Config struct {
 	// Is it the default value?
 	IsDefault bool `json:"isDefault"`
 
+	// The reason why this value is returned, such as override, holdout, condition hit, default value
+	// or the caller-supplied fallback value is used
+	Reason env.Reason `json:"reason,omitempty"`
+
 	// Configure the bound experiment
 	Experiment *Group `json:"experiment"`
 
+	// The version of the local cache the value is evaluated from, empty for the caller-supplied fallback value
+	Version string `json:"version,omitempty"`
+
 	// Remote configuration information is not disclosed to prevent concurrency problems
 	// and can provide read-only operations through the API
 	remoteConfig *protoccacheserver.RemoteConfig `json:"-"`
@@ -11,6 +11,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal/experiment"
 	"github.com/abetterchoice/go-sdk/testdata"
 	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
@@ -86,6 +87,7 @@ func Test_userContext_GetRemoteConfig(t *testing.T) {
 				Config: &Config{
 					Key:          "remoteConfig1",
 					Value:        &Value{data: []byte("remoteConfig1-condition1")},
+					Reason:       env.ReasonSplit,
 					remoteConfig: testdata.NormalTabConfig.ConfigData.RemoteConfigIndex["remoteConfig1"],
 					unitIDType:   protoccacheserver.UnitIDType_UNIT_ID_TYPE_DEFAULT,
 				},
@@ -182,3 +184,97 @@ func TestGetAllRemoteConfigs(t *testing.T) {
 		t.Logf("[GetAllRemoteConfigs] err=%v", err)
 	})
 }
+
+func Test_userContext_GetRemoteConfigWithFallback(t *testing.T) {
+	err := Init(context.Background(), projectIDList,
+		WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	tests := []struct {
+		name       string
+		projectID  string
+		key        string
+		attrs      []Attribution
+		opts       []ConfigOption
+		wantValue  string
+		wantReason env.Reason
+		wantErr    bool
+	}{
+		{
+			name:      "projectID not found without fallback",
+			projectID: "emptyProjectID",
+			key:       "remoteConfig1",
+			wantErr:   true,
+		},
+		{
+			name:       "projectID not found",
+			projectID:  "emptyProjectID",
+			key:        "remoteConfig1",
+			opts:       []ConfigOption{WithFallback("fallback")},
+			wantValue:  "fallback",
+			wantReason: env.ReasonNotReady,
+		},
+		{
+			name:       "key not found",
+			projectID:  projectID,
+			key:        "emptyKey",
+			opts:       []ConfigOption{WithFallback(int64(10))},
+			wantValue:  "10",
+			wantReason: env.ReasonFallback,
+		},
+		{
+			name:       "invalid user context",
+			projectID:  projectID,
+			key:        "remoteConfig1",
+			attrs:      []Attribution{WithDecisionID("")},
+			opts:       []ConfigOption{WithFallback("fallback")},
+			wantValue:  "fallback",
+			wantReason: env.ReasonError,
+		},
+		{
+			name:       "normal",
+			projectID:  projectID,
+			key:        "remoteConfig1",
+			opts:       []ConfigOption{WithFallback(true)},
+			wantValue:  "remoteConfig1-condition1",
+			wantReason: env.ReasonSplit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewUserContext("fallbackUnitID", tt.attrs...).GetRemoteConfig(context.TODO(), tt.projectID,
+				tt.key, tt.opts...)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.Nil(t, got)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantValue, got.String())
+			assert.Equal(t, tt.wantReason, got.Reason)
+		})
+	}
+}
+
+func Test_fallbackBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "nil", value: nil, want: ""},
+		{name: "bytes", value: []byte("abc"), want: "abc"},
+		{name: "string", value: "abc", want: "abc"},
+		{name: "bool", value: true, want: "true"},
+		{name: "int", value: 12, want: "12"},
+		{name: "float", value: 1.5, want: "1.5"},
+		{name: "json", value: map[string]int{"a": 1}, want: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fallbackBytes(tt.value)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
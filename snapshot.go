@@ -0,0 +1,63 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"sync"
+
+	"github.com/abetterchoice/go-sdk/internal/cache"
+)
+
+// applicationSnapshot The local cache of each project pinned by a user context
+type applicationSnapshot struct {
+	mu           sync.Mutex
+	applications map[string]*cache.Application
+}
+
+// WithPinnedSnapshot Pin the local cache of each project at the first evaluation of the project by the user context,
+// the later evaluations of the user context are served from it even if the local cache is refreshed in between.
+// Use it with a user context per request, so that GetExperiment for several layers, GetRemoteConfig and
+// GetValueByVariantKey in one request see the same version, the version is reported by the results.
+// The pinned local cache is kept as long as the user context
+func WithPinnedSnapshot() Attribution {
+	return func(c *userContext) {
+		c.snapshot = &applicationSnapshot{applications: make(map[string]*cache.Application)}
+	}
+}
+
+// application The pinned local cache of the project, it is pinned if not yet. nil if the project is not loaded
+func (s *applicationSnapshot) application(projectID string) *cache.Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if application, ok := s.applications[projectID]; ok {
+		return application
+	}
+	application := cache.GetApplication(projectID)
+	if application != nil { // the project not loaded yet is pinned once it is loaded
+		s.applications[projectID] = application
+	}
+	return application
+}
+
+// applicationVersion The version of the local cache, empty if nil
+func applicationVersion(application *cache.Application) string {
+	if application == nil {
+		return ""
+	}
+	return application.Version
+}
+
+// withVersion Set the version of the local cache the group and its holdout groups are evaluated from
+func withVersion(group *Group, application *cache.Application) *Group {
+	if group == nil {
+		return nil
+	}
+	group.Version = applicationVersion(application)
+	for _, holdoutGroup := range group.holdoutData {
+		if holdoutGroup != nil {
+			holdoutGroup.Version = group.Version
+		}
+	}
+	return group
+}
@@ -0,0 +1,45 @@
+package abc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPinnedSnapshot(t *testing.T) {
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient), WithDisableReport(true))
+	assert.Nil(t, err)
+	defer Release()
+	current := cache.GetApplication(projectID)
+	assert.NotNil(t, current)
+	userCtx := NewUserContext("unit", WithPinnedSnapshot()).(*userContext)
+	assert.Equal(t, current, userCtx.snapshot.application(projectID))
+	assert.Nil(t, userCtx.snapshot.application("emptyProjectID"))
+	assert.NotContains(t, userCtx.snapshot.applications, "emptyProjectID")
+
+	// the pinned local cache serves the later evaluations, the current one is not read again
+	pinned := *current
+	pinned.Version = "pinned"
+	userCtx.snapshot.applications[projectID] = &pinned
+	config, err := userCtx.GetRemoteConfig(context.TODO(), projectID, "remoteConfig1")
+	assert.Nil(t, err)
+	assert.Equal(t, "pinned", config.Version)
+	list, err := userCtx.GetExperiments(context.TODO(), projectID)
+	assert.Nil(t, err)
+	assert.Equal(t, "pinned", list.Version)
+	for _, group := range list.Data {
+		assert.Equal(t, "pinned", group.Version)
+	}
+	value, err := userCtx.GetValueByVariantKey(context.TODO(), projectID, "remoteConfig1")
+	assert.Nil(t, err)
+	assert.Equal(t, "pinned", value.Version)
+
+	// without the snapshot the current local cache is read
+	config, err = NewUserContext("unit").GetRemoteConfig(context.TODO(), projectID, "remoteConfig1")
+	assert.Nil(t, err)
+	assert.Equal(t, current.Version, config.Version)
+}
@@ -0,0 +1,72 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"net/http"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/pkg/errors"
+)
+
+// StatsSnapshot The runtime statistics of the SDK at a point in time, see Stats
+type StatsSnapshot = stats.Snapshot
+
+// Stats The runtime statistics of the SDK since the process started, including
+// evaluations by API and status with the latency histograms, local cache refreshes and the current version
+// of each project, DMP tag queries, the depth and the drops of the exposure channels and the plugin errors.
+// Unlike the monitor events reported through the metrics plugin, the statistics are not sampled
+func Stats() *StatsSnapshot {
+	var versions = make(map[string]string)
+	for _, projectID := range cache.ProjectIDList() {
+		if application := cache.GetApplication(projectID); application != nil {
+			versions[projectID] = application.Version
+		}
+	}
+	return stats.Collect(versions, exposureChannels())
+}
+
+// StatsHandler The http.Handler serving Stats in the Prometheus text exposition format,
+// the metric names are prefixed with abc_
+//
+// example:
+//
+//	http.Handle("/metrics/abc", abc.StatsHandler())
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", stats.ContentType)
+		err := Stats().WriteText(w)
+		if err != nil {
+			log.Errorf("write stats fail:%v", err)
+		}
+	})
+}
+
+// MemoryUsage The estimated memory of the local cache data of a project by index, see MemoryStats
+type MemoryUsage = cache.MemoryUsage
+
+// MemoryStats The estimated heap bytes of the local cache data of the project by index, and of the other kept
+// versions not shared with the served one. It walks the whole data of the project,
+// so it is meant for the diagnostics rather than per request
+func MemoryStats(projectID string) (*MemoryUsage, error) {
+	usage, ok := cache.GetMemoryUsage(projectID)
+	if !ok {
+		return nil, errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
+	}
+	return usage, nil
+}
+
+// evaluationStatus The status label of the evaluation in the runtime statistics
+func evaluationStatus(err error, isFallback bool) string {
+	if err != nil {
+		return stats.StatusError
+	}
+	if isFallback {
+		return stats.StatusFallback
+	}
+	return stats.StatusSuccess
+}
@@ -0,0 +1,90 @@
+package abc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func evaluationCount(snapshot *StatsSnapshot, api string, status string) uint64 {
+	for _, counter := range snapshot.Evaluations {
+		if counter.Labels[stats.LabelAPI] == api && counter.Labels[stats.LabelStatus] == status {
+			return counter.Value
+		}
+	}
+	return 0
+}
+
+func TestStats(t *testing.T) {
+	Release() // the initial load is recorded as a refresh
+	stats.Reset()
+	defer stats.Reset()
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+
+	userCtx := NewUserContext("unitID")
+	_, err = userCtx.GetExperiment(context.TODO(), projectID, "overrideLayer")
+	assert.Nil(t, err)
+	_, err = userCtx.GetRemoteConfig(context.TODO(), projectID, "remoteConfig1")
+	assert.Nil(t, err)
+	_, err = userCtx.GetRemoteConfig(context.TODO(), projectID, "emptyKey")
+	assert.NotNil(t, err)
+	_, err = userCtx.GetRemoteConfig(context.TODO(), projectID, "emptyKey", WithFallback("fallback"))
+	assert.Nil(t, err)
+	_, err = NewUserContext("").GetValueByVariantKey(context.TODO(), projectID, "remoteConfig1")
+	assert.NotNil(t, err)
+
+	snapshot := Stats()
+	assert.Equal(t, uint64(1), evaluationCount(snapshot, stats.APIGetExperiments, stats.StatusSuccess))
+	assert.Equal(t, uint64(1), evaluationCount(snapshot, stats.APIGetRemoteConfig, stats.StatusSuccess))
+	assert.Equal(t, uint64(1), evaluationCount(snapshot, stats.APIGetRemoteConfig, stats.StatusError))
+	assert.Equal(t, uint64(1), evaluationCount(snapshot, stats.APIGetRemoteConfig, stats.StatusFallback))
+	assert.Equal(t, uint64(1), evaluationCount(snapshot, stats.APIGetValueByVariantKey, stats.StatusError))
+	assert.Equal(t, applicationVersion(cache.GetApplication(projectID)), snapshot.Versions[projectID])
+	assert.NotEmpty(t, snapshot.Refreshes)
+	assert.Equal(t, projectID, snapshot.Refreshes[0].Labels[stats.LabelProjectID])
+	assert.Len(t, snapshot.ExposureChannels, 5)
+	for _, channel := range snapshot.ExposureChannels {
+		assert.Equal(t, 1<<19, channel.Capacity, channel.Name)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	stats.Reset()
+	defer stats.Reset()
+	recorder := httptest.NewRecorder()
+	StatsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, stats.ContentType, recorder.Header().Get("Content-Type"))
+	lines := strings.Split(recorder.Body.String(), "\n")
+	assert.Contains(t, lines, "# TYPE abc_evaluations_total counter")
+	assert.Contains(t, lines, `abc_exposure_channel_capacity{channel="experiment_exposure"} 524288`)
+	assert.Contains(t, lines, `abc_exposure_channel_drops_total{channel="remote_config_event"} 0`)
+}
+
+func TestMemoryStats(t *testing.T) {
+	defer Release()
+	_, err := MemoryStats(projectID)
+	assert.True(t, errors.Is(err, env.ErrProjectNotFound))
+	err = Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	usage, err := MemoryStats(projectID)
+	assert.Nil(t, err)
+	assert.Equal(t, projectID, usage.ProjectID)
+	assert.NotZero(t, usage.TabConfig.Bytes)
+	assert.NotZero(t, usage.GroupBucketInfo.Entries)
+	assert.Equal(t, usage.TabConfig.Bytes+usage.ExperimentBucketInfo.Bytes+usage.ExperimentBitmaps.Bytes+
+		usage.GroupBucketInfo.Bytes+usage.GroupBitmaps.Bytes+usage.Lookups.Bytes, usage.Total)
+}
@@ -0,0 +1,51 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"sort"
+
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
+)
+
+// experimentListAttributes The span attributes of the experiment evaluation, layer keys and hit group IDs are sorted.
+// The version is the one of application, the local cache the evaluation is served from
+func experimentListAttributes(application *cache.Application, list *ExperimentList) []trace.Attribute {
+	var attributes = []trace.Attribute{versionAttribute(application)}
+	if list == nil || len(list.Data) == 0 {
+		return attributes
+	}
+	var layerKeys = make([]string, 0, len(list.Data))
+	var groupIDs = make([]int64, 0, len(list.Data))
+	for layerKey, group := range list.Data {
+		layerKeys = append(layerKeys, layerKey)
+		if group != nil && group.ID != 0 {
+			groupIDs = append(groupIDs, group.ID)
+		}
+	}
+	sort.Strings(layerKeys)
+	sort.Slice(groupIDs, func(i, j int) bool { return groupIDs[i] < groupIDs[j] })
+	return append(attributes, trace.StringSlice(trace.AttributeLayerKeys, layerKeys),
+		trace.Int64Slice(trace.AttributeGroupIDs, groupIDs))
+}
+
+// configAttributes The span attributes of the remote configuration evaluation,
+// the layer key and group ID of the bound experiment are included if any
+func configAttributes(application *cache.Application, result *ConfigResult) []trace.Attribute {
+	var attributes = []trace.Attribute{versionAttribute(application)}
+	if result == nil || result.Config == nil {
+		return attributes
+	}
+	attributes = append(attributes, trace.String(trace.AttributeReason, string(result.Reason)))
+	if result.Experiment != nil {
+		attributes = append(attributes, trace.StringSlice(trace.AttributeLayerKeys, []string{result.Experiment.LayerKey}),
+			trace.Int64Slice(trace.AttributeGroupIDs, []int64{result.Experiment.ID}))
+	}
+	return attributes
+}
+
+func versionAttribute(application *cache.Application) trace.Attribute {
+	return trace.String(trace.AttributeVersion, applicationVersion(application))
+}
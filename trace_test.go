@@ -0,0 +1,133 @@
+package abc
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/plugin/trace"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *mockSpan) SetAttributes(attributes ...trace.Attribute) {
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *mockSpan) RecordError(err error) {
+	if err != nil {
+		s.err = err
+	}
+}
+
+func (s *mockSpan) End() {
+	s.ended = true
+}
+
+type mockTracer struct {
+	mu    sync.Mutex
+	spans []*mockSpan
+}
+
+func (t *mockTracer) Start(ctx context.Context, name string,
+	attributes ...trace.Attribute) (context.Context, trace.Span) {
+	span := &mockSpan{name: name, attributes: map[string]interface{}{}}
+	span.SetAttributes(attributes...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+// evaluationSpans The spans of the evaluations, the spans of the asynchronous exposure delivery are skipped
+func (t *mockTracer) evaluationSpans() []*mockSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var result []*mockSpan
+	for _, span := range t.spans {
+		if span.name == trace.SpanGetExperiments || span.name == trace.SpanGetRemoteConfig {
+			result = append(result, span)
+		}
+	}
+	return result
+}
+
+func TestTraceSpans(t *testing.T) {
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient))
+	assert.Nil(t, err)
+	tracer := &mockTracer{}
+	trace.RegisterTracer(tracer)
+	defer trace.RegisterTracer(nil)
+	version := applicationVersion(cache.GetApplication(projectID))
+
+	userCtx := NewUserContext("unitID")
+	_, err = userCtx.GetExperiment(context.TODO(), projectID, "overrideLayer")
+	assert.Nil(t, err)
+	_, err = userCtx.GetRemoteConfig(context.TODO(), projectID, "remoteConfig1")
+	assert.Nil(t, err)
+	_, err = userCtx.GetRemoteConfig(context.TODO(), projectID, "emptyKey")
+	assert.NotNil(t, err)
+
+	spans := tracer.evaluationSpans()
+	assert.Equal(t, 3, len(spans))
+	assert.Equal(t, &mockSpan{
+		name: trace.SpanGetExperiments,
+		attributes: map[string]interface{}{
+			trace.AttributeProjectID: projectID,
+			trace.AttributeVersion:   version,
+			trace.AttributeLayerKeys: []string{"overrideLayer"},
+			trace.AttributeGroupIDs:  []int64{100003001},
+		},
+		ended: true,
+	}, spans[0])
+	assert.Equal(t, &mockSpan{
+		name: trace.SpanGetRemoteConfig,
+		attributes: map[string]interface{}{
+			trace.AttributeProjectID: projectID,
+			trace.AttributeConfigKey: "remoteConfig1",
+			trace.AttributeVersion:   version,
+			trace.AttributeReason:    "SPLIT",
+		},
+		ended: true,
+	}, spans[1])
+	assert.Equal(t, trace.SpanGetRemoteConfig, spans[2].name)
+	assert.NotNil(t, spans[2].err)
+	assert.True(t, spans[2].ended)
+}
+
+func TestTraceSpans_pinnedSnapshot(t *testing.T) {
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient), WithDisableReport(true))
+	assert.Nil(t, err)
+	tracer := &mockTracer{}
+	trace.RegisterTracer(tracer)
+	defer trace.RegisterTracer(nil)
+	userCtx := NewUserContext("unitID", WithPinnedSnapshot()).(*userContext)
+	pinned := *cache.GetApplication(projectID)
+	pinned.Version = "pinned"
+	userCtx.snapshot.applications[projectID] = &pinned
+
+	// the version of the span is the one of the local cache serving the evaluation
+	_, err = userCtx.GetExperiments(context.TODO(), projectID)
+	assert.Nil(t, err)
+	_, err = userCtx.GetRemoteConfig(context.TODO(), projectID, "remoteConfig1")
+	assert.Nil(t, err)
+	spans := tracer.evaluationSpans()
+	assert.Equal(t, 2, len(spans))
+	for _, span := range spans {
+		assert.Equal(t, "pinned", span.attributes[trace.AttributeVersion])
+	}
+}
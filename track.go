@@ -0,0 +1,184 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/log"
+	"github.com/abetterchoice/go-sdk/plugin/metrics"
+	protoccacheserver "github.com/abetterchoice/protoc_cache_server"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/pkg/errors"
+)
+
+// The reserved keys of the metadata of the tracked events, they take precedence over the props
+const (
+	TrackUnitIDKey       = metrics.EventUnitIDKey // the unit ID of the hash sampling
+	TrackValueKey        = "value"
+	TrackExpandedDataKey = "expanded_data" // the expanded data of the user context, formatted as k1=v1;k2=v2
+	TrackGroupIDsKey     = "group_ids"     // the group IDs of the current assignments, separated by ; sign
+)
+
+// Track Record a conversion or a custom event of the user, for example a purchase with its amount as value.
+// The event carries the unit ID, the expanded data and the current assignments of the user, and is reported
+// through the async pipeline to the experiment metrics tables of ControlData, the same way as the exposures:
+// the assignments of a scene go to the table of the scene, the others to the default table.
+// The assignments are evaluated by Track on the local cache serving the call, the snapshot of WithPinnedSnapshot
+// included, and no exposure is recorded for them. If userCtx is nil, the user context attached to ctx is used
+func Track(ctx context.Context, projectID string, userCtx Context, eventName string, value float64,
+	props map[string]string) error {
+	if eventName == "" {
+		return errors.Errorf("event name is required")
+	}
+	if userCtx == nil {
+		var err error
+		userCtx, err = mustFromContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	c, ok := userCtx.(*userContext)
+	if !ok {
+		return errors.Errorf("user context should be created by NewUserContext")
+	}
+	if c.err != nil {
+		return c.err
+	}
+	if internal.C.IsDisableReport || cache.GetApplication(projectID) == nil {
+		return nil
+	}
+	metadata := make(map[string]string, len(props)+3)
+	for key, v := range props {
+		metadata[key] = v
+	}
+	metadata[TrackUnitIDKey] = reportedID(c.unitID)
+	metadata[TrackValueKey] = strconv.FormatFloat(value, 'f', -1, 64)
+	if expandedData := marshalExpandedData(c); expandedData != "" {
+		metadata[TrackExpandedDataKey] = expandedData
+	}
+	list, application, err := c.assignments(ctx, projectID)
+	if err != nil {
+		return errors.Wrap(err, "assignments")
+	}
+	reports := trackEventReports(application, list, &protoc_event_server.Event{
+		EventName: eventName,
+		ProjectId: projectID,
+		Time:      time.Now().Unix(),
+		Metadata:  metadata,
+	})
+	if len(reports) != 0 {
+		asyncTrackEvent(projectID, application, reports)
+	}
+	return nil
+}
+
+// trackEventReport The event with the group IDs reported to an experiment metrics table
+type trackEventReport struct {
+	sceneID       int64 // 0 for the default table
+	metricsConfig *protoccacheserver.MetricsConfig
+	eventGroup    *protoc_event_server.EventGroup
+}
+
+// trackEventReports The reports of the event to the experiment metrics tables of the assignments of the user
+func trackEventReports(application *cache.Application, list *ExperimentList,
+	event *protoc_event_server.Event) []*trackEventReport {
+	if application == nil || application.TabConfig == nil || application.TabConfig.ControlData == nil {
+		return nil
+	}
+	controlData := application.TabConfig.ControlData
+	metricsConfigList := controlData.ExperimentMetricsConfig
+	defaultMetricsConfig := controlData.DefaultExperimentMetricsConfig
+	if len(metricsConfigList) == 0 && defaultMetricsConfig == nil {
+		return nil
+	}
+	var reports []*trackEventReport
+	sceneGroupIDs, defaultGroupIDs := trackGroupIDs(list, controlData.IgnoreReportGroupId)
+	for sceneID, groupIDs := range sceneGroupIDs {
+		metricsConfig, ok := metricsConfigList[sceneID]
+		if !ok || metricsConfig == nil {
+			defaultGroupIDs = append(defaultGroupIDs, groupIDs...)
+			continue
+		}
+		if !metricsConfig.IsEnable || metricsConfig.Metadata == nil {
+			continue
+		}
+		reports = append(reports, &trackEventReport{sceneID: sceneID, metricsConfig: metricsConfig,
+			eventGroup: trackEventGroup(event, groupIDs)})
+	}
+	// The events of the users without assignments in any scene go to the default table
+	if (len(reports) != 0 && len(defaultGroupIDs) == 0) || defaultMetricsConfig == nil ||
+		!defaultMetricsConfig.IsEnable || defaultMetricsConfig.Metadata == nil {
+		return reports
+	}
+	return append(reports, &trackEventReport{metricsConfig: defaultMetricsConfig,
+		eventGroup: trackEventGroup(event, defaultGroupIDs)})
+}
+
+// logTrackEvent Report the event to the experiment metrics tables, a failed table is logged and does not stop
+// the others
+func logTrackEvent(ctx context.Context, application *cache.Application, reports []*trackEventReport) {
+	for _, report := range reports {
+		err := metrics.LogEvent(ctx, newMetricsMetadata(report.metricsConfig), report.eventGroup)
+		if err != nil {
+			stats.IncExposureError(ChannelTrackEvent)
+			log.ErrorContext(ctx, "logEvent fail", application.LogFields(
+				log.F("plugin", report.metricsConfig.PluginName), log.F("sceneID", report.sceneID), log.Err(err))...)
+		}
+	}
+}
+
+// trackGroupIDs The reported group IDs of each scene and of no scene
+func trackGroupIDs(list *ExperimentList, ignoreReportGroupID map[int64]bool) (map[int64][]int64, []int64) {
+	var sceneGroupIDs = make(map[int64][]int64)
+	var defaultGroupIDs []int64
+	for _, group := range list.Data {
+		if group == nil || ignoreReportGroupID[group.ID] {
+			continue
+		}
+		if len(group.sceneIDList) == 0 {
+			defaultGroupIDs = append(defaultGroupIDs, group.ID)
+			continue
+		}
+		for _, sceneID := range group.sceneIDList {
+			sceneGroupIDs[sceneID] = append(sceneGroupIDs[sceneID], group.ID)
+		}
+	}
+	return sceneGroupIDs, defaultGroupIDs
+}
+
+// trackEventGroup The copy of the event with the group IDs reported to a table
+func trackEventGroup(event *protoc_event_server.Event, groupIDs []int64) *protoc_event_server.EventGroup {
+	metadata := make(map[string]string, len(event.Metadata)+1)
+	for key, value := range event.Metadata {
+		metadata[key] = value
+	}
+	if len(groupIDs) != 0 {
+		groupIDs = uniqueSortedIDs(groupIDs)
+		metadata[TrackGroupIDsKey] = int64ListJoin(groupIDs, ";")
+	}
+	return &protoc_event_server.EventGroup{Events: []*protoc_event_server.Event{{
+		EventName: event.EventName,
+		ProjectId: event.ProjectId,
+		Time:      event.Time,
+		Metadata:  metadata,
+	}}}
+}
+
+func uniqueSortedIDs(ids []int64) []int64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	result := ids[:0]
+	for _, id := range ids {
+		if len(result) == 0 || id != result[len(result)-1] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package abc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal/stats"
+	"github.com/abetterchoice/go-sdk/plugin/metrics"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/abetterchoice/protoc_cache_server"
+	"github.com/abetterchoice/protoc_event_server"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack(t *testing.T) {
+	client := &recordingMetricsClient{name: "pubsub"} // the plugin of the default experiment metrics table
+	defer Release()
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient), WithRegisterMetricsPlugin(client, nil))
+	assert.Nil(t, err)
+	userCtx := NewUserContext("track_user", WithExpandedData(map[string]string{"channel": "ad"}))
+	assert.Nil(t, Track(context.Background(), projectID, userCtx, "purchase", 9.9,
+		map[string]string{"sku": "s1", TrackValueKey: "overridden"}))
+	var events []*protoc_event_server.EventGroup
+	var metadata []*metrics.Metadata
+	assert.Eventually(t, func() bool {
+		events, metadata = client.eventCalls()
+		return len(events) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "abc_exp_expose_test", metadata[0].TableName)
+	event := events[0].Events[0]
+	assert.Equal(t, "purchase", event.EventName)
+	assert.Equal(t, projectID, event.ProjectId)
+	assert.Equal(t, "track_user", event.Metadata[TrackUnitIDKey])
+	assert.Equal(t, "9.9", event.Metadata[TrackValueKey])
+	assert.Equal(t, "s1", event.Metadata["sku"])
+	assert.Equal(t, "channel=ad;new_id=track_user", event.Metadata[TrackExpandedDataKey])
+	// the assignments are evaluated without recording their exposures
+	exposures, _ := client.calls()
+	assert.Empty(t, exposures)
+}
+
+func TestTrack_invalid(t *testing.T) {
+	ctx := context.Background()
+	assert.NotNil(t, Track(ctx, projectID, NewUserContext("u1"), "", 1, nil))
+	assert.Equal(t, env.ErrUserContextNotFound, Track(ctx, projectID, nil, "purchase", 1, nil))
+	assert.NotNil(t, Track(ctx, projectID, &MockContext{}, "purchase", 1, nil))
+	// the user context attached to ctx
+	assert.Nil(t, Track(NewContext(ctx, NewUserContext("u1")), projectID, nil, "purchase", 1, nil))
+}
+
+func Test_trackEventGroup(t *testing.T) {
+	event := &protoc_event_server.Event{EventName: "e", Metadata: map[string]string{TrackUnitIDKey: "u1"}}
+	group := trackEventGroup(event, []int64{3, 1, 3, 2})
+	assert.Equal(t, "1;2;3", group.Events[0].Metadata[TrackGroupIDsKey])
+	assert.Equal(t, 1, len(event.Metadata))
+	assert.NotContains(t, trackEventGroup(event, nil).Events[0].Metadata, TrackGroupIDsKey)
+}
+
+func Test_logTrackEvent(t *testing.T) {
+	failing := &recordingMetricsClient{name: "failing_track", err: errors.New("mock err")}
+	recording := &recordingMetricsClient{name: "recording_track"}
+	metrics.RegisterClient(failing)
+	metrics.RegisterClient(recording)
+	event := &protoc_event_server.Event{EventName: "purchase", Metadata: map[string]string{TrackUnitIDKey: "u1"}}
+	reports := []*trackEventReport{
+		{sceneID: 1, metricsConfig: &protoc_cache_server.MetricsConfig{PluginName: failing.name, SamplingInterval: 1,
+			Metadata: &protoc_cache_server.MetricsMetadata{Name: "scene"}}, eventGroup: trackEventGroup(event, []int64{1})},
+		{metricsConfig: &protoc_cache_server.MetricsConfig{PluginName: recording.name, SamplingInterval: 1,
+			Metadata: &protoc_cache_server.MetricsMetadata{Name: "default"}}, eventGroup: trackEventGroup(event, nil)},
+	}
+	stats.Reset()
+	defer stats.Reset()
+	// the failed scene table does not stop the default table
+	logTrackEvent(context.Background(), nil, reports)
+	events, metadata := recording.eventCalls()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "default", metadata[0].TableName)
+	assert.Equal(t, []stats.Counter{{Labels: map[string]string{stats.LabelChannel: ChannelTrackEvent}, Value: 1}},
+		Stats().ExposureErrors)
+}
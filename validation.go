@@ -0,0 +1,28 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+)
+
+// ValidationError The error of the refresh rejecting a version of the local cache data by the fatal findings of its
+// validation, the served version is kept. Init returns it wrapped when the first version
+// of a project is rejected, use errors.As to get the findings
+type ValidationError = cache.ValidationError
+
+// ValidationFinding A problem found by the validation of the local cache data, the findings of the last refreshed
+// version are also shown by the project pages of DebugHandler
+type ValidationFinding = cache.Finding
+
+// WithLenientValidation Apply a refreshed version of the local cache data with a fatal finding of the validation,
+// the findings are only logged and shown by DebugHandler. By default the version is rejected
+// and the served version is kept
+func WithLenientValidation() InitOption {
+	return func(config *internal.GlobalConfig) error {
+		config.LenientValidation = true
+		return nil
+	}
+}
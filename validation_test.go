@@ -0,0 +1,47 @@
+package abc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/abetterchoice/protoc_cache_server"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLenientValidation(t *testing.T) {
+	config := &internal.GlobalConfig{}
+	assert.Nil(t, WithLenientValidation()(config))
+	assert.True(t, config.LenientValidation)
+
+	// the global domain with no buckets is a fatal finding
+	normal := testdata.NormalTabConfig
+	tabConfig := &protoc_cache_server.TabConfig{
+		ExperimentData: &protoc_cache_server.ExperimentData{GlobalDomain: &protoc_cache_server.Domain{
+			Metadata:             &protoc_cache_server.DomainMetadata{Key: "globalDomain"},
+			MultiLayerDomainList: normal.ExperimentData.GlobalDomain.MultiLayerDomainList,
+		}},
+		ConfigData:  normal.ConfigData,
+		ControlData: normal.ControlData,
+	}
+	initBroken := func(opts ...InitOption) error {
+		return Init(context.Background(), projectIDList, append([]InitOption{
+			WithRegisterCacheClient(testdata.MockCacheClientWithData(t, tabConfig,
+				testdata.NormalExperimentBucketInfo, testdata.NormalGroupBucketInfo)),
+			WithRegisterDMPClient(testdata.MockEmptyDMPClient)}, opts...)...)
+	}
+	err := initBroken()
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr), "the version is rejected by default, got %v", err)
+	Release()
+
+	assert.Nil(t, initBroken(WithLenientValidation()))
+	assert.True(t, IsProjectReady(projectID))
+	Release()
+
+	// Release restores the default
+	assert.True(t, errors.As(initBroken(), &validationErr))
+	Release()
+}
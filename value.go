@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"strconv"
+	"time"
 
+	"github.com/abetterchoice/go-sdk/env"
 	"github.com/abetterchoice/go-sdk/internal/experiment"
+	"github.com/abetterchoice/go-sdk/internal/stats"
 	"github.com/pkg/errors"
 )
 
@@ -33,23 +36,27 @@ import (
 //	A pointer to the ValueResult object containing the resolved value, and an
 //	error if any occurred during the lookup.
 func (c *userContext) GetValueByVariantKey(ctx context.Context, projectID string, key string,
-	opts ...ExperimentOption) (*ValueResult, error) {
+	opts ...ExperimentOption) (vr *ValueResult, err error) {
+	defer func(startTime time.Time) {
+		isFallback := vr != nil && env.IsFallbackReason(vr.Reason)
+		stats.ObserveEvaluation(stats.APIGetValueByVariantKey, evaluationStatus(err, isFallback), time.Since(startTime))
+	}(time.Now())
 	options := defaultExperimentOptions // 拷贝，defaultExperimentOptions 作为模板保持不变
-	if c.err != nil {
-		return nil, c.err
-	}
-	c.fillOption(&options)
+	c.fillOption(projectID, &options)
 	for _, opt := range opts {
 		err := opt(&options)
 		if err != nil {
 			return nil, errors.Wrap(err, "opt")
 		}
 	}
-	layerKeys, err := experiment.Executor.VariantKey2LayerKey(projectID, key)
+	layerKeys, err := c.variantKey2LayerKey(projectID, key, &options)
 	if err != nil {
+		if options.HasFallbackValue {
+			return fallbackValueResult(key, &options, fallbackReason(err)), nil
+		}
 		return nil, errors.Wrapf(err, "VariantKey2LayerKey")
 	}
-	vr := &ValueResult{
+	vr = &ValueResult{
 		Value: &Value{},
 		Detail: &valueDetail{
 			LayerKeys: layerKeys,
@@ -65,6 +72,9 @@ func (c *userContext) GetValueByVariantKey(ctx context.Context, projectID string
 		experimentOpts = append(experimentOpts, WithLayerKeyList(layerKeys))
 		experimentResult, err := c.GetExperiments(ctx, projectID, experimentOpts...)
 		if err != nil {
+			if options.HasFallbackValue {
+				return fallbackValueResult(key, &options, fallbackReason(err)), nil
+			}
 			return nil, errors.Wrap(err, "GetExperiment")
 		}
 		for _, layerKey := range layerKeys {
@@ -83,6 +93,8 @@ func (c *userContext) GetValueByVariantKey(ctx context.Context, projectID string
 			vr.Detail.ExperimentID = group.ExperimentID
 			vr.Detail.ExperimentKey = group.ExperimentKey
 			vr.Detail.LayerKey = layerKey
+			vr.Reason = group.Reason
+			vr.Version = group.Version
 			return vr, nil
 		}
 	}
@@ -92,13 +104,37 @@ func (c *userContext) GetValueByVariantKey(ctx context.Context, projectID string
 	}
 	vr.Value = configResult.Value
 	vr.Detail.ConfigKey = key
+	vr.Reason = configResult.Reason
+	vr.Version = configResult.Version
 	return vr, nil
 }
 
+// variantKey2LayerKey The layers of the variant key, the error of the construction of the user context included
+func (c *userContext) variantKey2LayerKey(projectID string, key string, options *experiment.Options) ([]string,
+	error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return experiment.Executor.VariantKey2LayerKey(projectID, key, options)
+}
+
+// fallbackValueResult Build the parameter value result carrying the caller-supplied fallback value
+func fallbackValueResult(key string, options *experiment.Options, reason env.Reason) *ValueResult {
+	return &ValueResult{
+		Value:  &Value{data: options.FallbackValue},
+		Detail: &valueDetail{ConfigKey: key},
+		Reason: reason,
+	}
+}
+
 // ValueResult TODO
 type ValueResult struct {
 	*Value
 	Detail *valueDetail
+	// The reason why this value is returned, the reason of the hit experiment group or remote configuration
+	Reason env.Reason
+	// The version of the local cache the value is evaluated from, empty for the caller-supplied fallback value
+	Version string
 }
 
 type valueDetail struct {
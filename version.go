@@ -0,0 +1,68 @@
+// Package abc provides a set of APIs for external use, including APIs for ABC system initialization.
+// It also encompasses functionalities such as traffic distribution for A/B experiments,
+// user configuration data retrieval, user feature flag management, exposure data reporting, and logger registration.
+package abc
+
+import (
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/internal/cache"
+	"github.com/pkg/errors"
+)
+
+// VersionCheck The check of a version of the local cache data of the project, for example a validation or the health
+// of the application. It is called before the version is served and at each refresh while it is served
+type VersionCheck = cache.VersionCheck
+
+// VersionStatus The versions of the local cache data of a project, see Versions
+type VersionStatus = cache.VersionStatus
+
+// WithVersionHistory Keep the size latest versions of the local cache data per project, the served one included,
+// so that PinVersion can go back to one of them. The default is 1
+func WithVersionHistory(size int) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		if size < 1 {
+			return errors.Errorf("version history size should be positive")
+		}
+		config.VersionHistory = size
+		return nil
+	}
+}
+
+// WithAutoRollback Roll back a version of the local cache data failing the check to the newest kept version passing
+// it. A refreshed version failing the check is not served, a served version failing the check later is replaced,
+// and it is not served again until a newer version is refreshed. If no kept version passes the check,
+// the served version is kept. Use it with WithVersionHistory to keep the versions to roll back to.
+// The rollbacks are counted in Stats as abc_cache_version_rollbacks_total
+func WithAutoRollback(check VersionCheck) InitOption {
+	return func(config *internal.GlobalConfig) error {
+		if check == nil {
+			return errors.Errorf("version check is required")
+		}
+		config.VersionCheck = check
+		return nil
+	}
+}
+
+// PinVersion Freeze the evaluations of the project on the kept version, for example a known-good version while
+// a bad publish is being fixed. The newer versions are still refreshed and kept, but not served until Unpin.
+// The pinned version is not checked by WithAutoRollback. The error wraps env.ErrProjectNotFound if the project
+// is not loaded, or env.ErrVersionNotKept if the version is not kept
+func PinVersion(projectID string, version string) error {
+	return cache.PinVersion(projectID, version)
+}
+
+// Unpin Go back to the newest version of the project passing the check of WithAutoRollback,
+// the error wraps env.ErrProjectNotFound if the project is not loaded
+func Unpin(projectID string) error {
+	return cache.Unpin(projectID)
+}
+
+// Versions The served, the latest refreshed, the kept and the pinned versions of the local cache data of the project
+func Versions(projectID string) (VersionStatus, error) {
+	status, ok := cache.GetVersionStatus(projectID)
+	if !ok {
+		return VersionStatus{}, errors.Wrapf(env.ErrProjectNotFound, "projectID [%s]", projectID)
+	}
+	return status, nil
+}
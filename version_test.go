@@ -0,0 +1,40 @@
+package abc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abetterchoice/go-sdk/env"
+	"github.com/abetterchoice/go-sdk/internal"
+	"github.com/abetterchoice/go-sdk/testdata"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersions(t *testing.T) {
+	config := &internal.GlobalConfig{}
+	assert.NotNil(t, WithVersionHistory(0)(config))
+	assert.NotNil(t, WithAutoRollback(nil)(config))
+	var checked []string
+	err := Init(context.Background(), projectIDList, WithRegisterCacheClient(testdata.MockCacheClient(t)),
+		WithRegisterDMPClient(testdata.MockEmptyDMPClient), WithVersionHistory(3),
+		WithAutoRollback(func(projectID string, version string) error {
+			checked = append(checked, projectID)
+			return nil
+		}))
+	assert.Nil(t, err)
+	defer Release()
+	assert.Equal(t, []string{projectID}, checked)
+	status, err := Versions(projectID)
+	assert.Nil(t, err)
+	assert.Len(t, status.Kept, 1)
+	assert.Nil(t, PinVersion(projectID, status.Served))
+	status, _ = Versions(projectID)
+	assert.Equal(t, status.Served, status.Pinned)
+	assert.Nil(t, Unpin(projectID))
+	assert.True(t, errors.Is(PinVersion(projectID, "not kept"), env.ErrVersionNotKept))
+	_, err = Versions("not exist")
+	assert.True(t, errors.Is(err, env.ErrProjectNotFound))
+	assert.True(t, errors.Is(Unpin("not exist"), env.ErrProjectNotFound))
+	assert.True(t, errors.Is(PinVersion("not exist", status.Served), env.ErrProjectNotFound))
+}